@@ -0,0 +1,341 @@
+package assembler
+
+import (
+	"assembler/ast"
+	"assembler/code"
+	"assembler/object"
+	"assembler/optimizer"
+	"assembler/parser"
+	"assembler/rom"
+	"assembler/symboltable"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+)
+
+// Error is an error of assembly with file, line number and source of the line
+type Error = parser.ParseError
+
+// Errors has all errors of program. Assemble returns Errors if assembly has errors.
+type Errors = parser.Errors
+
+// Options are options of Assemble
+type Options struct {
+	Filename string     // name of input used in error messages. "#include" is read relative to its dir
+	Format   rom.Format // format of machine language. HACK if empty
+	Listing  io.Writer  // listing file(.lst) is written if not nil
+	Symbols  io.Writer  // symbol file(.sym) is written if not nil
+	Optimize bool       // remove redundant instructions by peephole optimizer before assembling. listing is made from optimized assembly
+	Report   io.Writer  // report of instructions saved by optimizer is written if Optimize and not nil
+}
+
+// Assemble reads assembly from r and writes machine language to w in opts.Format.
+// CRLF and LF line endings and indent by spaces and tabs are accepted.
+// if assembly has errors, nothing is written and Errors which has all errors with line number is returned.
+func Assemble(r io.Reader, w io.Writer, opts Options) error {
+	format := opts.Format
+	if format == "" {
+		format = rom.HACK
+	}
+	if _, err := rom.ParseFormat(string(format)); err != nil {
+		return err
+	}
+	asm, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	input := string(asm)
+	var report *optimizer.Report
+	if opts.Optimize {
+		if input, report, err = optimizer.OptimizeAsm(input, opts.Filename); err != nil {
+			return err
+		}
+	}
+	program, err := AssembleProgram(input, opts.Filename)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(rom.Encode(rom.Words(program.Binary), format)); err != nil {
+		return err
+	}
+	if opts.Listing != nil {
+		if _, err := io.WriteString(opts.Listing, FormatListing(program.Listing)); err != nil {
+			return err
+		}
+	}
+	if opts.Symbols != nil {
+		if _, err := io.WriteString(opts.Symbols, symboltable.FormatSymbolFile(program.SymbolTable.Symbols())); err != nil {
+			return err
+		}
+	}
+	if report != nil && opts.Report != nil {
+		if _, err := fmt.Fprintln(opts.Report, report); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Program is result of assembling. it has listing and symbol table in addition to machine language.
+type Program struct {
+	Binary      []string
+	Listing     []ListingLine
+	SymbolTable *symboltable.SymbolTable
+}
+
+// INITIAL_VARIABLE_ADDRESS is RAM address of first variable
+const INITIAL_VARIABLE_ADDRESS = 16
+
+// fixup is A command or .equ which refers symbol not defined yet. it is patched after all lines are read.
+type fixup struct {
+	index   int    // index of line in source lines and listing
+	name    string // name of constant defined by .equ
+	operand string
+}
+
+// assembler assembles lines in single pass. forward references are resolved by backpatching.
+type assembler struct {
+	lines    []parser.SourceLine
+	st       *symboltable.SymbolTable
+	words    []int
+	listing  []ListingLine
+	errs     []indexedError
+	fixups   []fixup // A commands in order of line
+	equs     []fixup // .equ in order of line
+	constant map[string]int
+	cWords   map[ast.CCommand]int // words of C commands. programs use few kinds of C commands
+}
+
+// indexedError is error with index of line to sort errors in order of line
+type indexedError struct {
+	index int
+	err   *parser.ParseError
+}
+
+// AssembleProgram translates assembly to machine language like Assemble and records listing and symbol table.
+// "#include" and macros are expanded before assembling. included files are read relative to dir of filename.
+// each line is tokenized once. symbols defined after use are resolved by backpatching and
+// variables are allocated in order of first use.
+func AssembleProgram(input string, filename string) (*Program, error) {
+	sourceLines, err := parser.Preprocess(input, filename)
+	if err != nil {
+		return nil, err
+	}
+	a := &assembler{lines: sourceLines, st: symboltable.New(), listing: make([]ListingLine, 0, len(sourceLines)), constant: map[string]int{}, cWords: map[ast.CCommand]int{}}
+	for i, sourceLine := range sourceLines {
+		a.assembleLine(i, sourceLine)
+	}
+	a.patch()
+	if len(a.errs) > 0 {
+		return nil, sortErrors(a.errs)
+	}
+	binaryArr := make([]string, len(a.words))
+	for i, word := range a.words {
+		binaryArr[i] = formatWord(word)
+	}
+	for i := range a.listing {
+		if a.listing[i].Address >= 0 {
+			a.listing[i].Word = binaryArr[a.listing[i].Address]
+		}
+	}
+	return &Program{Binary: binaryArr, Listing: a.listing, SymbolTable: a.st}, nil
+}
+
+// sortErrors returns errors in order of line
+func sortErrors(indexedErrs []indexedError) Errors {
+	sort.SliceStable(indexedErrs, func(i, j int) bool { return indexedErrs[i].index < indexedErrs[j].index })
+	errs := make(Errors, len(indexedErrs))
+	for i, e := range indexedErrs {
+		errs[i] = e.err
+	}
+	return errs
+}
+
+// formatWord returns word in 16 "0" or "1" like code.Binary
+func formatWord(word int) string {
+	if word < 0 || word >= 1<<16 {
+		return fmt.Sprintf("%016b", word)
+	}
+	// 17ビット目を立ててから取り除くと0埋めになる
+	return strconv.FormatUint(uint64(word|1<<16), 2)[1:]
+}
+
+func (a *assembler) errorAt(index int, err *parser.ParseError) {
+	a.errs = append(a.errs, indexedError{index, err})
+}
+
+func (a *assembler) assembleLine(index int, sourceLine parser.SourceLine) {
+	line := ListingLine{Filename: sourceLine.Filename, Line: sourceLine.Line, Source: sourceLine.Text, Macro: sourceLine.Macro, Address: -1}
+	a.listing = append(a.listing, line)
+	instruction, err := parser.Scan(sourceLine)
+	if err != nil {
+		a.errorAt(index, err)
+		return
+	}
+	switch {
+	case instruction.IsEqu():
+		a.equ(fixup{index, instruction.Name, instruction.Operand}, false)
+	case instruction.Type == ast.L_COMMAND:
+		label := instruction.Operand
+		if a.st.Contains(label) {
+			a.errorAt(index, sourceLine.Errorf(parser.DUPLICATE_LABEL, "symbol %s is already defined", label))
+			return
+		}
+		a.st.AddLabel(label, len(a.words))
+		a.listing[index].Symbol, a.listing[index].Value = label, len(a.words)
+	case instruction.Type == ast.A_COMMAND:
+		a.listing[index].Address = len(a.words)
+		a.words = append(a.words, 0)
+		a.aCommand(fixup{index, "", instruction.Operand}, false)
+	case instruction.Type == ast.C_COMMAND:
+		a.listing[index].Address = len(a.words)
+		word, ok := a.cWords[*instruction.CCommand]
+		if !ok {
+			binary, _ := strconv.ParseUint(code.Binary(instruction.CCommand), 2, 16)
+			word = int(binary)
+			a.cWords[*instruction.CCommand] = word
+		}
+		a.words = append(a.words, word)
+	}
+}
+
+// aCommand sets value of A command. value is patched later if operand refers undefined symbol unless final.
+func (a *assembler) aCommand(f fixup, final bool) {
+	operand := f.operand
+	value := 0
+	if parser.IsSymbol(operand) {
+		address, ok := a.resolve(operand)
+		if !ok {
+			// ラベルか定数が後で定義されなければ変数になる
+			a.fixups = append(a.fixups, f)
+			return
+		}
+		value = address
+	} else {
+		var err *parser.ParseError
+		value, err = a.lines[f.index].Evaluate(operand, a.resolve)
+		if err != nil {
+			if err.Type == parser.UNDEFINED_SYMBOL && !final {
+				a.fixups = append(a.fixups, f)
+			} else {
+				a.errorAt(f.index, err)
+			}
+			return
+		}
+	}
+	line := &a.listing[f.index]
+	a.words[line.Address] = value
+	if operand != strconv.Itoa(value) {
+		line.Symbol, line.Value = operand, value
+	}
+}
+
+// equ defines constant. .equ can refer labels and constants defined before it, so it is evaluated after all labels are defined if it refers undefined symbol.
+func (a *assembler) equ(f fixup, final bool) {
+	resolve := a.resolve
+	if final {
+		resolve = func(symbol string) (int, bool) {
+			if index, ok := a.constant[symbol]; ok && index > f.index {
+				return 0, false
+			}
+			return a.resolve(symbol)
+		}
+	}
+	value, err := a.lines[f.index].Evaluate(f.operand, resolve)
+	if err != nil {
+		if err.Type == parser.UNDEFINED_SYMBOL && !final {
+			a.equs = append(a.equs, f)
+		} else {
+			a.errorAt(f.index, err)
+		}
+		return
+	}
+	name := f.name
+	if a.st.Contains(name) {
+		a.errorAt(f.index, a.lines[f.index].Errorf(parser.DUPLICATE_LABEL, "symbol %s is already defined", name))
+		return
+	}
+	a.st.AddConstant(name, value)
+	a.constant[name] = f.index
+	a.listing[f.index].Symbol, a.listing[f.index].Value = name, value
+}
+
+// resolve returns value of symbol in expression
+func (a *assembler) resolve(symbol string) (int, bool) {
+	value, ok := a.st.SymbolTableDict[symbol]
+	return value, ok
+}
+
+// patch resolves forward references after all lines are read
+func (a *assembler) patch() {
+	equs, fixups := a.equs, a.fixups
+	a.equs, a.fixups = nil, nil
+	for _, f := range equs {
+		a.equ(f, true)
+	}
+	// 式の中のシンボルは変数として割り当てない
+	nextVariableAddress := INITIAL_VARIABLE_ADDRESS
+	for _, f := range fixups {
+		if operand := f.operand; parser.IsSymbol(operand) && !a.st.Contains(operand) {
+			a.st.AddVariable(operand, nextVariableAddress)
+			nextVariableAddress++
+		}
+	}
+	for _, f := range fixups {
+		a.aCommand(f, true)
+	}
+}
+
+// AssembleAsmFile assembles asmFilename and writes machine language to hackFilename.
+// format of machine language is decided by extension of hackFilename. e.g. Intel HEX for .hex
+// listing(.lst) and symbol file(.sym) are written only if lstFilename and symFilename are not empty.
+// no file is written if assembly has errors.
+func AssembleAsmFile(asmFilename string, hackFilename string, lstFilename string, symFilename string) error {
+	asm, err := os.Open(asmFilename)
+	if err != nil {
+		return err
+	}
+	defer asm.Close()
+	var hack, lst, sym bytes.Buffer
+	opts := Options{Filename: asmFilename, Format: rom.FormatOf(hackFilename)}
+	if lstFilename != "" {
+		opts.Listing = &lst
+	}
+	if symFilename != "" {
+		opts.Symbols = &sym
+	}
+	if err := Assemble(asm, &hack, opts); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(hackFilename, hack.Bytes(), os.ModePerm); err != nil {
+		return err
+	}
+	if lstFilename != "" {
+		if err := ioutil.WriteFile(lstFilename, lst.Bytes(), os.ModePerm); err != nil {
+			return err
+		}
+	}
+	if symFilename != "" {
+		if err := ioutil.WriteFile(symFilename, sym.Bytes(), os.ModePerm); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AssembleObjectFile assembles asmFilename to relocatable object and writes it to objFilename
+func AssembleObjectFile(asmFilename string, objFilename string) error {
+	asm, err := ioutil.ReadFile(asmFilename)
+	if err != nil {
+		return err
+	}
+	obj, err := AssembleObject(string(asm), asmFilename)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(objFilename, []byte(object.Format(obj)), os.ModePerm)
+}
@@ -0,0 +1,255 @@
+package assembler
+
+import (
+	"assembler/disassembler"
+	"assembler/parser"
+	"assembler/rom"
+	"assembler/value"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAssemble(t *testing.T) {
+	testCases := []struct {
+		asmFilename  string
+		hackFilename string
+	}{
+		{"asm/add/Add.asm", "../hardware/computer/Add.hack"},
+		{"asm/rect/Rect.asm", "../hardware/computer/Rect.hack"},
+		{"asm/max/Max.asm", "../hardware/computer/Max.hack"},
+	}
+	for _, tt := range testCases {
+		asm, _ := ioutil.ReadFile(tt.asmFilename)
+		input := string(asm)
+		hack, _ := ioutil.ReadFile(tt.hackFilename)
+		binaryArrInFile := strings.Split(strings.TrimSpace(string(hack)), value.NEW_LINE)
+		binaryArr, err := assemble(input)
+		if err != nil {
+			t.Fatalf("%s: Assemble() returned error: %s", tt.asmFilename, err)
+		}
+		if len(binaryArr) != len(binaryArrInFile) {
+			t.Fatalf("%s: binary should have %d lines. got %d", tt.asmFilename, len(binaryArrInFile), len(binaryArr))
+		}
+		for i := range binaryArr {
+			if binaryArrInFile[i] != binaryArr[i] {
+				t.Errorf("binary should be %s got,%s", binaryArrInFile[i], binaryArr[i])
+			}
+		}
+	}
+}
+
+func TestAssembleLF(t *testing.T) {
+	binaryArr, err := assemble("  @2 // load 2\n\tD=A\n(END)\n@END\n0;JMP\n")
+	if err != nil {
+		t.Fatalf("Assemble() returned error: %s", err)
+	}
+	expected := []string{"0000000000000010", "1110110000010000", "0000000000000010", "1110101010000111"}
+	if strings.Join(binaryArr, ",") != strings.Join(expected, ",") {
+		t.Fatalf("binary should be %v. got %v", expected, binaryArr)
+	}
+}
+
+func TestAssembleErrors(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected []string
+	}{
+		{"@1\r\nD=M+2\r\n", []string{"line 2: D=M+2: comp \"M+2\" is not defined"}},
+		{"DM=M", []string{"line 1: DM=M: dest \"DM\" is not defined"}},
+		{"0;JUMP", []string{"line 1: 0;JUMP: jump \"JUMP\" is not defined"}},
+		{"@40000", []string{"line 1: @40000: 40000 is out of range 0..32767"}},
+		{"(LOOP)\n@LOOP\n(LOOP)", []string{"line 3: (LOOP): symbol LOOP is already defined"}},
+		{"(SCREEN)", []string{"line 1: (SCREEN): symbol SCREEN is already defined"}},
+		{"(LOOP\n@0", []string{"line 1: (LOOP: label is not closed by \")\""}},
+		{"@foo#bar", []string{"line 1: @foo#bar: symbol foo#bar has illegal character"}},
+		{"@foo-bar", []string{"line 1: @foo-bar: symbol foo is not defined"}},
+		{"@SCREEN-16385", []string{"line 1: @SCREEN-16385: -1 is out of range 0..32767"}},
+		{"@0x8000", []string{"line 1: @0x8000: 32768 is out of range 0..32767"}},
+		{"@(1+2", []string{`line 1: @(1+2: "(" is not closed by ")"`}},
+		{"@1/0", []string{"line 1: @1/0: division by zero"}},
+		{"@'AB'", []string{"line 1: @'AB': character literal in 'AB' should be one character in ''"}},
+		{".equ X 1\n.equ X 2", []string{"line 2: .equ X 2: symbol X is already defined"}},
+		{".equ X Y\n.equ Y 2", []string{"line 1: .equ X Y: symbol Y is not defined"}},
+		{".equ 1X 1", []string{`line 1: .equ 1X 1: constant name "1X" is not valid symbol`}},
+		{"(1ABC)", []string{"line 1: (1ABC): label \"1ABC\" is not valid symbol"}},
+		{"@12ab", []string{"line 1: @12ab: 12ab is neither number nor symbol"}},
+		{"hoge", []string{"line 1: hoge: invalid command"}},
+		{"@\nD=M+2 // typo\n@2", []string{
+			"line 1: @: A command should have value or symbol",
+			"line 2: D=M+2 // typo: comp \"M+2\" is not defined",
+		}},
+	}
+	for _, tt := range testCases {
+		_, err := assemble(tt.input)
+		errs, ok := err.(parser.Errors)
+		if !ok {
+			t.Fatalf("Assemble(%q) should return parser.Errors. got %v", tt.input, err)
+		}
+		if len(errs) != len(tt.expected) {
+			t.Fatalf("Assemble(%q) should return %d errors. got %v", tt.input, len(tt.expected), errs)
+		}
+		for i, e := range errs {
+			if e.Error() != tt.expected[i] {
+				t.Errorf("error should be %q. got %q", tt.expected[i], e.Error())
+			}
+		}
+	}
+}
+
+func TestDisassembleRoundTrip(t *testing.T) {
+	asmFilenames := []string{"asm/add/Add.asm", "asm/max/Max.asm", "asm/rect/Rect.asm", "asm/pong/Pong.asm"}
+	for _, asmFilename := range asmFilenames {
+		asm, err := ioutil.ReadFile(asmFilename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		binaryArr, _ := assemble(string(asm))
+		words, err := disassembler.ParseHack(strings.Join(binaryArr, value.NEW_LINE))
+		if err != nil {
+			t.Fatalf("%s: ParseHack() returned error: %s", asmFilename, err)
+		}
+		commands, errs := disassembler.Disassemble(words, nil)
+		if len(errs) > 0 {
+			t.Fatalf("%s: Disassemble() returned errors: %v", asmFilename, errs)
+		}
+		reassembled, _ := assemble(disassembler.Format(commands))
+		if strings.Join(reassembled, value.NEW_LINE) != strings.Join(binaryArr, value.NEW_LINE) {
+			t.Errorf("%s: disassembled program should be assembled to the same binary", asmFilename)
+		}
+	}
+}
+
+func TestAssembleExpression(t *testing.T) {
+	input := `.equ ROW 32
+.equ LAST_ROW ROW*255 // 8160
+@0x4000
+@0b1010
+@'A'
+@' '
+@SCREEN+ROW
+@KBD-1
+@TABLE+2*3
+@-(1-LAST_ROW)
+@(SCREEN|1)&0xF
+(TABLE)
+@i
+@i+1`
+	binaryArr, err := assemble(input)
+	if err != nil {
+		t.Fatalf("Assemble() returned error: %s", err)
+	}
+	expected := []int{0x4000, 10, 65, 32, 16384 + 32, 24575, 9 + 6, 8159, 1, 16, 17}
+	if len(binaryArr) != len(expected) {
+		t.Fatalf("Assemble() should return %d words. got %d", len(expected), len(binaryArr))
+	}
+	for i, value := range expected {
+		if binaryArr[i] != fmt.Sprintf("%016b", value) {
+			t.Errorf("word %d should be %016b. got %s", i, value, binaryArr[i])
+		}
+	}
+}
+
+func TestAssembleMacroError(t *testing.T) {
+	_, err := assemble(".macro SET value\n@\\value\nD=A+2\n.endm\n@0\nSET 3")
+	expected := `line 6: D=A+2 (in macro SET): comp "A+2" is not defined`
+	if err == nil || err.Error() != expected {
+		t.Fatalf("Assemble() should return %q. got %v", expected, err)
+	}
+}
+
+func TestAssembleAsmFileFormat(t *testing.T) {
+	hack, err := ioutil.ReadFile("../hardware/computer/Rect.hack")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := disassembler.ParseHack(string(hack))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	for _, format := range rom.Formats {
+		// 出力ファイルの拡張子で形式が決まる
+		romFilename := filepath.Join(dir, "Rect"+rom.Extension(format))
+		if err := AssembleAsmFile("asm/rect/Rect.asm", romFilename, "", ""); err != nil {
+			t.Fatalf("AssembleAsmFile() returned error: %s", err)
+		}
+		data, err := ioutil.ReadFile(romFilename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		words, err := rom.Decode(data, format)
+		if err != nil {
+			t.Fatalf("rom.Decode(%s) returned error: %s", format, err)
+		}
+		if !reflect.DeepEqual(words, expected) {
+			t.Fatalf("%s should have same words as Rect.hack", romFilename)
+		}
+	}
+}
+
+// assemble returns machine language of input like Assemble with default options
+func assemble(input string) ([]string, error) {
+	program, err := AssembleProgram(input, "")
+	if err != nil {
+		return nil, err
+	}
+	return program.Binary, nil
+}
+
+func TestAssembleReader(t *testing.T) {
+	var hack, lst, sym bytes.Buffer
+	input := "\t@2\t// load 2\r\n  D=A\r\n(END)\r\n\t@END\r\n\t0;JMP\r\n"
+	if err := Assemble(strings.NewReader(input), &hack, Options{Listing: &lst, Symbols: &sym}); err != nil {
+		t.Fatalf("Assemble() returned error: %s", err)
+	}
+	expected := "0000000000000010\r\n1110110000010000\r\n0000000000000010\r\n1110101010000111"
+	if hack.String() != expected {
+		t.Fatalf("Assemble() should write %q. got %q", expected, hack.String())
+	}
+	if !strings.Contains(lst.String(), "0002  0000000000000010     4  @END  // END=2") || !strings.Contains(sym.String(), "label 2 END") {
+		t.Fatalf("Assemble() should write listing and symbols. got %q and %q", lst.String(), sym.String())
+	}
+}
+
+func TestAssembleReaderFormat(t *testing.T) {
+	var hex bytes.Buffer
+	if err := Assemble(strings.NewReader("@2\nD=A"), &hex, Options{Format: rom.INTEL_HEX}); err != nil {
+		t.Fatalf("Assemble() returned error: %s", err)
+	}
+	if hex.String() != ":040000000002EC10FE\r\n:00000001FF\r\n" {
+		t.Fatalf("Assemble() should write Intel HEX. got %q", hex.String())
+	}
+	if err := Assemble(strings.NewReader("@2"), &hex, Options{Format: "srec"}); err == nil {
+		t.Fatalf("Assemble() should return error for unknown format")
+	}
+}
+
+func TestAssembleReaderErrors(t *testing.T) {
+	var hack bytes.Buffer
+	err := Assemble(strings.NewReader("@0\nD=M+2\n@32768"), &hack, Options{Filename: "Bad.asm"})
+	errs, ok := err.(Errors)
+	if !ok || len(errs) != 2 {
+		t.Fatalf("Assemble() should return 2 Errors. got %v", err)
+	}
+	if errs[0].Type != parser.INVALID_MNEMONIC || errs[0].Line != 2 || errs[1].Type != parser.OUT_OF_RANGE || errs[1].Line != 3 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	if hack.Len() != 0 {
+		t.Fatalf("Assemble() should write nothing if assembly has errors")
+	}
+}
+
+func TestAssembleReaderOptimize(t *testing.T) {
+	var hack, report bytes.Buffer
+	if err := Assemble(strings.NewReader("@SP\nM=M+1\n@SP\nM=M-1\n@SP\nD=M"), &hack, Options{Optimize: true, Report: &report}); err != nil {
+		t.Fatalf("Assemble() returned error: %s", err)
+	}
+	if hack.String() != "0000000000000000\r\n1111110000010000" || report.String() != "6 -> 2 instructions (4 saved: REDUNDANT_LOAD 2, STACK_ROUND_TRIP 2)\n" {
+		t.Fatalf("Assemble() should write optimized program. got %q (%q)", hack.String(), report.String())
+	}
+}
@@ -0,0 +1,145 @@
+package assembler
+
+import (
+	"assembler/ast"
+	"assembler/code"
+	"assembler/parser"
+	"assembler/symboltable"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// BENCHMARK_LINES is number of lines of program assembled by benchmarks
+const BENCHMARK_LINES = 100000
+
+// generateProgram returns program like output of VM translator which has about lines lines.
+// it has forward references to labels, variables, constants and expressions.
+func generateProgram(lines int) string {
+	var b strings.Builder
+	b.WriteString(".equ ROW 32\n")
+	i := 0
+	for ; i*16 < lines; i++ {
+		fmt.Fprintf(&b, "// push static %d\n", i%200)
+		fmt.Fprintf(&b, "    @Main.%d\n    D=M\n    @SP\n    AM=M+1\n    A=A-1\n    M=D\n\n", i%200)
+		fmt.Fprintf(&b, "// pop pointer to screen row %d\n", i%256)
+		fmt.Fprintf(&b, "    @SCREEN+ROW*%d // row\n    M=D\n\n", i%256)
+		fmt.Fprintf(&b, "// if-goto LOOP_%d\n", i+1)
+		fmt.Fprintf(&b, "    @LOOP_%d\n    D;JGT\n(LOOP_%d)\n", i+1, i)
+	}
+	fmt.Fprintf(&b, "(LOOP_%d)\n", i)
+	return b.String()
+}
+
+// assembleProgramMultiPass is AssembleProgram before single pass assembler.
+// it walks scanned lines three times: defining symbols, allocating variables and emitting binary. it is kept to compare output and speed.
+func assembleProgramMultiPass(input string, filename string) (*Program, error) {
+	sourceLines, err := parser.Preprocess(input, filename)
+	if err != nil {
+		return nil, err
+	}
+	st := symboltable.New()
+	lines := parser.ScanLines(sourceLines, st)
+
+	customVariableCount := 0
+	for _, line := range lines {
+		if line.Type == ast.A_COMMAND && parser.IsSymbol(line.Operand) && !st.Contains(line.Operand) {
+			st.AddVariable(line.Operand, INITIAL_VARIABLE_ADDRESS+customVariableCount)
+			customVariableCount++
+		}
+	}
+	errs := parser.Errors{}
+	binaryArr, listing := []string{}, []ListingLine{}
+	for _, line := range lines {
+		sourceLine := line.Source
+		listingLine := ListingLine{Filename: sourceLine.Filename, Line: sourceLine.Line, Source: sourceLine.Text, Macro: sourceLine.Macro, Address: -1}
+		switch {
+		case line.Err != nil:
+			errs = append(errs, line.Err)
+		case line.Type == ast.A_COMMAND:
+			value, ok := parser.Resolve(st)(line.Operand)
+			if !ok {
+				evaluated, err := sourceLine.Evaluate(line.Operand, parser.Resolve(st))
+				if err != nil {
+					errs = append(errs, err)
+					break
+				}
+				value = evaluated
+			}
+			listingLine.Address, listingLine.Word = len(binaryArr), code.Binary(&ast.ACommand{ValueStr: line.Operand, Value: value})
+			binaryArr = append(binaryArr, listingLine.Word)
+			if line.Operand != strconv.Itoa(value) {
+				listingLine.Symbol, listingLine.Value = line.Operand, value
+			}
+		case line.Type == ast.C_COMMAND:
+			listingLine.Address, listingLine.Word = len(binaryArr), code.Binary(line.CCommand)
+			binaryArr = append(binaryArr, listingLine.Word)
+		case line.Type == ast.L_COMMAND:
+			listingLine.Symbol = line.Operand
+			listingLine.Value, _ = st.GetAddress(line.Operand)
+		case line.IsEqu():
+			listingLine.Symbol = line.Name
+			listingLine.Value, _ = st.GetAddress(line.Name)
+		}
+		listing = append(listing, listingLine)
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return &Program{Binary: binaryArr, Listing: listing, SymbolTable: st}, nil
+}
+
+func TestAssembleProgramMatchesMultiPass(t *testing.T) {
+	inputs := map[string]string{
+		"generated": generateProgram(2000),
+		"forward":   "@i\n@END\n@X+1\n.equ LAST END*2\n.equ X LAST-1\n@j\n(END)\n@i+j\n@X\n0;JMP\n@k",
+	}
+	for _, asmFilename := range []string{"asm/add/Add.asm", "asm/max/Max.asm", "asm/rect/Rect.asm", "asm/pong/Pong.asm"} {
+		asm, err := ioutil.ReadFile(asmFilename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		inputs[asmFilename] = string(asm)
+	}
+	for name, input := range inputs {
+		expected, err := assembleProgramMultiPass(input, "")
+		if err != nil {
+			t.Fatalf("%s: assembleProgramMultiPass() returned error: %s", name, err)
+		}
+		program, err := AssembleProgram(input, "")
+		if err != nil {
+			t.Fatalf("%s: AssembleProgram() returned error: %s", name, err)
+		}
+		if !reflect.DeepEqual(program.Binary, expected.Binary) {
+			t.Errorf("%s: binary should be same as multi pass assembler", name)
+		}
+		if !reflect.DeepEqual(program.Listing, expected.Listing) {
+			t.Errorf("%s: listing should be same as multi pass assembler", name)
+		}
+		if !reflect.DeepEqual(program.SymbolTable.Symbols(), expected.SymbolTable.Symbols()) {
+			t.Errorf("%s: symbols should be same as multi pass assembler.\n got %v\nwant %v", name, program.SymbolTable.Symbols(), expected.SymbolTable.Symbols())
+		}
+	}
+}
+
+func benchmarkAssemble(b *testing.B, assemble func(input string, filename string) (*Program, error)) {
+	input := generateProgram(BENCHMARK_LINES)
+	b.SetBytes(int64(len(input)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := assemble(input, ""); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkAssembleProgram(b *testing.B) {
+	benchmarkAssemble(b, AssembleProgram)
+}
+
+func BenchmarkAssembleProgramMultiPass(b *testing.B) {
+	benchmarkAssemble(b, assembleProgramMultiPass)
+}
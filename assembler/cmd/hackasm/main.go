@@ -0,0 +1,166 @@
+package main
+
+import (
+	"assembler"
+	"assembler/object"
+	"assembler/rom"
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// STDIO is filename of stdin and stdout
+const STDIO = "-"
+
+type config struct {
+	outFilename  string
+	format       rom.Format
+	writeListing bool
+	writeSymbols bool
+	writeObject  bool
+	optimize     bool
+}
+
+func removeExt(filename string) string {
+	return strings.TrimSuffix(filename, filepath.Ext(filename))
+}
+
+// outputFilename returns filename of machine language or object of asmFilename
+func outputFilename(asmFilename string, conf config) string {
+	switch {
+	case conf.outFilename != "":
+		return conf.outFilename
+	case asmFilename == STDIO:
+		return STDIO
+	case conf.writeObject:
+		return removeExt(asmFilename) + ".hobj"
+	default:
+		return removeExt(asmFilename) + rom.Extension(conf.format)
+	}
+}
+
+// displayName returns name of asmFilename in messages
+func displayName(asmFilename string) string {
+	if asmFilename == STDIO {
+		return "<stdin>"
+	}
+	return asmFilename
+}
+
+func writeFile(filename string, data []byte) error {
+	if filename == STDIO {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+	return ioutil.WriteFile(filename, data, os.ModePerm)
+}
+
+// assembleFile assembles asmFilename. no file is written if assembly has errors.
+func assembleFile(asmFilename string, conf config) error {
+	var r io.Reader = os.Stdin
+	name := ""
+	if asmFilename != STDIO {
+		asm, err := os.Open(asmFilename)
+		if err != nil {
+			return err
+		}
+		defer asm.Close()
+		r, name = asm, asmFilename
+	}
+	outFilename := outputFilename(asmFilename, conf)
+	if conf.writeObject {
+		asm, err := ioutil.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		obj, err := assembler.AssembleObject(string(asm), name)
+		if err != nil {
+			return err
+		}
+		return writeFile(outFilename, []byte(object.Format(obj)))
+	}
+	var out, lst, sym, report bytes.Buffer
+	opts := assembler.Options{Filename: name, Format: conf.format, Optimize: conf.optimize, Report: &report}
+	if conf.writeListing {
+		opts.Listing = &lst
+	}
+	if conf.writeSymbols {
+		opts.Symbols = &sym
+	}
+	if err := assembler.Assemble(r, &out, opts); err != nil {
+		return err
+	}
+	if err := writeFile(outFilename, out.Bytes()); err != nil {
+		return err
+	}
+	if conf.optimize {
+		fmt.Fprintf(os.Stderr, "%s: %s", displayName(asmFilename), report.String())
+	}
+	if conf.writeListing {
+		if err := writeFile(removeExt(outFilename)+".lst", lst.Bytes()); err != nil {
+			return err
+		}
+	}
+	if conf.writeSymbols {
+		if err := writeFile(removeExt(outFilename)+".sym", sym.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [-o file] [-f format] [-lst] [-sym] [-O] [file.asm...]\n       %s -c [-o file.hobj] [file.asm...]\n", os.Args[0], os.Args[0])
+		fmt.Fprintln(os.Stderr, "assembles each file to machine language(.hack) in same dir. reads stdin and writes stdout if no file or \"-\" is given")
+		flag.PrintDefaults()
+	}
+	conf := config{}
+	flag.StringVar(&conf.outFilename, "o", "", "output file. \"-\" is stdout. only one input file can be given")
+	formatName := flag.String("f", "", "format of machine language: hack, bin, ihex, logisim, readmemb or readmemh (default: decided by extension of -o)")
+	flag.BoolVar(&conf.writeListing, "lst", false, "write listing file(.lst) next to output file")
+	flag.BoolVar(&conf.writeSymbols, "sym", false, "write symbol file(.sym) next to output file")
+	flag.BoolVar(&conf.writeObject, "c", false, "write relocatable object file(.hobj) for hacklink instead of machine language")
+	flag.BoolVar(&conf.optimize, "O", false, "remove redundant instructions by peephole optimizer and print instructions saved")
+	flag.Parse()
+	asmFilenames := flag.Args()
+	if len(asmFilenames) == 0 {
+		asmFilenames = []string{STDIO}
+	}
+	conf.format = rom.FormatOf(conf.outFilename)
+	if *formatName != "" {
+		format, err := rom.ParseFormat(*formatName)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		conf.format = format
+	}
+	// 標準出力には .lst と .sym を並べて書けない
+	toStdout := conf.outFilename == STDIO || (conf.outFilename == "" && asmFilenames[0] == STDIO)
+	if (conf.writeObject && (conf.writeListing || conf.writeSymbols || conf.optimize || *formatName != "")) ||
+		(conf.optimize && conf.writeListing) || (conf.outFilename != "" && len(asmFilenames) > 1) ||
+		((conf.writeListing || conf.writeSymbols) && toStdout) {
+		flag.Usage()
+		os.Exit(2)
+	}
+	status := 0
+	for _, asmFilename := range asmFilenames {
+		if err := assembleFile(asmFilename, conf); err != nil {
+			if errs, ok := err.(assembler.Errors); ok {
+				for _, e := range errs {
+					fmt.Fprintf(os.Stderr, "%s: %s\n", displayName(asmFilename), e)
+				}
+			} else {
+				fmt.Fprintln(os.Stderr, err)
+			}
+			status = 1
+		}
+	}
+	os.Exit(status)
+}
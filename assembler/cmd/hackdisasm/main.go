@@ -0,0 +1,44 @@
+package main
+
+import (
+	"assembler/disassembler"
+	"assembler/rom"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [-sym file.sym] [-o file.asm] [-f format] <file.hack>\n", os.Args[0])
+		fmt.Fprintln(os.Stderr, "disassembles machine language(.hack, .bin, .hex, .rom, .memb or .memh) to assembly")
+		flag.PrintDefaults()
+	}
+	symFilename := flag.String("sym", "", "symbol file(.sym) to restore labels and variables")
+	asmFilename := flag.String("o", "", "output file (default: <file>.dis.asm in same dir as .hack)")
+	formatName := flag.String("f", "", "format of input: hack, bin, ihex, logisim, readmemb or readmemh (default: decided by extension)")
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	hackFilename := flag.Arg(0)
+	if *asmFilename == "" {
+		// 元のアセンブリを上書きしないように .dis.asm にする
+		*asmFilename = strings.TrimSuffix(hackFilename, filepath.Ext(hackFilename)) + ".dis.asm"
+	}
+	format := rom.FormatOf(hackFilename)
+	if *formatName != "" {
+		var err error
+		if format, err = rom.ParseFormat(*formatName); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+	}
+	if err := disassembler.DisassembleFile(hackFilename, format, *asmFilename, *symFilename); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
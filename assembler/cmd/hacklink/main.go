@@ -0,0 +1,71 @@
+package main
+
+import (
+	"assembler/linker"
+	"assembler/object"
+	"assembler/rom"
+	"assembler/symboltable"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [-o file.hack] [-f format] [-sym] <file.hobj>...\n", os.Args[0])
+		fmt.Fprintln(os.Stderr, "links object files(.hobj) in order and writes machine language(.hack)")
+		flag.PrintDefaults()
+	}
+	hackFilename := flag.String("o", "", "output file. format is decided by its extension unless -f is given (default: <first file>.hack in same dir as first .hobj)")
+	formatName := flag.String("f", "", "format of machine language: hack, bin, ihex, logisim, readmemb or readmemh")
+	writeSymbols := flag.Bool("sym", false, "write symbol file(.sym) of linked program next to .hack")
+	flag.Parse()
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	format := rom.FormatOf(*hackFilename)
+	if *formatName != "" {
+		var err error
+		if format, err = rom.ParseFormat(*formatName); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+	}
+	objects := []*object.Object{}
+	for _, objFilename := range flag.Args() {
+		text, err := ioutil.ReadFile(objFilename)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		obj, err := object.Parse(objFilename, string(text))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		objects = append(objects, obj)
+	}
+	result, err := linker.Link(objects)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if *hackFilename == "" {
+		*hackFilename = strings.TrimSuffix(flag.Arg(0), filepath.Ext(flag.Arg(0))) + rom.Extension(format)
+	}
+	if err := ioutil.WriteFile(*hackFilename, rom.Encode(rom.Words(result.Binary), format), os.ModePerm); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if *writeSymbols {
+		symFilename := strings.TrimSuffix(*hackFilename, filepath.Ext(*hackFilename)) + ".sym"
+		if err := ioutil.WriteFile(symFilename, []byte(symboltable.FormatSymbolFile(result.SymbolTable.Symbols())), os.ModePerm); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"assembler/lint"
+	"assembler/parser"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s <file.asm>...\n", os.Args[0])
+		fmt.Fprintln(os.Stderr, "reports likely mistakes in assembly. exits with status 1 if any warning is found")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	status := 0
+	for _, asmFilename := range flag.Args() {
+		asm, err := ioutil.ReadFile(asmFilename)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		warnings, err := lint.LintAsm(string(asm), asmFilename)
+		if errs, ok := err.(parser.Errors); ok {
+			for _, e := range errs {
+				fmt.Fprintf(os.Stderr, "%s: %s\n", asmFilename, e)
+			}
+			status = 1
+			continue
+		} else if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", asmFilename, err)
+			status = 1
+			continue
+		}
+		for _, warning := range warnings {
+			fmt.Printf("%s: %s\n", asmFilename, warning)
+			status = 1
+		}
+	}
+	os.Exit(status)
+}
@@ -0,0 +1,196 @@
+package disassembler
+
+import (
+	"assembler/ast"
+	"assembler/code"
+	"assembler/rom"
+	"assembler/symboltable"
+	"assembler/value"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// IllegalInstructionError is returned for word which is not encoding of any Hack instruction
+type IllegalInstructionError struct {
+	Address int
+	Word    uint16
+	Message string
+}
+
+func (e *IllegalInstructionError) Error() string {
+	return fmt.Sprintf("address %d: %016b %s", e.Address, e.Word, e.Message)
+}
+
+// inverse tables of code.CompTable, code.DestTable and code.JumpTable
+var compMnemonics, destMnemonics, jumpMnemonics = inverse(code.CompTable), inverse(code.DestTable), inverse(code.JumpTable)
+
+func inverse(table map[string]string) map[string]string {
+	inversed := map[string]string{}
+	for mnemonic, binary := range table {
+		inversed[binary] = mnemonic
+	}
+	return inversed
+}
+
+// ParseHack parses machine language(.hack). each line has 16 "0" or "1". empty lines are ignored.
+func ParseHack(hack string) ([]uint16, error) {
+	return rom.Decode([]byte(hack), rom.HACK)
+}
+
+// DecodeCCommand decodes C instruction "111a cccc ccdd djjj"
+func DecodeCCommand(word uint16) (*ast.CCommand, error) {
+	binary := fmt.Sprintf("%016b", word)
+	if binary[:3] != "111" {
+		return nil, fmt.Errorf("C instruction should start with 111")
+	}
+	comp, ok := compMnemonics[binary[3:10]]
+	if !ok {
+		return nil, fmt.Errorf("comp %s is not defined", binary[3:10])
+	}
+	return &ast.CCommand{Comp: comp, Dest: destMnemonics[binary[10:13]], Jump: jumpMnemonics[binary[13:16]]}, nil
+}
+
+// Disassemble decodes words into commands.
+// A instruction before jump is written as label. labels are restored from symbols (e.g. parsed .sym), or "L_<address>" is used.
+// variables in symbols are restored only if assembler allocates the same address to them, so the output reassembles to the same words.
+func Disassemble(words []uint16, symbols []symboltable.Symbol) ([]ast.Command, []error) {
+	errors := []error{}
+	commands := make([]ast.Command, len(words))
+	for i, word := range words {
+		if word&0x8000 == 0 {
+			commands[i] = &ast.ACommand{Value: int(word), ValueStr: strconv.Itoa(int(word))}
+			continue
+		}
+		cCommand, err := DecodeCCommand(word)
+		if err != nil {
+			errors = append(errors, &IllegalInstructionError{Address: i, Word: word, Message: err.Error()})
+			continue
+		}
+		commands[i] = cCommand
+	}
+	if len(errors) > 0 {
+		return nil, errors
+	}
+
+	labels, variables := map[int]string{}, map[int]string{}
+	for _, symbol := range symbols {
+		switch symbol.Kind {
+		case symboltable.LABEL:
+			if _, ok := labels[symbol.Address]; !ok && symbol.Address <= len(words) {
+				labels[symbol.Address] = symbol.Name
+			}
+		case symboltable.VARIABLE:
+			if _, ok := variables[symbol.Address]; !ok {
+				variables[symbol.Address] = symbol.Name
+			}
+		}
+	}
+	// ジャンプ先のアドレスにラベルを付ける
+	for i, command := range commands {
+		aCommand, ok := command.(*ast.ACommand)
+		if !ok || !isJumpAt(commands, i+1) || aCommand.Value > len(words) {
+			continue
+		}
+		if _, ok := labels[aCommand.Value]; !ok {
+			labels[aCommand.Value] = fmt.Sprintf("L_%d", aCommand.Value)
+		}
+	}
+
+	usedVariables := map[string]bool{}
+	nextVariableAddress := 16
+	for _, command := range commands {
+		aCommand, ok := command.(*ast.ACommand)
+		if !ok {
+			continue
+		}
+		name, ok := variables[aCommand.Value]
+		switch {
+		case ok && usedVariables[name]:
+			aCommand.ValueStr = name
+		case ok && aCommand.Value == nextVariableAddress:
+			// アセンブラは最初に使われた順に16番地から変数を割り当てる
+			aCommand.ValueStr = name
+			usedVariables[name] = true
+			nextVariableAddress++
+		default:
+			if label, ok := labels[aCommand.Value]; ok {
+				aCommand.ValueStr = label
+			}
+		}
+	}
+
+	addresses := []int{}
+	for address := range labels {
+		addresses = append(addresses, address)
+	}
+	sort.Ints(addresses)
+	withLabels := []ast.Command{}
+	for i := 0; i <= len(commands); i++ {
+		for len(addresses) > 0 && addresses[0] == i {
+			withLabels = append(withLabels, &ast.LCommand{Symbol: labels[i]})
+			addresses = addresses[1:]
+		}
+		if i < len(commands) {
+			withLabels = append(withLabels, commands[i])
+		}
+	}
+	return withLabels, nil
+}
+
+func isJumpAt(commands []ast.Command, i int) bool {
+	if i >= len(commands) {
+		return false
+	}
+	cCommand, ok := commands[i].(*ast.CCommand)
+	return ok && cCommand.Jump != ""
+}
+
+// Format writes commands in assembly. each command is written in one line without indent like asm/*/*.asm
+func Format(commands []ast.Command) string {
+	var out strings.Builder
+	for _, command := range commands {
+		out.WriteString(strings.TrimSuffix(command.String(), value.NEW_LINE) + value.NEW_LINE)
+	}
+	return out.String()
+}
+
+// DisassembleHackFile disassembles .hack and writes assembly to asmFilename. symFilename is optional.
+// format of hackFilename is decided by its extension. e.g. Intel HEX for .hex
+func DisassembleHackFile(hackFilename string, asmFilename string, symFilename string) error {
+	return DisassembleFile(hackFilename, rom.FormatOf(hackFilename), asmFilename, symFilename)
+}
+
+// DisassembleFile disassembles ROM image written in format and writes assembly to asmFilename. symFilename is optional.
+func DisassembleFile(romFilename string, format rom.Format, asmFilename string, symFilename string) error {
+	data, err := ioutil.ReadFile(romFilename)
+	if err != nil {
+		return err
+	}
+	words, err := rom.Decode(data, format)
+	if err != nil {
+		return fmt.Errorf("%s: %s", romFilename, err)
+	}
+	symbols := []symboltable.Symbol{}
+	if symFilename != "" {
+		sym, err := ioutil.ReadFile(symFilename)
+		if err != nil {
+			return err
+		}
+		if symbols, err = symboltable.ParseSymbolFile(string(sym)); err != nil {
+			return fmt.Errorf("%s: %s", symFilename, err)
+		}
+	}
+	commands, errs := Disassemble(words, symbols)
+	if len(errs) > 0 {
+		messages := []string{}
+		for _, err := range errs {
+			messages = append(messages, fmt.Sprintf("%s: %s", romFilename, err))
+		}
+		return fmt.Errorf("%s", strings.Join(messages, value.LF))
+	}
+	return ioutil.WriteFile(asmFilename, []byte(Format(commands)), os.ModePerm)
+}
@@ -0,0 +1,110 @@
+package disassembler
+
+import (
+	"assembler/ast"
+	"assembler/symboltable"
+	"assembler/value"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func readRect(t *testing.T) []uint16 {
+	hack, err := ioutil.ReadFile("../../hardware/computer/Rect.hack")
+	if err != nil {
+		t.Fatal(err)
+	}
+	words, err := ParseHack(string(hack))
+	if err != nil {
+		t.Fatalf("ParseHack() returned error: %s", err)
+	}
+	return words
+}
+
+func TestDisassembleWithSymbols(t *testing.T) {
+	symbols := []symboltable.Symbol{
+		{Kind: symboltable.PREDEFINED, Address: 16384, Name: "SCREEN"},
+		{Kind: symboltable.LABEL, Address: 10, Name: "LOOP"},
+		{Kind: symboltable.LABEL, Address: 23, Name: "INFINITE_LOOP"},
+		{Kind: symboltable.VARIABLE, Address: 16, Name: "counter"},
+		{Kind: symboltable.VARIABLE, Address: 17, Name: "address"},
+	}
+	commands, errs := Disassemble(readRect(t), symbols)
+	if len(errs) > 0 {
+		t.Fatalf("Disassemble() returned errors: %v", errs)
+	}
+	expected := []string{
+		"@0", "D=M", "@INFINITE_LOOP", "D;JLE", "@counter", "M=D", "@16384", "D=A", "@address", "M=D",
+		"(LOOP)", "@address", "A=M", "M=-1", "@address", "D=M", "@32", "D=D+A", "@address", "M=D",
+		"@counter", "MD=M-1", "@LOOP", "D;JGT", "(INFINITE_LOOP)", "@INFINITE_LOOP", "0;JMP",
+	}
+	if len(commands) != len(expected) {
+		t.Fatalf("Disassemble() should return %d commands. got %d", len(expected), len(commands))
+	}
+	for i, command := range commands {
+		if actual := strings.TrimSuffix(command.String(), value.NEW_LINE); actual != expected[i] {
+			t.Errorf("command %d should be %s. got %s", i, expected[i], actual)
+		}
+	}
+}
+
+func TestDisassembleVariableOrder(t *testing.T) {
+	// 変数は最初に使われた順に割り当てられるので、j(17) が i(16) より先に使われる場合は名前を復元できない
+	words := []uint16{17, 0xFC10, 16, 0xE308}
+	symbols := []symboltable.Symbol{
+		{Kind: symboltable.VARIABLE, Address: 16, Name: "i"},
+		{Kind: symboltable.VARIABLE, Address: 17, Name: "j"},
+	}
+	commands, errs := Disassemble(words, symbols)
+	if len(errs) > 0 {
+		t.Fatalf("Disassemble() returned errors: %v", errs)
+	}
+	expected := "@17" + value.NEW_LINE + "D=M" + value.NEW_LINE + "@i" + value.NEW_LINE + "M=D" + value.NEW_LINE
+	if actual := Format(commands); actual != expected {
+		t.Fatalf("Format() should be %q. got %q", expected, actual)
+	}
+}
+
+func TestDecodeCCommand(t *testing.T) {
+	testCases := []struct {
+		word     uint16
+		expected ast.CCommand
+	}{
+		{0xEC10, ast.CCommand{Comp: "A", Dest: "D"}},
+		{0xEA87, ast.CCommand{Comp: "0", Jump: "JMP"}},
+		{0xF56F, ast.CCommand{Comp: "D|M", Dest: "AM", Jump: "JMP"}},
+	}
+	for _, tt := range testCases {
+		cCommand, err := DecodeCCommand(tt.word)
+		if err != nil {
+			t.Fatalf("DecodeCCommand(%016b) returned error: %s", tt.word, err)
+		}
+		if *cCommand != tt.expected {
+			t.Errorf("DecodeCCommand(%016b) should be %+v. got %+v", tt.word, tt.expected, *cCommand)
+		}
+	}
+}
+
+func TestDisassembleErrors(t *testing.T) {
+	words := []uint16{0, 0xC000, 0xE040}
+	_, errs := Disassemble(words, nil)
+	expected := []string{
+		"address 1: 1100000000000000 C instruction should start with 111",
+		"address 2: 1110000001000000 comp 0000001 is not defined",
+	}
+	if len(errs) != len(expected) {
+		t.Fatalf("Disassemble() should return %d errors. got %v", len(expected), errs)
+	}
+	for i, err := range errs {
+		if err.Error() != expected[i] {
+			t.Errorf("error should be %q. got %q", expected[i], err)
+		}
+	}
+}
+
+func TestParseHackError(t *testing.T) {
+	_, err := ParseHack("0000000000000000\r\n000000000000001\r\n")
+	if err == nil || err.Error() != `line 2: "000000000000001" should have 16 bits of 0 and 1` {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,100 @@
+package linker
+
+import (
+	"assembler/object"
+	"assembler/symboltable"
+	"assembler/value"
+	"fmt"
+	"strings"
+)
+
+// ROM_SIZE is number of words which Hack computer can load
+const ROM_SIZE = 32768
+
+// MAX_VALUE is max value which A command can load
+const MAX_VALUE = 32767
+
+// INITIAL_VARIABLE_ADDRESS is RAM address of first variable
+const INITIAL_VARIABLE_ADDRESS = 16
+
+// Result is linked program
+type Result struct {
+	Binary      []string
+	SymbolTable *symboltable.SymbolTable // predefined symbols, exported labels and constants of all modules and variables
+}
+
+// Link merges objects in order into a program.
+// imported symbols are resolved by exports of other modules, and it is an error if no module exports them.
+// variables are also resolved by exports, and others are allocated from 16 in order of first use like assembling concatenated modules.
+func Link(objects []*object.Object) (*Result, error) {
+	st := symboltable.New()
+	errs := []string{}
+	exportedBy := map[string]string{}
+	bases := make([]int, len(objects))
+	size := 0
+	for i, obj := range objects {
+		bases[i] = size
+		size += len(obj.Code)
+		for _, export := range obj.Exports {
+			if st.Contains(export.Name) {
+				module, ok := exportedBy[export.Name]
+				if !ok {
+					module = "predefined symbol"
+				}
+				errs = append(errs, fmt.Sprintf("%s: symbol %s is already defined by %s", obj.Name, export.Name, module))
+				continue
+			}
+			exportedBy[export.Name] = obj.Name
+			if export.Kind == symboltable.LABEL {
+				st.AddLabel(export.Name, bases[i]+export.Value)
+			} else {
+				st.AddConstant(export.Name, export.Value)
+			}
+		}
+	}
+	if size > ROM_SIZE {
+		errs = append(errs, fmt.Sprintf("program has %d words. ROM has only %d words", size, ROM_SIZE))
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("%s", strings.Join(errs, value.LF))
+	}
+
+	nextVariableAddress := INITIAL_VARIABLE_ADDRESS
+	binaryArr := make([]string, 0, size)
+	for i, obj := range objects {
+		// 変数ではないシンボルがどのモジュールにも無ければ、書き間違いなのでエラーにする
+		imported := map[string]bool{}
+		for _, symbol := range obj.Imports {
+			imported[symbol] = true
+			if !st.Contains(symbol) {
+				errs = append(errs, fmt.Sprintf("%s: symbol %s is not exported by any module", obj.Name, symbol))
+			}
+		}
+		for address, word := range obj.Code {
+			resolved := word.Value
+			switch word.Kind {
+			case object.RELATIVE:
+				resolved = bases[i] + word.Value
+			case object.SYMBOL:
+				if imported[word.Symbol] && !st.Contains(word.Symbol) {
+					continue
+				}
+				if !st.Contains(word.Symbol) {
+					st.AddVariable(word.Symbol, nextVariableAddress)
+					nextVariableAddress++
+				}
+				symbolValue, _ := st.GetAddress(word.Symbol)
+				resolved = symbolValue + word.Value
+			}
+			if word.Kind != object.ABSOLUTE && (resolved < 0 || resolved > MAX_VALUE) {
+				errs = append(errs, fmt.Sprintf("%s: address %d: %d is out of range 0..%d", obj.Name, address, resolved, MAX_VALUE))
+				continue
+			}
+			binaryArr = append(binaryArr, fmt.Sprintf("%016b", resolved))
+		}
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("%s", strings.Join(errs, value.LF))
+	}
+	return &Result{Binary: binaryArr, SymbolTable: st}, nil
+}
@@ -0,0 +1,94 @@
+package linker
+
+import (
+	"assembler/object"
+	"assembler/symboltable"
+	"fmt"
+	"testing"
+)
+
+func TestLink(t *testing.T) {
+	main := &object.Object{
+		Name: "Main.hobj",
+		Code: []object.Word{
+			{Kind: object.SYMBOL, Symbol: "FUNC", Value: 0},
+			{Kind: object.ABSOLUTE, Value: 0xEA87}, // 0;JMP
+			{Kind: object.SYMBOL, Symbol: "i", Value: 0},
+			{Kind: object.RELATIVE, Value: 1},
+		},
+		Exports:   []object.Export{{Kind: symboltable.LABEL, Name: "MAIN", Value: 0}},
+		Imports:   []string{"FUNC"},
+		Variables: []string{"i"},
+	}
+	sub := &object.Object{
+		Name: "Sub.hobj",
+		Code: []object.Word{
+			{Kind: object.SYMBOL, Symbol: "j", Value: 0},
+			{Kind: object.SYMBOL, Symbol: "i", Value: 1},
+			{Kind: object.RELATIVE, Value: 0},
+			{Kind: object.SYMBOL, Symbol: "MAIN", Value: 0},
+		},
+		Exports:   []object.Export{{Kind: symboltable.LABEL, Name: "FUNC", Value: 1}, {Kind: symboltable.CONSTANT, Name: "ROW", Value: 32}},
+		Imports:   []string{"MAIN"},
+		Variables: []string{"j", "i"},
+	}
+	result, err := Link([]*object.Object{main, sub})
+	if err != nil {
+		t.Fatalf("Link() returned error: %s", err)
+	}
+	expected := []int{5, 0xEA87, 16, 1, 17, 17, 4, 0}
+	if len(result.Binary) != len(expected) {
+		t.Fatalf("Link() should return %d words. got %d", len(expected), len(result.Binary))
+	}
+	for i, word := range expected {
+		if result.Binary[i] != fmt.Sprintf("%016b", word) {
+			t.Errorf("word %d should be %016b. got %s", i, word, result.Binary[i])
+		}
+	}
+	if kind, _ := result.SymbolTable.Kind("j"); kind != symboltable.VARIABLE {
+		t.Errorf("j should be variable. got %s", kind)
+	}
+	if address, _ := result.SymbolTable.GetAddress("FUNC"); address != 5 {
+		t.Errorf("FUNC should be 5. got %d", address)
+	}
+}
+
+func TestLinkError(t *testing.T) {
+	testCases := []struct {
+		objects  []*object.Object
+		expected string
+	}{
+		{
+			[]*object.Object{
+				{Name: "A.hobj", Exports: []object.Export{{Kind: symboltable.LABEL, Name: "LOOP"}}},
+				{Name: "B.hobj", Exports: []object.Export{{Kind: symboltable.LABEL, Name: "LOOP"}}},
+			},
+			"B.hobj: symbol LOOP is already defined by A.hobj",
+		},
+		{
+			[]*object.Object{{Name: "A.hobj", Exports: []object.Export{{Kind: symboltable.CONSTANT, Name: "SCREEN"}}}},
+			"A.hobj: symbol SCREEN is already defined by predefined symbol",
+		},
+		{
+			[]*object.Object{{Name: "A.hobj", Code: []object.Word{{Kind: object.SYMBOL, Symbol: "KBD", Value: 10000}}}},
+			"A.hobj: address 0: 34576 is out of range 0..32767",
+		},
+		{
+			[]*object.Object{
+				{Name: "A.hobj", Code: []object.Word{{Kind: object.SYMBOL, Symbol: "LOOOP", Value: 0}}, Imports: []string{"LOOOP"}},
+				{Name: "B.hobj", Exports: []object.Export{{Kind: symboltable.LABEL, Name: "LOOP"}}},
+			},
+			"A.hobj: symbol LOOOP is not exported by any module",
+		},
+		{
+			[]*object.Object{{Name: "A.hobj", Code: make([]object.Word, ROM_SIZE+1)}},
+			"program has 32769 words. ROM has only 32768 words",
+		},
+	}
+	for _, tt := range testCases {
+		_, err := Link(tt.objects)
+		if err == nil || err.Error() != tt.expected {
+			t.Errorf("Link() should return %q. got %v", tt.expected, err)
+		}
+	}
+}
@@ -0,0 +1,290 @@
+package lint
+
+import (
+	"assembler/ast"
+	"assembler/parser"
+	"assembler/symboltable"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type WarningType string
+
+const (
+	UNUSED_LABEL       WarningType = "UNUSED_LABEL"       // label is defined but never referred
+	UNUSED_A_VALUE     WarningType = "UNUSED_A_VALUE"     // value loaded to A is overwritten before use
+	LIKELY_TYPO        WarningType = "LIKELY_TYPO"        // variable has name similar to label or constant
+	M_AFTER_LABEL      WarningType = "M_AFTER_LABEL"      // M is used after A is loaded with ROM address
+	UNREACHABLE_CODE   WarningType = "UNREACHABLE_CODE"   // code after unconditional jump which no label can reach
+	VARIABLE_IN_SCREEN WarningType = "VARIABLE_IN_SCREEN" // variable is allocated to SCREEN region
+)
+
+// SCREEN is first address of screen memory map. variables are allocated from 16 to SCREEN-1.
+const SCREEN = 16384
+
+// INITIAL_VARIABLE_ADDRESS is RAM address of first variable
+const INITIAL_VARIABLE_ADDRESS = 16
+
+// Warning is a mistake found by Lint
+type Warning struct {
+	Type    WarningType
+	Source  parser.SourceLine
+	Message string
+}
+
+func (w *Warning) String() string {
+	message := fmt.Sprintf("line %d: %s: %s (%s)", w.Source.Line, w.Source.Text, w.Message, w.Type)
+	if w.Source.Macro != "" {
+		message = fmt.Sprintf("line %d: %s (in macro %s): %s (%s)", w.Source.Line, w.Source.Text, w.Source.Macro, w.Message, w.Type)
+	}
+	if w.Source.Filename != "" {
+		message = w.Source.Filename + ": " + message
+	}
+	return message
+}
+
+// Lint checks statements and returns warnings in order of line
+func Lint(statements []parser.Statement, st *symboltable.SymbolTable) []*Warning {
+	warnings := []*Warning{}
+	warnings = append(warnings, checkLabels(statements)...)
+	warnings = append(warnings, checkAValues(statements, st)...)
+	warnings = append(warnings, checkUnreachableCode(statements)...)
+	warnings = append(warnings, checkVariables(statements, st)...)
+	return sortWarnings(statements, warnings)
+}
+
+// LintAsm parses and checks assembly
+func LintAsm(input string, filename string) ([]*Warning, error) {
+	statements, st, err := parser.ParseStatements(input, filename)
+	if err != nil {
+		return nil, err
+	}
+	return Lint(statements, st), nil
+}
+
+// sortWarnings sorts warnings in order of statements. order of warnings of same statement is kept.
+func sortWarnings(statements []parser.Statement, warnings []*Warning) []*Warning {
+	sorted := []*Warning{}
+	used := make([]bool, len(warnings))
+	for _, statement := range statements {
+		for i, warning := range warnings {
+			if !used[i] && warning.Source == statement.Source {
+				sorted = append(sorted, warning)
+				used[i] = true
+			}
+		}
+	}
+	return sorted
+}
+
+// referredSymbols returns symbols used by A commands
+func referredSymbols(statements []parser.Statement) map[string]bool {
+	referred := map[string]bool{}
+	for _, statement := range statements {
+		if aCommand, ok := statement.Command.(*ast.ACommand); ok {
+			for _, symbol := range parser.ExpressionSymbols(aCommand.ValueStr) {
+				referred[symbol] = true
+			}
+		}
+	}
+	return referred
+}
+
+func checkLabels(statements []parser.Statement) []*Warning {
+	warnings := []*Warning{}
+	referred := referredSymbols(statements)
+	for _, statement := range statements {
+		if lCommand, ok := statement.Command.(*ast.LCommand); ok && !referred[lCommand.Symbol] {
+			warnings = append(warnings, &Warning{UNUSED_LABEL, statement.Source, fmt.Sprintf("label %s is never referred", lCommand.Symbol)})
+		}
+	}
+	return warnings
+}
+
+// usesA returns whether C command reads A register. M and jump use A as address.
+func usesA(cCommand *ast.CCommand) bool {
+	return strings.ContainsAny(cCommand.Comp, "AM") || strings.Contains(cCommand.Dest, "M") || cCommand.Jump != ""
+}
+
+func isLabel(st *symboltable.SymbolTable, symbol string) bool {
+	kind, _ := st.Kind(symbol)
+	return kind == symboltable.LABEL
+}
+
+func checkAValues(statements []parser.Statement, st *symboltable.SymbolTable) []*Warning {
+	warnings := []*Warning{}
+	for i, statement := range statements {
+		aCommand, ok := statement.Command.(*ast.ACommand)
+		if !ok {
+			continue
+		}
+		if i+1 < len(statements) {
+			if cCommand, ok := statements[i+1].Command.(*ast.CCommand); ok && isLabel(st, aCommand.ValueStr) &&
+				(strings.Contains(cCommand.Comp, "M") || strings.Contains(cCommand.Dest, "M")) {
+				warnings = append(warnings, &Warning{M_AFTER_LABEL, statements[i+1].Source,
+					fmt.Sprintf("M is RAM[%s] but %s is ROM address", aCommand.ValueStr, aCommand.ValueStr)})
+			}
+		}
+		// ラベルまでの直線コードで A が使われる前に上書きされるか調べる
+		for _, next := range statements[i+1:] {
+			if _, ok := next.Command.(*ast.LCommand); ok {
+				break
+			}
+			if cCommand, ok := next.Command.(*ast.CCommand); ok {
+				if usesA(cCommand) {
+					break
+				}
+				if !strings.Contains(cCommand.Dest, "A") {
+					continue
+				}
+			}
+			warnings = append(warnings, &Warning{UNUSED_A_VALUE, statement.Source,
+				fmt.Sprintf("@%s is overwritten by %q before use", aCommand.ValueStr, next.Source.Text)})
+			break
+		}
+	}
+	return warnings
+}
+
+func checkUnreachableCode(statements []parser.Statement) []*Warning {
+	warnings := []*Warning{}
+	// "@133" "D=A" のように数値で読み込まれるアドレスは、戻りアドレスとしてラベルがなくても到達できる
+	targets := numericValues(statements)
+	addresses := romAddresses(statements)
+	isEntry := func(i int) bool {
+		if _, ok := statements[i].Command.(*ast.LCommand); ok {
+			return true
+		}
+		return targets[addresses[i]]
+	}
+	for i := 0; i < len(statements); i++ {
+		cCommand, ok := statements[i].Command.(*ast.CCommand)
+		if !ok || !isUnconditionalJump(cCommand) || i+1 >= len(statements) || isEntry(i+1) {
+			continue
+		}
+		warnings = append(warnings, &Warning{UNREACHABLE_CODE, statements[i+1].Source, fmt.Sprintf("no label can reach code after %q", statements[i].Source.Text)})
+		// 次のラベルか数値アドレスまでは同じ到達不能コード
+		for i+1 < len(statements) && !isEntry(i+1) {
+			i++
+		}
+	}
+	return warnings
+}
+
+// numericValues returns values of A commands with numeric constant like "@133"
+func numericValues(statements []parser.Statement) map[int]bool {
+	values := map[int]bool{}
+	for _, statement := range statements {
+		if aCommand, ok := statement.Command.(*ast.ACommand); ok && isNumeric(aCommand.ValueStr) {
+			values[aCommand.Value] = true
+		}
+	}
+	return values
+}
+
+// romAddresses returns ROM address of each statement. label has address of next command.
+func romAddresses(statements []parser.Statement) []int {
+	addresses := make([]int, len(statements))
+	address := 0
+	for i, statement := range statements {
+		addresses[i] = address
+		if _, ok := statement.Command.(*ast.LCommand); !ok {
+			address++
+		}
+	}
+	return addresses
+}
+
+func isNumeric(valueStr string) bool {
+	_, err := strconv.Atoi(valueStr)
+	return err == nil
+}
+
+// isUnconditionalJump returns whether C command always jumps. e.g. "0;JMP", "D;JMP"
+func isUnconditionalJump(cCommand *ast.CCommand) bool {
+	return cCommand.Jump == "JMP"
+}
+
+func checkVariables(statements []parser.Statement, st *symboltable.SymbolTable) []*Warning {
+	warnings := []*Warning{}
+	names := []string{}
+	for _, symbol := range st.Symbols() {
+		if symbol.Kind == symboltable.LABEL || symbol.Kind == symboltable.CONSTANT {
+			names = append(names, symbol.Name)
+		}
+	}
+	allocated := map[string]bool{}
+	nextVariableAddress := INITIAL_VARIABLE_ADDRESS
+	for _, statement := range statements {
+		aCommand, ok := statement.Command.(*ast.ACommand)
+		// アセンブラと同じく、単独で使われた未定義のシンボルだけが変数になる
+		if !ok || !parser.IsSymbol(aCommand.ValueStr) || st.Contains(aCommand.ValueStr) || allocated[aCommand.ValueStr] {
+			continue
+		}
+		variable := aCommand.ValueStr
+		allocated[variable] = true
+		for _, name := range names {
+			if isSimilar(variable, name) {
+				warnings = append(warnings, &Warning{LIKELY_TYPO, statement.Source, fmt.Sprintf("variable %s is allocated but looks like typo of %s", variable, name)})
+				break
+			}
+		}
+		if nextVariableAddress == SCREEN {
+			warnings = append(warnings, &Warning{VARIABLE_IN_SCREEN, statement.Source,
+				fmt.Sprintf("variable %s is allocated to %d in SCREEN region. only %d variables can be allocated", variable, SCREEN, SCREEN-INITIAL_VARIABLE_ADDRESS)})
+		}
+		nextVariableAddress++
+	}
+	return warnings
+}
+
+// isSimilar returns whether a and b differ only in case or by one edit(insertion, deletion, substitution or transposition)
+func isSimilar(a string, b string) bool {
+	if a == b {
+		return false
+	}
+	if strings.EqualFold(a, b) {
+		return true
+	}
+	if len(a) < 3 || len(b) < 3 {
+		// i と j のような短い名前は似ていても意図的なことが多い
+		return false
+	}
+	return editDistance(a, b) <= 1
+}
+
+// editDistance returns Damerau-Levenshtein distance(optimal string alignment)
+func editDistance(a string, b string) int {
+	d := make([][]int, len(a)+1)
+	for i := range d {
+		d[i] = make([]int, len(b)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(a)][len(b)]
+}
+
+func min(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
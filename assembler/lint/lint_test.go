@@ -0,0 +1,114 @@
+package lint
+
+import "testing"
+
+func TestLintAsm(t *testing.T) {
+	testCases := []struct {
+		asm      string
+		expected []string
+	}{
+		{"(LOOP)\n@LOOP\n0;JMP", []string{}},
+		{"(LOOP)\n@i\nM=0\n(END)\n@END\n0;JMP", []string{"line 1: (LOOP): label LOOP is never referred (UNUSED_LABEL)"}},
+		{"@i\n@j\nM=0", []string{`line 1: @i: @i is overwritten by "@j" before use (UNUSED_A_VALUE)`}},
+		{"@5\nD=D+1\nA=D\nM=0", []string{`line 1: @5: @5 is overwritten by "A=D" before use (UNUSED_A_VALUE)`}},
+		{"@5\nD=A\n@6\nAM=M+1\n@7\n0;JMP", []string{}},
+		{"(LOOP)\n@LOOOP\nM=0\n@LOOP\n0;JMP", []string{"line 2: @LOOOP: variable LOOOP is allocated but looks like typo of LOOP (LIKELY_TYPO)"}},
+		{".equ ROW 32\n@row\nM=0\n@ROW\nD=A", []string{"line 2: @row: variable row is allocated but looks like typo of ROW (LIKELY_TYPO)"}},
+		{"(LOOP)\n@LOOP\nD=M\n@LOOP\nD;JGT", []string{"line 3: D=M: M is RAM[LOOP] but LOOP is ROM address (M_AFTER_LABEL)"}},
+		{"(END)\n@END\n0;JMP\n@i\nM=0\nM=1\n(NEXT)\n@NEXT\n0;JMP", []string{`line 4: @i: no label can reach code after "0;JMP" (UNREACHABLE_CODE)`}},
+		{"@2\n0;JMP\n@i\nM=0\n(END)\n@END\n0;JMP", []string{}},
+		{"@3\n0;JMP\n@i\nM=0\n(END)\n@END\n0;JMP", []string{`line 3: @i: no label can reach code after "0;JMP" (UNREACHABLE_CODE)`}},
+		{"@4\n0;JMP\n@i\nM=0\n(END)\n@END\n0;JMP", []string{`line 3: @i: no label can reach code after "0;JMP" (UNREACHABLE_CODE)`}},
+	}
+	for _, tt := range testCases {
+		warnings, err := LintAsm(tt.asm, "")
+		if err != nil {
+			t.Fatalf("LintAsm(%q) returned error: %s", tt.asm, err)
+		}
+		if len(warnings) != len(tt.expected) {
+			t.Fatalf("LintAsm(%q) should return %d warnings. got %v", tt.asm, len(tt.expected), warnings)
+		}
+		for i, warning := range warnings {
+			if warning.String() != tt.expected[i] {
+				t.Fatalf("warning should be %q. got %q", tt.expected[i], warning.String())
+			}
+		}
+	}
+}
+
+func TestLintReturnAddressIsReachable(t *testing.T) {
+	// vmtranslator の call のように、戻りアドレスを数値で push してからジャンプする
+	asm := `@6
+D=A
+@R13
+M=D
+@FUNC
+0;JMP
+D=M
+@R14
+M=D
+@END
+0;JMP
+@i
+M=0
+(FUNC)
+@R13
+A=M
+0;JMP
+(END)
+@END
+0;JMP`
+	warnings, err := LintAsm(asm, "")
+	if err != nil {
+		t.Fatalf("LintAsm() returned error: %s", err)
+	}
+	expected := `line 12: @i: no label can reach code after "0;JMP" (UNREACHABLE_CODE)`
+	if len(warnings) != 1 || warnings[0].String() != expected {
+		t.Fatalf("only code after return address should be reported as %q. got %v", expected, warnings)
+	}
+}
+
+func TestLintVariableInScreen(t *testing.T) {
+	asm := ""
+	for i := 0; i <= SCREEN-INITIAL_VARIABLE_ADDRESS; i++ {
+		asm += "@v" + string(rune('a'+i/26/26%26)) + string(rune('a'+i/26%26)) + string(rune('a'+i%26)) + "\nM=0\n"
+	}
+	warnings, err := LintAsm(asm, "")
+	if err != nil {
+		t.Fatalf("LintAsm() returned error: %s", err)
+	}
+	screenWarnings := []*Warning{}
+	for _, warning := range warnings {
+		if warning.Type == VARIABLE_IN_SCREEN {
+			screenWarnings = append(screenWarnings, warning)
+		}
+	}
+	if len(screenWarnings) != 1 || screenWarnings[0].Source.Line != 2*(SCREEN-INITIAL_VARIABLE_ADDRESS)+1 {
+		t.Fatalf("VARIABLE_IN_SCREEN should be reported once at line %d. got %v", 2*(SCREEN-INITIAL_VARIABLE_ADDRESS)+1, screenWarnings)
+	}
+}
+
+func TestLintParseError(t *testing.T) {
+	if _, err := LintAsm("D=Q", ""); err == nil {
+		t.Fatalf("LintAsm() should return error for invalid command")
+	}
+}
+
+func TestIsSimilar(t *testing.T) {
+	testCases := []struct {
+		a, b     string
+		expected bool
+	}{
+		{"LOOOP", "LOOP", true},
+		{"LOPO", "LOOP", true},
+		{"END", "END", false},
+		{"i", "j", false},
+		{"loop", "LOOP", true},
+		{"START", "STOP", false},
+	}
+	for _, tt := range testCases {
+		if isSimilar(tt.a, tt.b) != tt.expected {
+			t.Fatalf("isSimilar(%q, %q) should be %t", tt.a, tt.b, tt.expected)
+		}
+	}
+}
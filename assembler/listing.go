@@ -0,0 +1,47 @@
+package assembler
+
+import (
+	"assembler/value"
+	"fmt"
+	"strings"
+)
+
+// ListingLine is a line of listing file(.lst). Address is -1 if the line has no instruction.
+type ListingLine struct {
+	Filename string // file included by "#include"
+	Line     int
+	Source   string
+	Macro    string // name of macro if the line is expanded from macro call
+	Address  int
+	Word     string
+	Symbol   string // symbol used by A command or defined by label
+	Value    int    // resolved value of Symbol
+}
+
+// FormatListing writes listing as columns of ROM address, word, line number and source.
+// resolved value of symbol is written after source. e.g. "0002  0000000000001010     3  @LOOP  // LOOP=10"
+// lines expanded from macro are written with "+" after the macro call, and lines of included file have its filename in line number.
+func FormatListing(listing []ListingLine) string {
+	var out strings.Builder
+	out.WriteString(fmt.Sprintf("%-5s %-16s %5s  %s", "ADDR", "WORD", "LINE", "SOURCE") + value.NEW_LINE)
+	for _, line := range listing {
+		address := ""
+		if line.Address >= 0 {
+			address = fmt.Sprintf("%04d", line.Address)
+		}
+		lineNumber := fmt.Sprint(line.Line)
+		if line.Filename != "" {
+			lineNumber = fmt.Sprintf("%s:%d", line.Filename, line.Line)
+		}
+		source := line.Source
+		if line.Macro != "" {
+			source = "+ " + source
+		}
+		text := fmt.Sprintf("%-5s %-16s %5s  %s", address, line.Word, lineNumber, source)
+		if line.Symbol != "" {
+			text += fmt.Sprintf("  // %s=%d", line.Symbol, line.Value)
+		}
+		out.WriteString(strings.TrimRight(text, " ") + value.NEW_LINE)
+	}
+	return out.String()
+}
@@ -0,0 +1,48 @@
+package assembler
+
+import (
+	"assembler/value"
+	"strings"
+	"testing"
+)
+
+func TestFormatListing(t *testing.T) {
+	program, err := AssembleProgram("// count\r\n@i\r\nM=1\r\n(LOOP)\r\n@LOOP\r\n0;JMP", "")
+	if err != nil {
+		t.Fatalf("AssembleProgram() returned error: %s", err)
+	}
+	expected := []string{
+		"ADDR  WORD              LINE  SOURCE",
+		"                           1  // count",
+		"0000  0000000000010000     2  @i  // i=16",
+		"0001  1110111111001000     3  M=1",
+		"                           4  (LOOP)  // LOOP=2",
+		"0002  0000000000000010     5  @LOOP  // LOOP=2",
+		"0003  1110101010000111     6  0;JMP",
+	}
+	listing := FormatListing(program.Listing)
+	if listing != strings.Join(expected, value.NEW_LINE)+value.NEW_LINE {
+		t.Fatalf("FormatListing() should be\n%s\ngot\n%s", strings.Join(expected, "\n"), listing)
+	}
+}
+
+func TestFormatListingMacro(t *testing.T) {
+	program, err := AssembleProgram(".macro INC address\r\n@\\address\r\nM=M+1\r\n.endm\r\nINC R0", "")
+	if err != nil {
+		t.Fatalf("AssembleProgram() returned error: %s", err)
+	}
+	expected := []string{
+		"ADDR  WORD              LINE  SOURCE",
+		"                           1  .macro INC address",
+		"                           2  @\\address",
+		"                           3  M=M+1",
+		"                           4  .endm",
+		"                           5  INC R0",
+		"0000  0000000000000000     5  + @R0  // R0=0",
+		"0001  1111110111001000     5  + M=M+1",
+	}
+	listing := FormatListing(program.Listing)
+	if listing != strings.Join(expected, value.NEW_LINE)+value.NEW_LINE {
+		t.Fatalf("FormatListing() should be\n%s\ngot\n%s", strings.Join(expected, "\n"), listing)
+	}
+}
@@ -0,0 +1,145 @@
+package object
+
+import (
+	"assembler/symboltable"
+	"assembler/value"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// HEADER is first line of object file
+// version 2 separates imported symbols, which other modules should export, from variables.
+const HEADER = "HACKOBJ 2"
+
+type WordKind string
+
+const (
+	ABSOLUTE WordKind = "word" // word which is not changed by linker
+	RELATIVE WordKind = "rel"  // A command whose value is address in module. linker adds start address of module
+	SYMBOL   WordKind = "sym"  // A command whose value is address of imported symbol or variable + Value
+)
+
+// Word is a word of code in object file
+type Word struct {
+	Kind   WordKind
+	Value  int    // word of ABSOLUTE, address in module of RELATIVE or addend of SYMBOL
+	Symbol string // imported symbol or variable of SYMBOL
+}
+
+// Export is label or constant declared by .global, which other modules can refer
+type Export struct {
+	Kind  symboltable.SymbolKind // LABEL or CONSTANT
+	Name  string
+	Value int // address in module of LABEL or value of CONSTANT
+}
+
+// Object is relocatable module assembled from .asm
+type Object struct {
+	Name      string // name used in error message. e.g. filename
+	Code      []Word
+	Exports   []Export
+	Imports   []string // symbols which other modules should export. declared by .extern
+	Variables []string // symbols allocated as variables by linker unless other module exports them
+}
+
+func New(name string) *Object {
+	return &Object{Name: name, Code: []Word{}, Exports: []Export{}, Imports: []string{}, Variables: []string{}}
+}
+
+// Format writes object file(.hobj).
+//
+//	HACKOBJ 2
+//	export label LOOP 2
+//	export constant ROW 32
+//	import FUNC
+//	variable i
+//	word 0000000000010000
+//	rel 2
+//	sym i 1
+func Format(obj *Object) string {
+	var out strings.Builder
+	out.WriteString(HEADER + value.NEW_LINE)
+	for _, export := range obj.Exports {
+		out.WriteString(fmt.Sprintf("export %s %s %d", export.Kind, export.Name, export.Value) + value.NEW_LINE)
+	}
+	for _, symbol := range obj.Imports {
+		out.WriteString(fmt.Sprintf("import %s", symbol) + value.NEW_LINE)
+	}
+	for _, symbol := range obj.Variables {
+		out.WriteString(fmt.Sprintf("variable %s", symbol) + value.NEW_LINE)
+	}
+	for _, word := range obj.Code {
+		switch word.Kind {
+		case ABSOLUTE:
+			out.WriteString(fmt.Sprintf("%s %016b", word.Kind, word.Value) + value.NEW_LINE)
+		case RELATIVE:
+			out.WriteString(fmt.Sprintf("%s %d", word.Kind, word.Value) + value.NEW_LINE)
+		case SYMBOL:
+			out.WriteString(fmt.Sprintf("%s %s %d", word.Kind, word.Symbol, word.Value) + value.NEW_LINE)
+		}
+	}
+	return out.String()
+}
+
+// Parse reads object file written by Format. name is used in error message.
+func Parse(name string, text string) (*Object, error) {
+	obj := New(name)
+	lines := strings.Split(text, value.LF)
+	if strings.TrimSpace(lines[0]) != HEADER {
+		return nil, fmt.Errorf("%s: line 1: object file should start with %q", name, HEADER)
+	}
+	declared := map[string]bool{} // imported symbols and variables
+	for i, line := range lines[1:] {
+		lineNumber := i + 2
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		errorf := func(format string, a ...interface{}) error {
+			return fmt.Errorf("%s: line %d: %s", name, lineNumber, fmt.Sprintf(format, a...))
+		}
+		switch {
+		case fields[0] == "export" && len(fields) == 4:
+			kind := symboltable.SymbolKind(fields[1])
+			if kind != symboltable.LABEL && kind != symboltable.CONSTANT {
+				return nil, errorf("unknown export kind %q", fields[1])
+			}
+			exportValue, err := strconv.Atoi(fields[3])
+			if err != nil {
+				return nil, errorf("invalid value %q", fields[3])
+			}
+			obj.Exports = append(obj.Exports, Export{Kind: kind, Name: fields[2], Value: exportValue})
+		case fields[0] == "import" && len(fields) == 2:
+			obj.Imports = append(obj.Imports, fields[1])
+			declared[fields[1]] = true
+		case fields[0] == "variable" && len(fields) == 2:
+			obj.Variables = append(obj.Variables, fields[1])
+			declared[fields[1]] = true
+		case fields[0] == string(ABSOLUTE) && len(fields) == 2:
+			word, err := strconv.ParseUint(fields[1], 2, 16)
+			if err != nil || len(fields[1]) != 16 {
+				return nil, errorf("%q should have 16 bits of 0 and 1", fields[1])
+			}
+			obj.Code = append(obj.Code, Word{Kind: ABSOLUTE, Value: int(word)})
+		case fields[0] == string(RELATIVE) && len(fields) == 2:
+			address, err := strconv.Atoi(fields[1])
+			if err != nil {
+				return nil, errorf("invalid address %q", fields[1])
+			}
+			obj.Code = append(obj.Code, Word{Kind: RELATIVE, Value: address})
+		case fields[0] == string(SYMBOL) && len(fields) == 3:
+			if !declared[fields[1]] {
+				return nil, errorf("symbol %s is neither imported nor variable", fields[1])
+			}
+			addend, err := strconv.Atoi(fields[2])
+			if err != nil {
+				return nil, errorf("invalid addend %q", fields[2])
+			}
+			obj.Code = append(obj.Code, Word{Kind: SYMBOL, Symbol: fields[1], Value: addend})
+		default:
+			return nil, errorf("invalid line %q", strings.TrimSpace(line))
+		}
+	}
+	return obj, nil
+}
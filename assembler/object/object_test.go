@@ -0,0 +1,58 @@
+package object
+
+import (
+	"assembler/symboltable"
+	"testing"
+)
+
+func TestFormatAndParse(t *testing.T) {
+	obj := &Object{
+		Name:      "Main.asm",
+		Code:      []Word{{Kind: ABSOLUTE, Value: 16}, {Kind: RELATIVE, Value: 2}, {Kind: SYMBOL, Symbol: "i", Value: 1}, {Kind: SYMBOL, Symbol: "FUNC", Value: 0}},
+		Exports:   []Export{{Kind: symboltable.LABEL, Name: "LOOP", Value: 2}, {Kind: symboltable.CONSTANT, Name: "ROW", Value: 32}},
+		Imports:   []string{"FUNC"},
+		Variables: []string{"i"},
+	}
+	text := Format(obj)
+	expected := "HACKOBJ 2\r\nexport label LOOP 2\r\nexport constant ROW 32\r\nimport FUNC\r\nvariable i\r\nword 0000000000010000\r\nrel 2\r\nsym i 1\r\nsym FUNC 0\r\n"
+	if text != expected {
+		t.Fatalf("Format() should be %q. got %q", expected, text)
+	}
+	parsed, err := Parse("Main.hobj", text)
+	if err != nil {
+		t.Fatalf("Parse() returned error: %s", err)
+	}
+	if len(parsed.Code) != len(obj.Code) || len(parsed.Exports) != len(obj.Exports) || len(parsed.Imports) != len(obj.Imports) || len(parsed.Variables) != len(obj.Variables) {
+		t.Fatalf("Parse() should return %+v. got %+v", obj, parsed)
+	}
+	for i := range obj.Code {
+		if parsed.Code[i] != obj.Code[i] {
+			t.Errorf("word should be %+v. got %+v", obj.Code[i], parsed.Code[i])
+		}
+	}
+	for i := range obj.Exports {
+		if parsed.Exports[i] != obj.Exports[i] {
+			t.Errorf("export should be %+v. got %+v", obj.Exports[i], parsed.Exports[i])
+		}
+	}
+}
+
+func TestParseError(t *testing.T) {
+	testCases := []struct {
+		text     string
+		expected string
+	}{
+		{"word 0000000000000000", `Main.hobj: line 1: object file should start with "HACKOBJ 2"`},
+		{"HACKOBJ 1\nimport i", `Main.hobj: line 1: object file should start with "HACKOBJ 2"`},
+		{"HACKOBJ 2\nword 101", `Main.hobj: line 2: "101" should have 16 bits of 0 and 1`},
+		{"HACKOBJ 2\nsym i 0", "Main.hobj: line 2: symbol i is neither imported nor variable"},
+		{"HACKOBJ 2\nexport variable i 16", `Main.hobj: line 2: unknown export kind "variable"`},
+		{"HACKOBJ 2\nhoge", `Main.hobj: line 2: invalid line "hoge"`},
+	}
+	for _, tt := range testCases {
+		_, err := Parse("Main.hobj", tt.text)
+		if err == nil || err.Error() != tt.expected {
+			t.Errorf("Parse(%q) should return %q. got %v", tt.text, tt.expected, err)
+		}
+	}
+}
@@ -0,0 +1,372 @@
+package optimizer
+
+import (
+	"assembler/ast"
+	"assembler/parser"
+	"assembler/symboltable"
+	"assembler/value"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type Rule string
+
+const (
+	REDUNDANT_LOAD   Rule = "REDUNDANT_LOAD"   // A or D is loaded with value which it already has
+	DEAD_STORE       Rule = "DEAD_STORE"       // A, D or M is overwritten before use
+	STACK_ROUND_TRIP Rule = "STACK_ROUND_TRIP" // M=M+1 followed by M=M-1 like push followed by pop
+	JUMP_TO_NEXT     Rule = "JUMP_TO_NEXT"     // jump to next instruction
+)
+
+// rules is order of rules in report
+var rules = []Rule{REDUNDANT_LOAD, DEAD_STORE, STACK_ROUND_TRIP, JUMP_TO_NEXT}
+
+// Report is number of instructions saved by Optimize
+type Report struct {
+	Before  int // number of instructions(labels are not counted) before optimization
+	After   int
+	Removed map[Rule]int // number of removed instructions by rule
+}
+
+func (r *Report) Saved() int {
+	return r.Before - r.After
+}
+
+// String returns summary of report. e.g. "100 -> 80 instructions (20 saved: REDUNDANT_LOAD 12, DEAD_STORE 8)"
+func (r *Report) String() string {
+	removed := []string{}
+	for _, rule := range rules {
+		if r.Removed[rule] > 0 {
+			removed = append(removed, fmt.Sprintf("%s %d", rule, r.Removed[rule]))
+		}
+	}
+	summary := fmt.Sprintf("%d -> %d instructions (%d saved", r.Before, r.After, r.Saved())
+	if len(removed) > 0 {
+		summary += ": " + strings.Join(removed, ", ")
+	}
+	return summary + ")"
+}
+
+// Optimize removes instructions which do not change RAM, jumps and values of registers used later.
+// labels are kept, so commands should refer labels alone like "@LOOP" because addresses of instructions change.
+func Optimize(commands []ast.Command) ([]ast.Command, *Report) {
+	report := &Report{Before: countInstructions(commands), Removed: map[Rule]int{}}
+	passes := []struct {
+		rule Rule
+		pass func([]ast.Command) []ast.Command
+	}{
+		{REDUNDANT_LOAD, removeRedundantLoads},
+		{STACK_ROUND_TRIP, removeStackRoundTrips},
+		{DEAD_STORE, removeDeadStores},
+		{JUMP_TO_NEXT, removeJumpsToNext},
+	}
+	// 一つの削除で別の削除ができるようになるので、変化がなくなるまで繰り返す
+	for changed := true; changed; {
+		changed = false
+		for _, p := range passes {
+			before := countInstructions(commands)
+			commands = p.pass(commands)
+			if removed := before - countInstructions(commands); removed > 0 {
+				report.Removed[p.rule] += removed
+				changed = true
+			}
+		}
+	}
+	report.After = countInstructions(commands)
+	return commands, report
+}
+
+// OptimizeAsm parses and optimizes assembly. macros, includes and .equ are expanded in output.
+func OptimizeAsm(input string, filename string) (string, *Report, error) {
+	statements, st, err := parser.ParseStatements(input, filename)
+	if err != nil {
+		return "", nil, err
+	}
+	commands := make([]ast.Command, 0, len(statements))
+	for _, statement := range statements {
+		if aCommand, ok := statement.Command.(*ast.ACommand); ok {
+			valueStr, err := operand(aCommand, st)
+			if err != nil {
+				return "", nil, fmt.Errorf("line %d: %s: %s", statement.Source.Line, statement.Source.Text, err)
+			}
+			statement.Command = &ast.ACommand{Value: aCommand.Value, ValueStr: valueStr}
+		}
+		commands = append(commands, statement.Command)
+	}
+	optimized, report := Optimize(commands)
+	return Format(optimized), report, nil
+}
+
+// operand returns value of A command in output. constants and expressions are replaced with their value
+// because .equ is not written, and label can not be used in expression because its address changes.
+func operand(aCommand *ast.ACommand, st *symboltable.SymbolTable) (string, error) {
+	if kind, ok := st.Kind(aCommand.ValueStr); parser.IsSymbol(aCommand.ValueStr) && (!ok || kind == symboltable.PREDEFINED || kind == symboltable.LABEL) {
+		return aCommand.ValueStr, nil
+	}
+	for _, symbol := range parser.ExpressionSymbols(aCommand.ValueStr) {
+		if kind, _ := st.Kind(symbol); kind == symboltable.LABEL {
+			return "", fmt.Errorf("address of label %s changes by optimization. label should be used alone", symbol)
+		}
+	}
+	return strconv.Itoa(aCommand.Value), nil
+}
+
+// Format writes commands as assembly
+func Format(commands []ast.Command) string {
+	var out strings.Builder
+	for _, command := range commands {
+		out.WriteString(strings.TrimSuffix(command.String(), value.NEW_LINE) + value.NEW_LINE)
+	}
+	return out.String()
+}
+
+func countInstructions(commands []ast.Command) int {
+	count := 0
+	for _, command := range commands {
+		if _, ok := command.(*ast.LCommand); !ok {
+			count++
+		}
+	}
+	return count
+}
+
+// readsA returns whether C command uses A register. M and jump use A as address.
+func readsA(c *ast.CCommand) bool {
+	return strings.ContainsAny(c.Comp, "AM") || strings.Contains(c.Dest, "M") || c.Jump != ""
+}
+
+func reads(c *ast.CCommand, register string) bool {
+	if register == "A" {
+		return readsA(c)
+	}
+	return strings.Contains(c.Comp, register)
+}
+
+// isDead returns whether value of register is overwritten before use from commands[from].
+// labels and jumps are regarded as use because other instructions can continue from there.
+func isDead(commands []ast.Command, from int, register string) bool {
+	for _, command := range commands[from:] {
+		switch c := command.(type) {
+		case *ast.LCommand:
+			return false
+		case *ast.ACommand:
+			if register == "A" {
+				return true
+			}
+		case *ast.CCommand:
+			if reads(c, register) || c.Jump != "" {
+				return false
+			}
+			if strings.Contains(c.Dest, register) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// removeRedundantLoads removes "@X" when A already has X, and "D=M" right after "M=D".
+func removeRedundantLoads(commands []ast.Command) []ast.Command {
+	optimized := make([]ast.Command, 0, len(commands))
+	knownA := ""
+	for i, command := range commands {
+		switch c := command.(type) {
+		case *ast.LCommand:
+			knownA = ""
+		case *ast.ACommand:
+			if c.ValueStr == knownA {
+				continue
+			}
+			knownA = c.ValueStr
+		case *ast.CCommand:
+			if c.Dest == "D" && c.Comp == "M" && c.Jump == "" && i > 0 {
+				if prev, ok := commands[i-1].(*ast.CCommand); ok && (prev.Dest == "M" || prev.Dest == "MD") && prev.Comp == "D" && prev.Jump == "" {
+					continue
+				}
+			}
+			if strings.Contains(c.Dest, "A") || c.Jump != "" {
+				knownA = ""
+			}
+		}
+		optimized = append(optimized, command)
+	}
+	return optimized
+}
+
+// removeDeadStores removes A commands and C commands which write only registers overwritten before use,
+// and "M=..." right before other instruction writes M.
+func removeDeadStores(commands []ast.Command) []ast.Command {
+	optimized := make([]ast.Command, 0, len(commands))
+	for i, command := range commands {
+		switch c := command.(type) {
+		case *ast.ACommand:
+			if isDead(commands, i+1, "A") && !allocatesVariable(commands, i) {
+				continue
+			}
+		case *ast.CCommand:
+			// M=0 の直後の M=-1 のように、同じアドレスにすぐ書き込まれる
+			if c.Dest == "M" && c.Jump == "" && i+1 < len(commands) {
+				if next, ok := commands[i+1].(*ast.CCommand); ok && strings.Contains(next.Dest, "M") && !strings.Contains(next.Comp, "M") {
+					continue
+				}
+			}
+			if c.Jump == "" && !strings.Contains(c.Dest, "M") {
+				dead := true
+				for _, register := range c.Dest {
+					dead = dead && isDead(commands, i+1, string(register))
+				}
+				if dead {
+					continue
+				}
+			}
+		}
+		optimized = append(optimized, command)
+	}
+	return optimized
+}
+
+// allocatesVariable returns whether A command of commands[i] may be first use of variable.
+// removing it changes addresses of variables allocated after it.
+func allocatesVariable(commands []ast.Command, i int) bool {
+	symbol := commands[i].(*ast.ACommand).ValueStr
+	if !parser.IsSymbol(symbol) || symboltable.New().Contains(symbol) {
+		return false
+	}
+	for j, command := range commands {
+		switch c := command.(type) {
+		case *ast.LCommand:
+			if c.Symbol == symbol {
+				return false
+			}
+		case *ast.ACommand:
+			if j < i && c.ValueStr == symbol {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// cancels are comps of "M=M+1" and "M=M-1" which cancel each other
+var cancels = map[string]string{"M+1": "M-1", "M-1": "M+1"}
+
+// removeStackRoundTrips removes "M=M+1" which is cancelled by "M=M-1" of same address later in straight-line code, and vice versa.
+// the decrement can also load the pointer like "AM=M-1" of pop, and it becomes "A=M".
+// pointer read between them like "A=M" is replaced with "A=M+1", so push followed by pop or add like
+// "@SP", "M=M+1", "@SP", "A=M", "A=A-1", "D=M", ..., "@SP", "M=M-1" does not change SP.
+// RAM accessed through the pointer is assumed not to be the pointer itself, like stack which does not overlap SP.
+func removeStackRoundTrips(commands []ast.Command) []ast.Command {
+	optimized := make([]ast.Command, 0, len(commands))
+	for i := 0; i < len(commands); i++ {
+		if replaced, end, ok := cancelRoundTrip(commands, i); ok {
+			optimized = append(optimized, replaced...)
+			i = end
+			continue
+		}
+		optimized = append(optimized, commands[i])
+	}
+	return optimized
+}
+
+// cancelRoundTrip returns commands[i+1:end+1] without the round trip which starts from commands[i].
+// commands[i] should be "M=M+1" or "M=M-1" right after "@X".
+func cancelRoundTrip(commands []ast.Command, i int) ([]ast.Command, int, bool) {
+	c, ok := commands[i].(*ast.CCommand)
+	if !ok || c.Dest != "M" || c.Jump != "" || i == 0 {
+		return nil, 0, false
+	}
+	cancel, ok := cancels[c.Comp]
+	pointer, isA := commands[i-1].(*ast.ACommand)
+	if !ok || !isA {
+		return nil, 0, false
+	}
+	replaced := []ast.Command{}
+	a := pointer.ValueStr // A の値が分かっているときのシンボル
+	for j := i + 1; j < len(commands); j++ {
+		switch next := commands[j].(type) {
+		case *ast.LCommand:
+			return nil, 0, false
+		case *ast.ACommand:
+			a = next.ValueStr
+			if sameAddress(a, pointer.ValueStr) { // @R0 は @SP と同じアドレス
+				a = pointer.ValueStr
+			}
+			replaced = append(replaced, next)
+		case *ast.CCommand:
+			if next.Jump != "" {
+				return nil, 0, false
+			}
+			if a != pointer.ValueStr {
+				replaced = append(replaced, next)
+				break
+			}
+			if next.Comp == cancel && strings.Contains(next.Dest, "M") {
+				// "AM=M-1" は M を戻して A に読み込むだけになる
+				if dest := strings.Replace(next.Dest, "M", "", 1); dest != "" {
+					replaced = append(replaced, &ast.CCommand{Dest: dest, Comp: "M"})
+				}
+				return replaced, j, true
+			}
+			// 間の push のような同じ向きの M=M+1 はずれを保つ
+			if next.Dest == "M" && next.Comp == c.Comp {
+				replaced = append(replaced, next)
+				break
+			}
+			if strings.Contains(next.Dest, "M") || strings.Contains(next.Comp, "M") && next.Comp != "M" {
+				return nil, 0, false
+			}
+			if next.Comp == "M" { // 取り除いた M=M+1 の分を読むときに足す
+				next = &ast.CCommand{Dest: next.Dest, Comp: c.Comp}
+			}
+			if strings.Contains(next.Dest, "A") {
+				a = ""
+			}
+			replaced = append(replaced, next)
+		}
+	}
+	return nil, 0, false
+}
+
+// predefined has only predefined symbols like SP and R0
+var predefined = symboltable.New()
+
+// sameAddress returns whether operands are the same predefined symbol or number like "SP", "R0" and "0"
+func sameAddress(a string, b string) bool {
+	address := func(operand string) (int, bool) {
+		if value, err := strconv.Atoi(operand); err == nil {
+			return value, true
+		}
+		value, err := predefined.GetAddress(operand)
+		return value, err == nil
+	}
+	addressA, okA := address(a)
+	addressB, okB := address(b)
+	return okA && okB && addressA == addressB
+}
+
+// removeJumpsToNext removes "@L", "0;JMP" followed by "(L)" if value of A is not used after "(L)".
+func removeJumpsToNext(commands []ast.Command) []ast.Command {
+	optimized := make([]ast.Command, 0, len(commands))
+	for i := 0; i < len(commands); i++ {
+		if aCommand, ok := commands[i].(*ast.ACommand); ok && i+1 < len(commands) {
+			if c, ok := commands[i+1].(*ast.CCommand); ok && c.Dest == "" && c.Jump != "" {
+				next, target := i+2, false
+				for ; next < len(commands); next++ {
+					lCommand, ok := commands[next].(*ast.LCommand)
+					if !ok {
+						break
+					}
+					target = target || lCommand.Symbol == aCommand.ValueStr
+				}
+				// ラベルの先で A が使われるなら、ジャンプで A に入るラベルのアドレスが必要
+				if target && isDead(commands, next, "A") {
+					i++
+					continue
+				}
+			}
+		}
+		optimized = append(optimized, commands[i])
+	}
+	return optimized
+}
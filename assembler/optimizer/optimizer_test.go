@@ -0,0 +1,287 @@
+package optimizer
+
+import (
+	"assembler/ast"
+	"assembler/code"
+	"assembler/parser"
+	"assembler/symboltable"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// vmProgram is assembly written by vmtranslator for
+// push constant 7, push constant 8, add, pop temp 0, push temp 0, push constant 15, eq, if-goto END, push constant 1, (END)
+const vmProgram = `@256
+D=A
+@SP
+M=D
+@7
+D=A
+@SP
+A=M
+M=D
+@SP
+M=M+1
+@8
+D=A
+@SP
+A=M
+M=D
+@SP
+M=M+1
+@SP
+A=M
+A=A-1
+D=M
+A=A-1
+M=M+D
+@SP
+M=M-1
+@0
+D=A
+@5
+D=D+A
+@temp
+M=D
+@SP
+A=M
+A=A-1
+D=M
+@temp
+A=M
+M=D
+@SP
+M=M-1
+@0
+D=A
+@5
+A=A+D
+D=M
+@SP
+A=M
+M=D
+@SP
+M=M+1
+@15
+D=A
+@SP
+A=M
+M=D
+@SP
+M=M+1
+@SP
+M=M-1
+A=M
+D=M
+@SP
+M=M-1
+A=M
+D=M-D
+@TRUE1
+D;JEQ
+@SP
+A=M
+M=0
+@NEXT1
+0;JMP
+(TRUE1)
+@SP
+A=M
+M=0
+M=-1
+(NEXT1)
+@SP
+M=M+1
+@SP
+M=M-1
+A=M
+D=M
+@END
+D;JNE
+@1
+D=A
+@SP
+A=M
+M=D
+@SP
+M=M+1
+@END
+0;JMP
+(END)
+@END
+0;JMP
+`
+
+// run executes commands until it reaches END label and returns RAM
+func run(t *testing.T, commands []ast.Command) []int16 {
+	st := symboltable.New()
+	address := 0
+	for _, command := range commands {
+		if lCommand, ok := command.(*ast.LCommand); ok {
+			st.AddLabel(lCommand.Symbol, address)
+			continue
+		}
+		address++
+	}
+	rom := []ast.Command{}
+	nextVariableAddress := 16
+	for _, command := range commands {
+		switch c := command.(type) {
+		case *ast.ACommand:
+			if value, err := strconv.Atoi(c.ValueStr); err == nil {
+				rom = append(rom, &ast.ACommand{Value: value})
+				continue
+			}
+			if !st.Contains(c.ValueStr) {
+				st.AddVariable(c.ValueStr, nextVariableAddress)
+				nextVariableAddress++
+			}
+			value, _ := st.GetAddress(c.ValueStr)
+			rom = append(rom, &ast.ACommand{Value: value})
+		case *ast.CCommand:
+			rom = append(rom, c)
+		}
+	}
+	end, _ := st.GetAddress("END")
+	ram := make([]int16, 1024)
+	var a, d int16
+	for pc, steps := 0, 0; pc != end; steps++ {
+		if steps > 10000 || pc >= len(rom) {
+			t.Fatalf("program should reach END")
+		}
+		c, ok := rom[pc].(*ast.CCommand)
+		if !ok {
+			a = int16(rom[pc].(*ast.ACommand).Value)
+			pc++
+			continue
+		}
+		bits := code.Binary(c)
+		x, y := d, a
+		if bits[3] == '1' {
+			y = ram[a]
+		}
+		alu := bits[4:10]
+		if alu[0] == '1' {
+			x = 0
+		}
+		if alu[1] == '1' {
+			x = ^x
+		}
+		if alu[2] == '1' {
+			y = 0
+		}
+		if alu[3] == '1' {
+			y = ^y
+		}
+		out := x & y
+		if alu[4] == '1' {
+			out = x + y
+		}
+		if alu[5] == '1' {
+			out = ^out
+		}
+		if strings.Contains(c.Dest, "M") {
+			ram[a] = out
+		}
+		jump := c.Jump == "JMP" || out < 0 && strings.Contains(c.Jump, "L") || out == 0 && (strings.Contains(c.Jump, "E") && c.Jump != "JNE") ||
+			out > 0 && (c.Jump == "JGT" || c.Jump == "JGE" || c.Jump == "JNE") || out != 0 && c.Jump == "JNE"
+		target := int(a)
+		if strings.Contains(c.Dest, "A") {
+			a = out
+		}
+		if strings.Contains(c.Dest, "D") {
+			d = out
+		}
+		if jump {
+			pc = target
+		} else {
+			pc++
+		}
+	}
+	return ram
+}
+
+func parse(t *testing.T, asm string) []ast.Command {
+	statements, _, err := parser.ParseStatements(asm, "")
+	if err != nil {
+		t.Fatalf("ParseStatements() returned error: %s", err)
+	}
+	commands := []ast.Command{}
+	for _, statement := range statements {
+		commands = append(commands, statement.Command)
+	}
+	return commands
+}
+
+func TestOptimizeKeepsBehavior(t *testing.T) {
+	commands := parse(t, vmProgram)
+	optimized, report := Optimize(commands)
+	if report.Saved() <= 0 || report.Before != countInstructions(commands) || report.After != countInstructions(optimized) {
+		t.Fatalf("report is wrong: %s", report)
+	}
+	expected, got := run(t, commands), run(t, optimized)
+	for address := range expected {
+		if expected[address] != got[address] {
+			t.Fatalf("RAM[%d] should be %d. got %d\n%s", address, expected[address], got[address], Format(optimized))
+		}
+	}
+}
+
+func TestOptimize(t *testing.T) {
+	testCases := []struct {
+		asm      string
+		expected string
+	}{
+		// push してすぐ pop する
+		{"@SP\nA=M\nM=D\n@SP\nM=M+1\n@SP\nM=M-1\nA=M\nD=M\n(END)", "@SP\nA=M\nM=D\n@SP\nA=M\nD=M\n(END)\n"},
+		{"@SP\nM=M+1\n@SP\nAM=M-1\nD=M\n@R0\nM=D\n(END)", "@SP\nA=M\nD=M\n@R0\nM=D\n(END)\n"},
+		// push して add する。SP を戻さずに SP+1 を読む
+		{"@SP\nA=M\nM=D\n@SP\nM=M+1\n@SP\nA=M\nA=A-1\nD=M\nA=A-1\nM=M+D\n@SP\nM=M-1\n(END)", "@SP\nA=M\nM=D\n@SP\nA=M+1\nA=A-1\nD=M\nA=A-1\nM=D+M\n@SP\n(END)\n"},
+		{"@SP\nM=M+1\n@R0\nD=M\n@SP\nM=M-1\n(END)", "@R0\nD=M+1\n@SP\n(END)\n"},
+		{"@SP\nM=M+1\n@SP\nM=M+D\n@SP\nM=M-1\n(END)", "@SP\nM=M+1\nM=D+M\nM=M-1\n(END)\n"},
+		{"@SP\nM=D\nD=M\n@R0\nM=D\n(END)", "@SP\nM=D\n@R0\nM=D\n(END)\n"},
+		{"@7\nD=A\n@8\nD=A\n@R0\nM=D\n(END)", "@8\nD=A\n@R0\nM=D\n(END)\n"},
+		{"@i\n@j\nM=0\n(END)", "@i\n@j\nM=0\n(END)\n"},
+		{"@i\nM=0\n@R1\n@j\nM=0\n@i\n@j\nM=1\n(END)", "@i\nM=0\n@j\nM=1\n(END)\n"},
+		{"@SP\nA=M\nM=0\nM=-1\nM=M+1\n(END)", "@SP\nA=M\nM=-1\nM=M+1\n(END)\n"},
+		{"@NEXT\n0;JMP\n(NEXT)\n@R0\nM=0", "(NEXT)\n@R0\nM=0\n"},
+		{"@NEXT\nD;JGT\n(NEXT)\nD=A\n(END)", "@NEXT\nD;JGT\n(NEXT)\nD=A\n(END)\n"},
+		{"D=A\n(LOOP)\n@LOOP\nD;JGT", "D=A\n(LOOP)\n@LOOP\nD;JGT\n"},
+	}
+	for _, tt := range testCases {
+		optimized, _ := Optimize(parse(t, tt.asm))
+		if got := strings.ReplaceAll(Format(optimized), "\r\n", "\n"); got != tt.expected {
+			t.Fatalf("Optimize(%q) should be %q. got %q", tt.asm, tt.expected, got)
+		}
+	}
+}
+
+func TestOptimizeAsm(t *testing.T) {
+	optimized, report, err := OptimizeAsm(".equ ROW 32\n@SCREEN+ROW\nD=A\n@ROW\nD=D+A\n@R0\nM=D", "")
+	if err != nil {
+		t.Fatalf("OptimizeAsm() returned error: %s", err)
+	}
+	expected := "@16416\r\nD=A\r\n@32\r\nD=D+A\r\n@R0\r\nM=D\r\n"
+	if optimized != expected || report.String() != "6 -> 6 instructions (0 saved)" {
+		t.Fatalf("OptimizeAsm() should return %q, got %q (%s)", expected, optimized, report)
+	}
+	if _, _, err := OptimizeAsm("(LOOP)\n@LOOP+1\n0;JMP", ""); err == nil {
+		t.Fatalf("OptimizeAsm() should return error for label in expression")
+	}
+}
+
+func TestOptimizePong(t *testing.T) {
+	asm, err := ioutil.ReadFile("../asm/pong/Pong.asm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, report, err := OptimizeAsm(string(asm), "")
+	if err != nil {
+		t.Fatalf("OptimizeAsm() returned error: %s", err)
+	}
+	if report.Saved() <= 0 {
+		t.Fatalf("Pong should be optimized: %s", report)
+	}
+}
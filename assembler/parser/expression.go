@@ -0,0 +1,266 @@
+package parser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type expressionTokenType string
+
+const (
+	NUMBER_TOKEN   expressionTokenType = "NUMBER"
+	SYMBOL_TOKEN   expressionTokenType = "SYMBOL"
+	OPERATOR_TOKEN expressionTokenType = "OPERATOR"
+)
+
+type expressionToken struct {
+	Type    expressionTokenType
+	Literal string
+	Value   int // value of NUMBER
+}
+
+// expressionError is error of expression. it is converted to ParseError of the line.
+type expressionError struct {
+	Type    ErrorType
+	Message string
+}
+
+func (e *expressionError) Error() string {
+	return e.Message
+}
+
+// IsSymbol returns whether str can be used as symbol. symbol consists of letters, digits, "_", ".", "$" and ":" and does not start with digit.
+func IsSymbol(str string) bool {
+	return str != "" && !isNumber(str[0]) && isSymbol(str)
+}
+
+// tokenizeExpression splits operand of A command. e.g. "SCREEN+0x20" -> "SCREEN", "+", "0x20"
+func tokenizeExpression(expression string) ([]expressionToken, error) {
+	tokens := []expressionToken{}
+	for i := 0; i < len(expression); {
+		c := expression[i]
+		switch {
+		case strings.IndexByte("+-*/&|()", c) != -1:
+			tokens = append(tokens, expressionToken{Type: OPERATOR_TOKEN, Literal: string(c)})
+			i++
+		case c == '\'':
+			// 文字リテラル 'A' は文字コードになる
+			if i+2 >= len(expression) || expression[i+2] != '\'' {
+				return nil, &expressionError{INVALID_SYMBOL, fmt.Sprintf("character literal in %s should be one character in ''", expression)}
+			}
+			tokens = append(tokens, expressionToken{Type: NUMBER_TOKEN, Literal: expression[i : i+3], Value: int(expression[i+1])})
+			i += 3
+		case isNumber(c):
+			start := i
+			for i < len(expression) && (isLetter(expression[i]) || isNumber(expression[i])) {
+				i++
+			}
+			literal := expression[start:i]
+			value, err := parseNumber(literal)
+			if err != nil {
+				return nil, &expressionError{INVALID_SYMBOL, fmt.Sprintf("%s is neither number nor symbol", literal)}
+			}
+			tokens = append(tokens, expressionToken{Type: NUMBER_TOKEN, Literal: literal, Value: value})
+		case isSymbol(string(c)):
+			start := i
+			for i < len(expression) && isSymbol(string(expression[i])) {
+				i++
+			}
+			tokens = append(tokens, expressionToken{Type: SYMBOL_TOKEN, Literal: expression[start:i]})
+		default:
+			return nil, &expressionError{INVALID_SYMBOL, fmt.Sprintf("symbol %s has illegal character", expression)}
+		}
+	}
+	return tokens, nil
+}
+
+// parseNumber parses decimal, hex(0x4000) and binary(0b1010)
+func parseNumber(literal string) (int, error) {
+	base, digits := 10, literal
+	switch {
+	case strings.HasPrefix(literal, "0x") || strings.HasPrefix(literal, "0X"):
+		base, digits = 16, literal[2:]
+	case strings.HasPrefix(literal, "0b") || strings.HasPrefix(literal, "0B"):
+		base, digits = 2, literal[2:]
+	}
+	value, err := strconv.ParseInt(digits, base, 32)
+	return int(value), err
+}
+
+// expressionParser evaluates expression by recursive descent.
+// precedence is "|" < "&" < "+", "-" < "*", "/" < unary "-" like C.
+type expressionParser struct {
+	tokens   []expressionToken
+	position int
+	resolve  func(symbol string) (int, bool)
+}
+
+// evaluate evaluates constant expression. resolve returns value of symbol.
+func evaluate(expression string, resolve func(symbol string) (int, bool)) (int, error) {
+	tokens, err := tokenizeExpression(expression)
+	if err != nil {
+		return 0, err
+	}
+	ep := &expressionParser{tokens: tokens, resolve: resolve}
+	value, err := ep.parseBinary(0)
+	if err != nil {
+		return 0, err
+	}
+	if ep.position < len(ep.tokens) {
+		return 0, &expressionError{INVALID_COMMAND, fmt.Sprintf("unexpected %q in %s", ep.tokens[ep.position].Literal, expression)}
+	}
+	return value, nil
+}
+
+var binaryOperators = [][]string{{"|"}, {"&"}, {"+", "-"}, {"*", "/"}}
+
+func (ep *expressionParser) current() (expressionToken, bool) {
+	if ep.position >= len(ep.tokens) {
+		return expressionToken{}, false
+	}
+	return ep.tokens[ep.position], true
+}
+
+func (ep *expressionParser) isOperator(operators ...string) (string, bool) {
+	tok, ok := ep.current()
+	if !ok || tok.Type != OPERATOR_TOKEN {
+		return "", false
+	}
+	for _, operator := range operators {
+		if tok.Literal == operator {
+			return operator, true
+		}
+	}
+	return "", false
+}
+
+func (ep *expressionParser) parseBinary(precedence int) (int, error) {
+	if precedence == len(binaryOperators) {
+		return ep.parseUnary()
+	}
+	left, err := ep.parseBinary(precedence + 1)
+	if err != nil {
+		return 0, err
+	}
+	for {
+		operator, ok := ep.isOperator(binaryOperators[precedence]...)
+		if !ok {
+			return left, nil
+		}
+		ep.position++
+		right, err := ep.parseBinary(precedence + 1)
+		if err != nil {
+			return 0, err
+		}
+		switch operator {
+		case "|":
+			left |= right
+		case "&":
+			left &= right
+		case "+":
+			left += right
+		case "-":
+			left -= right
+		case "*":
+			left *= right
+		case "/":
+			if right == 0 {
+				return 0, &expressionError{INVALID_COMMAND, "division by zero"}
+			}
+			left /= right
+		}
+	}
+}
+
+func (ep *expressionParser) parseUnary() (int, error) {
+	if _, ok := ep.isOperator("-"); ok {
+		ep.position++
+		value, err := ep.parseUnary()
+		return -value, err
+	}
+	return ep.parsePrimary()
+}
+
+func (ep *expressionParser) parsePrimary() (int, error) {
+	tok, ok := ep.current()
+	if !ok {
+		return 0, &expressionError{INVALID_COMMAND, "expression is not completed"}
+	}
+	ep.position++
+	switch {
+	case tok.Type == NUMBER_TOKEN:
+		return tok.Value, nil
+	case tok.Type == SYMBOL_TOKEN:
+		value, ok := ep.resolve(tok.Literal)
+		if !ok {
+			return 0, &expressionError{UNDEFINED_SYMBOL, fmt.Sprintf("symbol %s is not defined", tok.Literal)}
+		}
+		return value, nil
+	case tok.Literal == "(":
+		value, err := ep.parseBinary(0)
+		if err != nil {
+			return 0, err
+		}
+		if _, ok := ep.isOperator(")"); !ok {
+			return 0, &expressionError{INVALID_COMMAND, "\"(\" is not closed by \")\""}
+		}
+		ep.position++
+		return value, nil
+	default:
+		return 0, &expressionError{INVALID_COMMAND, fmt.Sprintf("unexpected %q", tok.Literal)}
+	}
+}
+
+// renameSymbols replaces symbols in expression by rename. it is used to rename labels in macro.
+func renameSymbols(expression string, rename func(symbol string) string) string {
+	tokens, err := tokenizeExpression(expression)
+	if err != nil {
+		return expression
+	}
+	renamed := ""
+	for _, tok := range tokens {
+		if tok.Type == SYMBOL_TOKEN {
+			renamed += rename(tok.Literal)
+		} else {
+			renamed += tok.Literal
+		}
+	}
+	return renamed
+}
+
+// ExpressionSymbols returns symbols used in expression. nil is returned if expression has illegal character.
+func ExpressionSymbols(expression string) []string {
+	tokens, err := tokenizeExpression(expression)
+	if err != nil {
+		return nil
+	}
+	symbols := []string{}
+	for _, tok := range tokens {
+		if tok.Type == SYMBOL_TOKEN {
+			symbols = append(symbols, tok.Literal)
+		}
+	}
+	return symbols
+}
+
+// SplitRelocatable splits "SYMBOL", "SYMBOL+addend" or "SYMBOL-addend" into symbol and expression of addend.
+// addend should not have "|" and "&" whose precedence is lower than "+".
+func SplitRelocatable(expression string) (string, string, bool) {
+	tokens, err := tokenizeExpression(expression)
+	if err != nil || len(tokens) == 0 || tokens[0].Type != SYMBOL_TOKEN {
+		return "", "", false
+	}
+	if len(tokens) == 1 {
+		return tokens[0].Literal, "0", true
+	}
+	if tokens[1].Literal != "+" && tokens[1].Literal != "-" {
+		return "", "", false
+	}
+	for _, tok := range tokens[2:] {
+		if tok.Literal == "|" || tok.Literal == "&" {
+			return "", "", false
+		}
+	}
+	return tokens[0].Literal, "0" + expression[len(tokens[0].Literal):], true
+}
@@ -0,0 +1,51 @@
+package parser
+
+import "testing"
+
+func TestEvaluate(t *testing.T) {
+	symbols := map[string]int{"SCREEN": 16384, "KBD": 24576, "TABLE": 100}
+	resolve := func(symbol string) (int, bool) {
+		value, ok := symbols[symbol]
+		return value, ok
+	}
+	testCases := []struct {
+		expression string
+		expected   int
+	}{
+		{"10", 10},
+		{"0x4000", 16384},
+		{"0XFF", 255},
+		{"0b1010", 10},
+		{"'A'", 65},
+		{"SCREEN+32", 16416},
+		{"KBD-1", 24575},
+		{"TABLE+2*3", 106},
+		{"(TABLE+2)*3", 306},
+		{"10-2-3", 5},
+		{"-1+2", 1},
+		{"7/2", 3},
+		{"SCREEN|1&3", 16385},
+		{"0xFF&0x0F|0x100", 0x10F},
+	}
+	for _, tt := range testCases {
+		value, err := evaluate(tt.expression, resolve)
+		if err != nil {
+			t.Fatalf("evaluate(%q) returned error: %s", tt.expression, err)
+		}
+		if value != tt.expected {
+			t.Errorf("evaluate(%q) should be %d. got %d", tt.expression, tt.expected, value)
+		}
+	}
+}
+
+func TestRenameSymbols(t *testing.T) {
+	renamed := renameSymbols("LOOP+LOOP2*'L'", func(symbol string) string {
+		if symbol == "LOOP" {
+			return "LOOP$M.1"
+		}
+		return symbol
+	})
+	if renamed != "LOOP$M.1+LOOP2*'L'" {
+		t.Fatalf("renameSymbols() should be %q. got %q", "LOOP$M.1+LOOP2*'L'", renamed)
+	}
+}
@@ -2,215 +2,184 @@ package parser
 
 import (
 	"assembler/ast"
-	"assembler/symboltable"
+	"assembler/code"
 	"assembler/value"
 	"fmt"
-	"strconv"
 	"strings"
 )
 
-type Parser struct {
-	*symboltable.SymbolTable
-	input             string
-	commandStrList    []string
-	currentCommandIdx int
-	readPosition      int
-}
-
-func New(input string, symbolTable *symboltable.SymbolTable) *Parser {
-	parser := &Parser{input: input, commandStrList: strings.Split(input, value.NEW_LINE), currentCommandIdx: 0, readPosition: 0, SymbolTable: symbolTable}
-	return parser
-}
+// ErrorType is type of ParseError
+type ErrorType string
+
+const (
+	INVALID_COMMAND  ErrorType = "INVALID_COMMAND"  // line is not A, C or L command
+	INVALID_MNEMONIC ErrorType = "INVALID_MNEMONIC" // comp, dest or jump is not defined
+	OUT_OF_RANGE     ErrorType = "OUT_OF_RANGE"     // value of A command is not in 0..32767
+	INVALID_SYMBOL   ErrorType = "INVALID_SYMBOL"   // symbol has illegal character
+	UNDEFINED_SYMBOL ErrorType = "UNDEFINED_SYMBOL" // symbol in expression or .equ is not defined
+	DUPLICATE_LABEL  ErrorType = "DUPLICATE_LABEL"  // label is defined twice
+	INVALID_MACRO    ErrorType = "INVALID_MACRO"    // macro is not defined or called correctly
+	INVALID_INCLUDE  ErrorType = "INVALID_INCLUDE"  // file of "#include" can not be read
+	NOT_RELOCATABLE  ErrorType = "NOT_RELOCATABLE"  // expression can not be written in object file
+)
 
-func (p *Parser) Advance() {
-	p.currentCommandIdx++
-}
+// MAX_VALUE is max value which A command can load
+const MAX_VALUE = 32767
 
-func (p *Parser) HasMoreCommand() bool {
-	return len(p.commandStrList) > p.currentCommandIdx
+// ParseError is diagnostic reported for a line of assembly
+type ParseError struct {
+	Type     ErrorType
+	Filename string // file included by "#include". empty for input of Assemble
+	Line     int    // line number starting from 1
+	Text     string // source of the line
+	Macro    string // name of macro if the line is expanded from macro
+	Message  string
 }
 
-func (p *Parser) CommandType() ast.CommandType {
-	commansStr := p.commandStrList[p.currentCommandIdx]
-	if commansStr == "" {
-		return ""
+func (e *ParseError) Error() string {
+	message := fmt.Sprintf("line %d: %s: %s", e.Line, e.Text, e.Message)
+	if e.Macro != "" {
+		message = fmt.Sprintf("line %d: %s (in macro %s): %s", e.Line, e.Text, e.Macro, e.Message)
 	}
-	// switch by prefix char
-	commandStrPrefix := commansStr[0]
-	switch commandStrPrefix {
-	case '@':
-		return ast.A_COMMAND
-	case '0', '1', 'D', 'A', '!', '-', 'M':
-		return ast.C_COMMAND
-	case '(':
-		return ast.L_COMMAND
-	default:
-		return ""
+	if e.Filename != "" {
+		message = e.Filename + ": " + message
 	}
+	return message
 }
 
-func (p *Parser) ParseAssembly() ([]ast.Command, error) {
-	commands := []ast.Command{}
-	for p.HasMoreCommand() {
-		command, _ := p.ParseCommand()
-		commands = append(commands, command)
-		p.Advance()
+// Errors is list of ParseError. Assemble returns all errors found in assembly.
+type Errors []*ParseError
+
+func (errs Errors) Error() string {
+	messages := []string{}
+	for _, err := range errs {
+		messages = append(messages, err.Error())
 	}
-	return commands, nil
+	return strings.Join(messages, value.LF)
 }
 
-func (p *Parser) ParseCommand() (ast.Command, error) {
-	p.removeWhiteSpace()
-	switch p.CommandType() {
-	case ast.A_COMMAND:
-		aCommand, err := p.parseACommand()
-		p.resetReadPosition()
-		if err != nil {
-			return nil, err
-		}
-		return aCommand, nil
-	case ast.C_COMMAND:
-		cCommand, err := p.parseCCommand()
-		p.resetReadPosition()
-		if err != nil {
-			return nil, err
-		}
-		return cCommand, nil
-	case ast.L_COMMAND:
-		lCommand, err := p.parseLCommand()
-		p.resetReadPosition()
-		if err != nil {
-			return nil, err
-		}
-		return lCommand, nil
-	default:
-		return nil, fmt.Errorf("%s is invalid Command Type ", p.commandStrList[p.currentCommandIdx])
+// splitLines splits input into lines. both CRLF and LF are accepted.
+func splitLines(input string, filename string) []SourceLine {
+	lines := strings.Split(strings.ReplaceAll(input, value.NEW_LINE, value.LF), value.LF)
+	sourceLines := make([]SourceLine, len(lines))
+	for i, line := range lines {
+		sourceLines[i] = SourceLine{Text: strings.TrimSpace(line), Filename: filename, Line: i + 1}
 	}
+	return sourceLines
 }
 
-func (p *Parser) parseACommand() (*ast.ACommand, error) {
-	p.readChar() // read "@"
-	valueStr := ""
-	for p.hasMoreChar() {
-		c := p.commandStrList[p.currentCommandIdx][p.readPosition]
-		valueStr += string(c)
-		p.readChar()
-	}
-	value, err := strconv.Atoi(valueStr)
-	if err != nil {
-		if p.Contains(valueStr) {
-			value, _ = p.GetAddress(valueStr)
-		}
-	}
-	return &ast.ACommand{ValueStr: valueStr, Value: value}, nil
+// scanner reads characters of an instruction from which comments and white spaces are removed
+type scanner struct {
+	line         SourceLine
+	text         string
+	readPosition int
 }
 
-func (p *Parser) parseCCommand() (*ast.CCommand, error) {
+func (s *scanner) parseCCommand() (*ast.CCommand, *ParseError) {
 	dest, comp, jump := "", "", ""
-	hasEqual := strings.Contains(p.commandStrList[p.currentCommandIdx], "=")
-	hasSemicolon := strings.Contains(p.commandStrList[p.currentCommandIdx], ";")
+	hasEqual := strings.Contains(s.text, "=")
+	hasSemicolon := strings.Contains(s.text, ";")
 	if hasEqual && hasSemicolon { // parseDest
-		dest = p.parseDest()
-		p.readChar() // read "="
-		comp = p.parseComp()
-		p.readChar() // read ";"
-		jump = p.parseJump()
+		dest = s.parseDest()
+		s.readChar() // read "="
+		comp = s.parseComp()
+		s.readChar() // read ";"
+		jump = s.parseJump()
 	} else if hasEqual {
-		dest = p.parseDest() // read "="
-		p.readChar()
-		comp = p.parseComp()
+		dest = s.parseDest() // read "="
+		s.readChar()
+		comp = s.parseComp()
 	} else if hasSemicolon {
-		comp = p.parseComp()
-		p.readChar() // read ";"
-		jump = p.parseJump()
-
+		comp = s.parseComp()
+		s.readChar() // read ";"
+		jump = s.parseJump()
+	} else {
+		comp = s.parseComp()
+	}
+	if s.hasMoreChar() && !strings.HasPrefix(s.text[s.readPosition:], "//") {
+		return nil, s.line.Errorf(INVALID_COMMAND, "unexpected %q after C command", s.text[s.readPosition:])
+	}
+	if canonical, ok := code.CompAliases[comp]; ok {
+		comp = canonical
+	}
+	if _, ok := code.CompTable[comp]; !ok {
+		return nil, s.line.Errorf(INVALID_MNEMONIC, "comp %q is not defined", comp)
+	}
+	if _, ok := code.DestTable[dest]; !ok && (dest != "" || hasEqual) {
+		return nil, s.line.Errorf(INVALID_MNEMONIC, "dest %q is not defined", dest)
+	}
+	if _, ok := code.JumpTable[jump]; !ok && (jump != "" || hasSemicolon) {
+		return nil, s.line.Errorf(INVALID_MNEMONIC, "jump %q is not defined", jump)
 	}
 	return &ast.CCommand{Dest: dest, Comp: comp, Jump: jump}, nil
 }
 
-func (p *Parser) parseDest() string {
-	dest := ""
+func (s *scanner) parseDest() string {
+	return s.readUntil(func(c byte) bool { return c == '=' })
+}
 
-	for c := p.commandStrList[p.currentCommandIdx][p.readPosition]; c != '='; c = p.commandStrList[p.currentCommandIdx][p.readPosition] {
-		dest += string(c)
-		p.readChar()
-	}
-	return dest
+func (s *scanner) parseComp() string {
+	return s.readUntil(func(c byte) bool { return c == ';' || c == '/' })
 }
 
-func (p *Parser) parseComp() string {
-	comp := ""
-	for p.hasMoreChar() {
-		c := p.commandStrList[p.currentCommandIdx][p.readPosition]
-		if c == ';' {
-			break
-		}
-		if c == '/' {
-			break
-		}
-		comp += string(c)
-		p.readChar()
-	}
-	return comp
+func (s *scanner) parseJump() string {
+	return s.readUntil(func(c byte) bool { return !isLetter(c) && !isNumber(c) && !isUnderline(c) })
 }
 
-func (p *Parser) parseJump() string {
-	jump := ""
-	for p.hasMoreChar() {
-		c := p.commandStrList[p.currentCommandIdx][p.readPosition]
-		if !isLetter(c) && !isNumber(c) && !isUnderline(c) {
-			break
-		}
-		jump += string(c)
-		p.readChar()
+// readUntil reads instruction until isEnd returns true and returns the characters read.
+// it slices command instead of building new string.
+func (s *scanner) readUntil(isEnd func(c byte) bool) string {
+	start := s.readPosition
+	for s.hasMoreChar() && !isEnd(s.text[s.readPosition]) {
+		s.readChar()
 	}
-	return jump
+	return s.text[start:s.readPosition]
 }
 
-func (p *Parser) parseLCommand() (*ast.LCommand, error) {
-	p.readChar() // read '('
-	valueStr := ""
-	for p.commandStrList[p.currentCommandIdx][p.readPosition] != ')' {
-		valueStr += string(p.commandStrList[p.currentCommandIdx][p.readPosition])
-		p.readChar()
+func (s *scanner) parseLCommand() (*ast.LCommand, *ParseError) {
+	s.readChar() // read '('
+	valueStr := s.readUntil(func(c byte) bool { return c == ')' })
+	if !s.hasMoreChar() {
+		return nil, s.line.Errorf(INVALID_COMMAND, "label is not closed by \")\"")
+	}
+	s.readChar() // read ')'
+	if s.hasMoreChar() {
+		return nil, s.line.Errorf(INVALID_COMMAND, "unexpected %q after label", s.text[s.readPosition:])
+	}
+	if !IsSymbol(valueStr) {
+		return nil, s.line.Errorf(INVALID_SYMBOL, "label %q is not valid symbol", valueStr)
 	}
 	return &ast.LCommand{Symbol: valueStr}, nil
 }
 
-func (p *Parser) Symbol() (string, error) {
-	switch p.CommandType() {
-	case ast.A_COMMAND:
-		aCommand, _ := p.parseACommand()
-		p.resetReadPosition()
-		return aCommand.ValueStr, nil
-	case ast.L_COMMAND:
-		lCommand, _ := p.parseLCommand()
-		p.resetReadPosition()
-		return lCommand.Symbol, nil
-	default:
-		return "", fmt.Errorf("%s does not have Symbol ", p.CommandType())
+// removeCommentAndWhiteSpace removes comment and white spaces except in character literal like ' '
+func removeCommentAndWhiteSpace(line string) string {
+	// 多くの行は取り除くものがないので、そのまま返す
+	if strings.IndexAny(line, " \t\r/'") == -1 {
+		return line
 	}
+	removed := []byte{}
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case c == '\'' && i+2 < len(line) && line[i+2] == '\'':
+			removed = append(removed, line[i:i+3]...)
+			i += 2
+		case strings.HasPrefix(line[i:], "//"):
+			return string(removed)
+		case c != value.SPACE && c != value.TAB && string(c) != value.CR:
+			removed = append(removed, c)
+		}
+	}
+	return string(removed)
 }
 
-func (p *Parser) removeWhiteSpace() {
-	p.commandStrList[p.currentCommandIdx] = strings.Replace(p.commandStrList[p.currentCommandIdx], string(value.SPACE), "", -1)
-	p.commandStrList[p.currentCommandIdx] = strings.Replace(p.commandStrList[p.currentCommandIdx], string(value.TAB), "", -1)
-}
-
-func (p *Parser) readChar() {
-	p.readPosition++
-}
-
-func (p *Parser) hasMoreChar() bool {
-	return len(p.commandStrList[p.currentCommandIdx]) > p.readPosition
-}
-func (p *Parser) resetReadPosition() {
-	p.readPosition = 0
+func (s *scanner) readChar() {
+	s.readPosition++
 }
 
-func (p *Parser) ResetParseIdx() {
-	p.resetReadPosition()
-	p.currentCommandIdx = 0
+func (s *scanner) hasMoreChar() bool {
+	return len(s.text) > s.readPosition
 }
 
 func isLetter(ch byte) bool {
@@ -224,3 +193,14 @@ func isNumber(ch byte) bool {
 func isUnderline(ch byte) bool {
 	return ch == '_'
 }
+
+// isSymbol returns whether str consists of letters, digits, "_", ".", "$" and ":"
+func isSymbol(str string) bool {
+	for i := 0; i < len(str); i++ {
+		c := str[i]
+		if !isLetter(c) && !isNumber(c) && !isUnderline(c) && c != '.' && c != '$' && c != ':' {
+			return false
+		}
+	}
+	return true
+}
@@ -3,128 +3,131 @@ package parser
 import (
 	"assembler/ast"
 	"assembler/symboltable"
+	"reflect"
 	"testing"
 )
 
-var st = symboltable.New()
-
-func TestAdvance(t *testing.T) {
-	p := New("sample", st)
-	p.Advance()
-	if p.currentCommandIdx != 1 {
-		t.Fatalf("p.currentCommandIdx should be 1 , but got %d", p.currentCommandIdx)
+func TestScanLines(t *testing.T) {
+	st := symboltable.New()
+	lines := ScanLines(splitLines(".equ LAST END-1\n@i\n(LOOP)\nD=M\n(LOOP)\n@LOOP\n0;JMP\n(END)", ""), st)
+	if len(lines) != 8 {
+		t.Fatalf("len(lines) should be 8. got %d", len(lines))
 	}
-}
-
-func TestHasMoreCommand(t *testing.T) {
-	p := New(`sample
-	hoge`, st)
-	p.Advance()
-	if !p.HasMoreCommand() {
-		t.Fatal("p.HasMoreCommand should be true , but got false")
+	// ラベルは命令のアドレスを指し、.equ は後で定義されたラベルを参照できる
+	for symbol, expected := range map[string]int{"LOOP": 1, "END": 4, "LAST": 3} {
+		if address, err := st.GetAddress(symbol); err != nil || address != expected {
+			t.Errorf("address of %s should be %d. got %d", symbol, expected, address)
+		}
+	}
+	if lines[1].Type != ast.A_COMMAND || lines[1].Operand != "i" || st.Contains("i") {
+		t.Errorf("lines[1] should be A command of variable i which is not allocated. got %+v", lines[1])
 	}
-	p.Advance()
-	if p.HasMoreCommand() {
-		t.Fatalf("p.HasMoreCommand should be false , but got true")
+	if lines[4].Err == nil || lines[4].Err.Type != DUPLICATE_LABEL || lines[4].Type != "" {
+		t.Errorf("lines[4] should have DUPLICATE_LABEL error. got %+v", lines[4])
 	}
 }
 
-func TestCommandType(t *testing.T) {
-	testCases := []struct {
-		parser      *Parser
-		commandType ast.CommandType
-	}{
-		{&Parser{commandStrList: []string{"@10"}, currentCommandIdx: 0, SymbolTable: st}, ast.A_COMMAND},
-		{&Parser{commandStrList: []string{"D=M"}, currentCommandIdx: 0, SymbolTable: st}, ast.C_COMMAND},
-		{&Parser{commandStrList: []string{"(SAMPLE)"}, currentCommandIdx: 0, SymbolTable: st}, ast.L_COMMAND},
+func TestParseStatements(t *testing.T) {
+	statements, st, err := ParseStatements(".equ ROW 32\n(LOOP)\n@SCREEN+ROW\nD=M\n@i\n@LOOP\n0;JMP", "")
+	if err != nil {
+		t.Fatalf("ParseStatements() returned error: %s", err)
 	}
-	for _, tt := range testCases {
-		commandType := tt.parser.CommandType()
-		if commandType != tt.commandType {
-			t.Fatalf("commandType() Should be %s, got %s", tt.commandType, commandType)
-		}
+	expected := []ast.Command{
+		&ast.LCommand{Symbol: "LOOP"},
+		&ast.ACommand{ValueStr: "SCREEN+ROW", Value: 16416},
+		&ast.CCommand{Dest: "D", Comp: "M"},
+		&ast.ACommand{ValueStr: "i", Value: 0},
+		&ast.ACommand{ValueStr: "LOOP", Value: 0},
+		&ast.CCommand{Comp: "0", Jump: "JMP"},
 	}
-}
-
-func TestParseACommand(t *testing.T) {
-	testCases := []struct {
-		parser   *Parser
-		valueStr string
-	}{
-		{&Parser{commandStrList: []string{"@10"}, currentCommandIdx: 0}, "10"},
-		{&Parser{commandStrList: []string{"@100"}, currentCommandIdx: 0}, "100"},
+	if len(statements) != len(expected) {
+		t.Fatalf("len(statements) should be %d. got %d", len(expected), len(statements))
 	}
-	for _, tt := range testCases {
-		command, _ := tt.parser.parseACommand()
-		if command.ValueStr != tt.valueStr {
-			t.Fatalf("command.Value Should be %s, got %s", command.ValueStr, tt.valueStr)
+	for i, statement := range statements {
+		if !reflect.DeepEqual(statement.Command, expected[i]) {
+			t.Errorf("statements[%d].Command should be %+v. got %+v", i, expected[i], statement.Command)
+		}
+		if statement.Source.Line != i+2 {
+			t.Errorf("statements[%d] should be line %d. got %d", i, i+2, statement.Source.Line)
 		}
 	}
+	if st.Contains("i") {
+		t.Errorf("variable i should not be allocated")
+	}
 }
-func TestParseCCommand(t *testing.T) {
+
+func TestParseStatementsError(t *testing.T) {
 	testCases := []struct {
-		parser  *Parser
-		command *ast.CCommand
+		input     string
+		errorType ErrorType
+		line      int
 	}{
-		{&Parser{commandStrList: []string{"D=M//HOGE"}, currentCommandIdx: 0, SymbolTable: st}, &ast.CCommand{
-			Comp: "M",
-			Dest: "D",
-			Jump: "",
-		}},
-		{&Parser{commandStrList: []string{"D=D-M"}, currentCommandIdx: 0, SymbolTable: st}, &ast.CCommand{
-			Comp: "D-M",
-			Dest: "D",
-			Jump: "",
-		}},
-		{&Parser{commandStrList: []string{"0;JMP"}, currentCommandIdx: 0, SymbolTable: st}, &ast.CCommand{
-			Comp: "0",
-			Dest: "",
-			Jump: "JMP",
-		}},
-		{&Parser{commandStrList: []string{"AM=D|A;JMP"}, currentCommandIdx: 0, SymbolTable: st}, &ast.CCommand{Comp: "D|A", Dest: "AM", Jump: "JMP"}},
+		{"@0\r\nD=M+2", INVALID_MNEMONIC, 2},
+		{"@32768", OUT_OF_RANGE, 1},
+		{"@0\n@0\n@a#b", INVALID_SYMBOL, 3},
+		{"@a-b", UNDEFINED_SYMBOL, 1},
+		{"(LOOP", INVALID_COMMAND, 1},
+		{"(LOOP)\n@LOOP\n.equ LOOP 1", DUPLICATE_LABEL, 3},
 	}
 	for _, tt := range testCases {
-		command, _ := tt.parser.parseCCommand()
-		if command.Comp != tt.command.Comp {
-			t.Fatalf("command.Comp Should be %s, got %s", tt.command.Comp, command.Comp)
+		_, _, err := ParseStatements(tt.input, "")
+		errs, ok := err.(Errors)
+		if !ok || len(errs) != 1 {
+			t.Fatalf("ParseStatements() should return an error for %q. got %v", tt.input, err)
 		}
-		if command.Dest != tt.command.Dest {
-			t.Fatalf("command.Dest Should be %s, got %s", tt.command.Dest, command.Dest)
-		}
-		if command.Jump != tt.command.Jump {
-			t.Fatalf("command.Jump Should be %s, got %s", tt.command.Jump, command.Jump)
+		if errs[0].Type != tt.errorType || errs[0].Line != tt.line {
+			t.Fatalf("error should be %s at line %d. got %s at line %d", tt.errorType, tt.line, errs[0].Type, errs[0].Line)
 		}
 	}
 }
 
-func TestParseLCommand(t *testing.T) {
+func TestScan(t *testing.T) {
 	testCases := []struct {
-		parser *Parser
-		symbol string
+		text        string
+		instruction Instruction
 	}{
-		{&Parser{commandStrList: []string{"(HOGE)"}, currentCommandIdx: 0, SymbolTable: st}, "HOGE"},
-		{&Parser{commandStrList: []string{"(FUGA)"}, currentCommandIdx: 0, SymbolTable: st}, "FUGA"},
+		{"@LOOP+1 // next", Instruction{Type: ast.A_COMMAND, Operand: "LOOP+1"}},
+		{"@100", Instruction{Type: ast.A_COMMAND, Operand: "100"}},
+		{"D=M//HOGE", Instruction{Type: ast.C_COMMAND, CCommand: &ast.CCommand{Dest: "D", Comp: "M"}}},
+		{"D=D-M", Instruction{Type: ast.C_COMMAND, CCommand: &ast.CCommand{Dest: "D", Comp: "D-M"}}},
+		{"0;JMP", Instruction{Type: ast.C_COMMAND, CCommand: &ast.CCommand{Comp: "0", Jump: "JMP"}}},
+		{"AM=D|A;JMP", Instruction{Type: ast.C_COMMAND, CCommand: &ast.CCommand{Dest: "AM", Comp: "D|A", Jump: "JMP"}}},
+		{"M=M+D", Instruction{Type: ast.C_COMMAND, CCommand: &ast.CCommand{Dest: "M", Comp: "D+M"}}},
+		{"  AM = M-1 ; JGT", Instruction{Type: ast.C_COMMAND, CCommand: &ast.CCommand{Dest: "AM", Comp: "M-1", Jump: "JGT"}}},
+		{"(LOOP)", Instruction{Type: ast.L_COMMAND, Operand: "LOOP"}},
+		{".equ ROW 32 * 2 // comment", Instruction{Name: "ROW", Operand: "32*2"}},
+		{"// comment", Instruction{}},
 	}
 	for _, tt := range testCases {
-		command, _ := tt.parser.parseLCommand()
-		if command.Symbol != tt.symbol {
-			t.Fatalf("command.Symbol Should be %s, got %s", command.Symbol, tt.symbol)
+		instruction, err := Scan(SourceLine{Text: tt.text, Line: 1})
+		if err != nil {
+			t.Fatalf("Scan(%q) returned error: %s", tt.text, err)
+		}
+		if !reflect.DeepEqual(instruction, tt.instruction) {
+			t.Errorf("Scan(%q) should be %+v. got %+v", tt.text, tt.instruction, instruction)
 		}
 	}
 }
 
-func TestParseSymbol(t *testing.T) {
+func TestScanError(t *testing.T) {
 	testCases := []struct {
-		parser *Parser
-		symbol string
+		text      string
+		errorType ErrorType
 	}{
-		{&Parser{commandStrList: []string{"(HOGE)"}, currentCommandIdx: 0, SymbolTable: st}, "HOGE"},
-		{&Parser{commandStrList: []string{"@FUGA"}, currentCommandIdx: 0, SymbolTable: st}, "FUGA"},
+		{"@", INVALID_COMMAND},
+		{"D=M+2", INVALID_MNEMONIC},
+		{"(LOOP", INVALID_COMMAND},
+		{"(LOOP)X", INVALID_COMMAND},
+		{"(1ST)", INVALID_SYMBOL},
+		{"0;JMP)", INVALID_COMMAND},
+		{".equ 1X 1", INVALID_SYMBOL},
+		{".equX 1", INVALID_COMMAND},
+		{"hoge", INVALID_COMMAND},
 	}
 	for _, tt := range testCases {
-		symbol, _ := tt.parser.Symbol()
-		if symbol != tt.symbol {
-			t.Fatalf("symbol Should be %s, got %s", symbol, tt.symbol)
+		_, err := Scan(SourceLine{Text: tt.text, Line: 1})
+		if err == nil || err.Type != tt.errorType {
+			t.Errorf("Scan(%q) should return %s. got %v", tt.text, tt.errorType, err)
 		}
 	}
 }
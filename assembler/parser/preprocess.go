@@ -0,0 +1,229 @@
+package parser
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// SourceLine is a line of assembly after "#include" and macros are expanded
+type SourceLine struct {
+	Text      string // line without leading and trailing white spaces
+	Filename  string // file included by "#include". empty for input of Assemble
+	Line      int    // line number in the file. lines expanded from macro have line number of the macro call
+	Macro     string // name of macro if the line is expanded from macro
+	Directive bool   // whether the line is "#include", macro definition or macro call which has no instruction
+}
+
+// Errorf returns ParseError of line
+func (l SourceLine) Errorf(errorType ErrorType, format string, a ...interface{}) *ParseError {
+	return &ParseError{Type: errorType, Filename: l.Filename, Line: l.Line, Text: l.Text, Macro: l.Macro, Message: fmt.Sprintf(format, a...)}
+}
+
+// MAX_NESTING is limit of nested macro calls and includes. it stops recursive macro.
+const MAX_NESTING = 64
+
+type macro struct {
+	name   string
+	params []string
+	body   []SourceLine
+	labels map[string]bool // labels defined in body. they are renamed for each expansion
+}
+
+type preprocessor struct {
+	macros     map[string]*macro
+	expansions int      // number of macro expansions. used to make labels in macro unique
+	including  []string // files being included. used to detect recursive include
+	rootDir    string   // dir of input. filenames of SourceLine are relative to it
+	lines      []SourceLine
+	errors     Errors
+}
+
+// Preprocess expands `#include "file.asm"` and macros defined by ".macro NAME param1, param2" ... ".endm".
+// parameters are referred as "\param1" in body and macro is called by "NAME arg1, arg2".
+// labels defined in macro body are renamed to "LABEL$NAME.N" so that each expansion has unique labels.
+// included files are read relative to dir of including file. filename may be empty if input is not read from file.
+func Preprocess(input string, filename string) ([]SourceLine, error) {
+	lines := splitLines(input, "")
+	pp := &preprocessor{macros: map[string]*macro{}, including: []string{}, rootDir: filepath.Dir(filename), lines: make([]SourceLine, 0, len(lines)), errors: Errors{}}
+	if filename != "" {
+		pp.including = append(pp.including, filepath.Clean(filename))
+	}
+	pp.process(lines, filepath.Dir(filename), 0)
+	if len(pp.errors) > 0 {
+		return nil, pp.errors
+	}
+	return pp.lines, nil
+}
+
+func (pp *preprocessor) process(lines []SourceLine, dir string, depth int) {
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		name, rest := splitDirective(line.Text)
+		switch {
+		case name == "#include":
+			pp.include(line, rest, dir, depth)
+		case name == ".macro":
+			i = pp.define(lines, i)
+		case name == ".endm":
+			pp.errors = append(pp.errors, line.Errorf(INVALID_MACRO, ".endm without .macro"))
+		case pp.macros[name] != nil:
+			pp.expand(line, pp.macros[name], rest, dir, depth)
+		default:
+			pp.lines = append(pp.lines, line)
+		}
+	}
+}
+
+// splitDirective splits line into first word and the rest. comment is removed.
+func splitDirective(text string) (string, string) {
+	if idx := strings.Index(text, "//"); idx != -1 {
+		text = text[:idx]
+	}
+	text = strings.TrimSpace(text)
+	if idx := strings.IndexAny(text, " \t"); idx != -1 {
+		return text[:idx], strings.TrimSpace(text[idx:])
+	}
+	return text, ""
+}
+
+func (pp *preprocessor) include(line SourceLine, rest string, dir string, depth int) {
+	line.Directive = true
+	pp.lines = append(pp.lines, line)
+	if len(rest) < 2 || rest[0] != '"' || rest[len(rest)-1] != '"' {
+		pp.errors = append(pp.errors, line.Errorf(INVALID_INCLUDE, `#include should be followed by "file.asm"`))
+		return
+	}
+	filename := filepath.Join(dir, rest[1:len(rest)-1])
+	for _, including := range pp.including {
+		if including == filename {
+			pp.errors = append(pp.errors, line.Errorf(INVALID_INCLUDE, "%s is included recursively", filename))
+			return
+		}
+	}
+	if depth >= MAX_NESTING {
+		pp.errors = append(pp.errors, line.Errorf(INVALID_INCLUDE, "includes are nested too deeply"))
+		return
+	}
+	input, err := ioutil.ReadFile(filename)
+	if err != nil {
+		pp.errors = append(pp.errors, line.Errorf(INVALID_INCLUDE, "%s", err))
+		return
+	}
+	displayName, err := filepath.Rel(pp.rootDir, filename)
+	if err != nil {
+		displayName = filename
+	}
+	pp.including = append(pp.including, filename)
+	pp.process(splitLines(string(input), displayName), filepath.Dir(filename), depth+1)
+	pp.including = pp.including[:len(pp.including)-1]
+}
+
+// define reads macro definition from lines[start] to ".endm" and returns index of ".endm"
+func (pp *preprocessor) define(lines []SourceLine, start int) int {
+	header := lines[start]
+	_, rest := splitDirective(header.Text)
+	name, params := splitDirective(rest)
+	m := &macro{name: name, params: splitArguments(params), body: []SourceLine{}, labels: map[string]bool{}}
+	if name == "" || !isSymbol(name) || isNumber(name[0]) {
+		pp.errors = append(pp.errors, header.Errorf(INVALID_MACRO, "macro name %q is not valid symbol", name))
+	} else if pp.macros[name] != nil {
+		pp.errors = append(pp.errors, header.Errorf(INVALID_MACRO, "macro %s is already defined", name))
+	}
+	for _, param := range m.params {
+		if !isSymbol(param) || param == "" {
+			pp.errors = append(pp.errors, header.Errorf(INVALID_MACRO, "parameter %q is not valid symbol", param))
+		}
+	}
+	end := start + 1
+	for ; end < len(lines); end++ {
+		directive, _ := splitDirective(lines[end].Text)
+		if directive == ".endm" {
+			break
+		}
+		if directive == ".macro" {
+			pp.errors = append(pp.errors, lines[end].Errorf(INVALID_MACRO, "macro can not be defined in macro %s", name))
+		}
+		m.body = append(m.body, lines[end])
+		if command := removeCommentAndWhiteSpace(lines[end].Text); strings.HasPrefix(command, "(") && strings.HasSuffix(command, ")") {
+			m.labels[command[1:len(command)-1]] = true
+		}
+	}
+	if end == len(lines) {
+		pp.errors = append(pp.errors, header.Errorf(INVALID_MACRO, "macro %s is not closed by .endm", name))
+	}
+	// 定義はリスティングに残すが、命令としては読まない
+	for i := start; i <= end && i < len(lines); i++ {
+		line := lines[i]
+		line.Directive = true
+		pp.lines = append(pp.lines, line)
+	}
+	if name != "" && pp.macros[name] == nil {
+		pp.macros[name] = m
+	}
+	return end
+}
+
+// splitArguments splits "a, b, c" into arguments
+func splitArguments(arguments string) []string {
+	if strings.TrimSpace(arguments) == "" {
+		return []string{}
+	}
+	args := strings.Split(arguments, ",")
+	for i := range args {
+		args[i] = strings.TrimSpace(args[i])
+	}
+	return args
+}
+
+func (pp *preprocessor) expand(call SourceLine, m *macro, arguments string, dir string, depth int) {
+	call.Directive = true
+	pp.lines = append(pp.lines, call)
+	args := splitArguments(arguments)
+	if len(args) != len(m.params) {
+		pp.errors = append(pp.errors, call.Errorf(INVALID_MACRO, "macro %s takes %d arguments. got %d", m.name, len(m.params), len(args)))
+		return
+	}
+	if depth >= MAX_NESTING {
+		pp.errors = append(pp.errors, call.Errorf(INVALID_MACRO, "macro calls are nested too deeply"))
+		return
+	}
+	pp.expansions++
+	// 長いパラメータから置換して、\a が \ab の一部を置換しないようにする
+	order := make([]int, len(m.params))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return len(m.params[order[i]]) > len(m.params[order[j]]) })
+	expanded := []SourceLine{}
+	for _, bodyLine := range m.body {
+		text := bodyLine.Text
+		for _, i := range order {
+			text = strings.ReplaceAll(text, `\`+m.params[i], args[i])
+		}
+		command := removeCommentAndWhiteSpace(text)
+		switch {
+		case strings.HasPrefix(command, "(") && m.labels[strings.TrimSuffix(command[1:], ")")]:
+			text = fmt.Sprintf("(%s)", localLabel(strings.TrimSuffix(command[1:], ")"), m.name, pp.expansions))
+		case strings.HasPrefix(command, "@"):
+			// @LOOP+1 のような式の中のラベルも置き換える
+			renamed := "@" + renameSymbols(command[1:], func(symbol string) string {
+				if m.labels[symbol] {
+					return localLabel(symbol, m.name, pp.expansions)
+				}
+				return symbol
+			})
+			if renamed != "@"+command[1:] {
+				text = renamed
+			}
+		}
+		expanded = append(expanded, SourceLine{Text: text, Filename: call.Filename, Line: call.Line, Macro: m.name})
+	}
+	pp.process(expanded, dir, depth+1)
+}
+
+func localLabel(label string, macroName string, expansion int) string {
+	return fmt.Sprintf("%s$%s.%d", label, macroName, expansion)
+}
@@ -0,0 +1,147 @@
+package parser
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPreprocessMacro(t *testing.T) {
+	input := `.macro PUSH value // push value to stack
+@\value
+D=A
+@SP
+AM=M+1
+A=A-1
+M=D
+.endm
+.macro LOOP
+(WAIT)
+@WAIT
+0;JMP
+.endm
+PUSH 7
+LOOP
+LOOP`
+	lines, err := Preprocess(input, "")
+	if err != nil {
+		t.Fatalf("Preprocess() returned error: %s", err)
+	}
+	expected := []SourceLine{
+		{Text: "PUSH 7", Line: 14, Directive: true},
+		{Text: "@7", Line: 14, Macro: "PUSH"},
+		{Text: "D=A", Line: 14, Macro: "PUSH"},
+		{Text: "@SP", Line: 14, Macro: "PUSH"},
+		{Text: "AM=M+1", Line: 14, Macro: "PUSH"},
+		{Text: "A=A-1", Line: 14, Macro: "PUSH"},
+		{Text: "M=D", Line: 14, Macro: "PUSH"},
+		{Text: "LOOP", Line: 15, Directive: true},
+		{Text: "(WAIT$LOOP.2)", Line: 15, Macro: "LOOP"},
+		{Text: "@WAIT$LOOP.2", Line: 15, Macro: "LOOP"},
+		{Text: "0;JMP", Line: 15, Macro: "LOOP"},
+		{Text: "LOOP", Line: 16, Directive: true},
+		{Text: "(WAIT$LOOP.3)", Line: 16, Macro: "LOOP"},
+		{Text: "@WAIT$LOOP.3", Line: 16, Macro: "LOOP"},
+		{Text: "0;JMP", Line: 16, Macro: "LOOP"},
+	}
+	// 13行のマクロ定義は命令として読まれない
+	if len(lines) != 13+len(expected) {
+		t.Fatalf("Preprocess() should return %d lines. got %d", 13+len(expected), len(lines))
+	}
+	for i, line := range lines[:13] {
+		if !line.Directive || line.Line != i+1 {
+			t.Errorf("line %d of macro definition should be directive. got %+v", i+1, line)
+		}
+	}
+	for i, line := range lines[13:] {
+		if line != expected[i] {
+			t.Errorf("line should be %+v. got %+v", expected[i], line)
+		}
+	}
+}
+
+func TestPreprocessNestedMacro(t *testing.T) {
+	input := ".macro SETD value\n@\\value\nD=A\n.endm\n.macro SET address, value\nSETD \\value\n@\\address\nM=D\n.endm\nSET R1, 3"
+	lines, err := Preprocess(input, "")
+	if err != nil {
+		t.Fatalf("Preprocess() returned error: %s", err)
+	}
+	texts := []string{}
+	for _, line := range lines {
+		if !line.Directive {
+			texts = append(texts, line.Text)
+		}
+	}
+	expected := []string{"@3", "D=A", "@R1", "M=D"}
+	if len(texts) != len(expected) {
+		t.Fatalf("expanded lines should be %v. got %v", expected, texts)
+	}
+	for i := range texts {
+		if texts[i] != expected[i] {
+			t.Errorf("expanded line should be %s. got %s", expected[i], texts[i])
+		}
+	}
+}
+
+func TestPreprocessInclude(t *testing.T) {
+	dir, err := ioutil.TempDir("", "preprocess")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	os.Mkdir(filepath.Join(dir, "lib"), os.ModePerm)
+	ioutil.WriteFile(filepath.Join(dir, "lib", "stack.asm"), []byte("#include \"inc.asm\"\r\n.macro POPD\r\n@SP\r\nAM=M-1\r\nD=M\r\n.endm"), os.ModePerm)
+	ioutil.WriteFile(filepath.Join(dir, "lib", "inc.asm"), []byte("@SP\r\nM=M+1"), os.ModePerm)
+	lines, err := Preprocess("#include \"lib/stack.asm\"\r\nPOPD", filepath.Join(dir, "Main.asm"))
+	if err != nil {
+		t.Fatalf("Preprocess() returned error: %s", err)
+	}
+	expected := []SourceLine{
+		{Text: `#include "lib/stack.asm"`, Line: 1, Directive: true},
+		{Text: `#include "inc.asm"`, Filename: filepath.Join("lib", "stack.asm"), Line: 1, Directive: true},
+		{Text: "@SP", Filename: filepath.Join("lib", "inc.asm"), Line: 1},
+		{Text: "M=M+1", Filename: filepath.Join("lib", "inc.asm"), Line: 2},
+	}
+	for i, line := range lines[:len(expected)] {
+		if line != expected[i] {
+			t.Errorf("line should be %+v. got %+v", expected[i], line)
+		}
+	}
+	if last := lines[len(lines)-1]; last.Text != "D=M" || last.Macro != "POPD" || last.Line != 2 || last.Filename != "" {
+		t.Errorf("last line should be D=M expanded from POPD at line 2. got %+v", last)
+	}
+}
+
+func TestPreprocessError(t *testing.T) {
+	dir, err := ioutil.TempDir("", "preprocess")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(filepath.Join(dir, "Self.asm"), []byte("#include \"Self.asm\""), os.ModePerm)
+	testCases := []struct {
+		input    string
+		filename string
+		expected string
+	}{
+		{".macro A\n@0", "", "line 1: .macro A: macro A is not closed by .endm"},
+		{".endm", "", "line 1: .endm: .endm without .macro"},
+		{".macro 1A\n.endm", "", `line 1: .macro 1A: macro name "1A" is not valid symbol`},
+		{".macro A x\n.endm\n.macro A x\n.endm", "", "line 3: .macro A x: macro A is already defined"},
+		{".macro A x, y\n.endm\nA 1", "", "line 3: A 1: macro A takes 2 arguments. got 1"},
+		{".macro A\nA\n.endm\nA", "", "line 4: A (in macro A): macro calls are nested too deeply"},
+		{"#include Self.asm", "", `line 1: #include Self.asm: #include should be followed by "file.asm"`},
+		{"#include \"Self.asm\"", filepath.Join(dir, "Self.asm"), "line 1: #include \"Self.asm\": " + filepath.Join(dir, "Self.asm") + " is included recursively"},
+	}
+	for _, tt := range testCases {
+		_, err := Preprocess(tt.input, tt.filename)
+		errs, ok := err.(Errors)
+		if !ok || len(errs) == 0 {
+			t.Fatalf("Preprocess(%q) should return Errors. got %v", tt.input, err)
+		}
+		if errs[0].Error() != tt.expected {
+			t.Errorf("error should be %q. got %q", tt.expected, errs[0].Error())
+		}
+	}
+}
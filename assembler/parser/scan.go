@@ -0,0 +1,129 @@
+package parser
+
+import (
+	"assembler/ast"
+	"strings"
+)
+
+// Instruction is a line of assembly tokenized by Scan
+type Instruction struct {
+	Type     ast.CommandType // A_COMMAND, C_COMMAND or L_COMMAND. empty for .equ and lines without instruction
+	Operand  string          // symbol or expression of A command, label of L command or expression of .equ
+	Name     string          // name of constant defined by .equ
+	CCommand *ast.CCommand   // dest, comp and jump of C command
+}
+
+// IsEqu returns whether instruction is ".equ NAME expression"
+func (i Instruction) IsEqu() bool {
+	return i.Name != ""
+}
+
+// Scan tokenizes line expanded by Preprocess once. operand of A command and .equ is not evaluated,
+// so caller can resolve symbols defined after the line. directive lines have no instruction.
+func Scan(line SourceLine) (Instruction, *ParseError) {
+	if line.Directive {
+		return Instruction{}, nil
+	}
+	// .global と .extern はオブジェクトのためのもので、プログラム全体をアセンブルするときは無視する
+	if directive, name, ok := Linkage(line); ok {
+		if !IsSymbol(name) {
+			return Instruction{}, line.Errorf(INVALID_SYMBOL, "%s should be followed by a symbol. got %q", directive, name)
+		}
+		return Instruction{}, nil
+	}
+	// 命令の行は splitDirective で分割しない
+	if directive, rest := splitEqu(line.Text); directive == ".equ" {
+		name, expression := splitDirective(rest)
+		expression = removeCommentAndWhiteSpace(expression)
+		if !IsSymbol(name) {
+			return Instruction{}, line.Errorf(INVALID_SYMBOL, "constant name %q is not valid symbol", name)
+		}
+		if expression == "" {
+			return Instruction{}, line.Errorf(INVALID_COMMAND, ".equ should be \".equ NAME value\"")
+		}
+		return Instruction{Name: name, Operand: expression}, nil
+	}
+	text := removeCommentAndWhiteSpace(line.Text)
+	if text == "" {
+		return Instruction{}, nil
+	}
+	s := &scanner{line: line, text: text}
+	switch commandType(text) {
+	case ast.A_COMMAND:
+		if len(text) == 1 {
+			return Instruction{}, line.Errorf(INVALID_COMMAND, "A command should have value or symbol")
+		}
+		return Instruction{Type: ast.A_COMMAND, Operand: text[1:]}, nil
+	case ast.C_COMMAND:
+		cCommand, err := s.parseCCommand()
+		if err != nil {
+			return Instruction{}, err
+		}
+		return Instruction{Type: ast.C_COMMAND, CCommand: cCommand}, nil
+	case ast.L_COMMAND:
+		lCommand, err := s.parseLCommand()
+		if err != nil {
+			return Instruction{}, err
+		}
+		return Instruction{Type: ast.L_COMMAND, Operand: lCommand.Symbol}, nil
+	default:
+		return Instruction{}, line.Errorf(INVALID_COMMAND, "invalid command")
+	}
+}
+
+// commandType decides type of instruction by its first character
+func commandType(text string) ast.CommandType {
+	switch text[0] {
+	case '@':
+		return ast.A_COMMAND
+	case '0', '1', 'D', 'A', '!', '-', 'M':
+		return ast.C_COMMAND
+	case '(':
+		return ast.L_COMMAND
+	default:
+		return ""
+	}
+}
+
+// Linkage returns directive and symbol of ".global NAME" or ".extern NAME".
+// .global exports label or constant of object and .extern imports symbol which other object should export.
+func Linkage(line SourceLine) (string, string, bool) {
+	if line.Directive || !strings.HasPrefix(line.Text, ".") {
+		return "", "", false
+	}
+	directive, rest := splitDirective(line.Text)
+	if directive != ".global" && directive != ".extern" {
+		return "", "", false
+	}
+	return directive, rest, true
+}
+
+// splitEqu splits line like splitDirective only if line may be .equ
+func splitEqu(text string) (string, string) {
+	if !strings.HasPrefix(text, ".equ") {
+		return "", ""
+	}
+	return splitDirective(text)
+}
+
+// Evaluate evaluates operand of line with resolve. value should be in 0..MAX_VALUE.
+func (l SourceLine) Evaluate(expression string, resolve func(symbol string) (int, bool)) (int, *ParseError) {
+	value, err := l.EvaluateAddend(expression, resolve)
+	if err != nil {
+		return 0, err
+	}
+	if value < 0 || value > MAX_VALUE {
+		return 0, l.Errorf(OUT_OF_RANGE, "%d is out of range 0..%d", value, MAX_VALUE)
+	}
+	return value, nil
+}
+
+// EvaluateAddend evaluates expression like Evaluate, but value can be out of 0..MAX_VALUE. e.g. "0-1" of "@i-1" in object
+func (l SourceLine) EvaluateAddend(expression string, resolve func(symbol string) (int, bool)) (int, *ParseError) {
+	value, err := evaluate(expression, resolve)
+	if err != nil {
+		e := err.(*expressionError)
+		return 0, l.Errorf(e.Type, "%s", e.Message)
+	}
+	return value, nil
+}
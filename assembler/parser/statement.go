@@ -0,0 +1,120 @@
+package parser
+
+import (
+	"assembler/ast"
+	"assembler/symboltable"
+)
+
+// Statement is command with its source line
+type Statement struct {
+	Command ast.Command
+	Source  SourceLine
+}
+
+// Line is source line tokenized by Scan
+type Line struct {
+	Instruction
+	Source SourceLine
+	Err    *ParseError // error of the line. line which has error has no instruction
+}
+
+// ScanLines tokenizes lines expanded by Preprocess once, and adds labels and constants defined by .equ to symbol table.
+// .equ is evaluated after all lines are read, so it can refer labels and constants defined before it.
+func ScanLines(sourceLines []SourceLine, st *symboltable.SymbolTable) []Line {
+	lines := make([]Line, len(sourceLines))
+	address := 0
+	for i, sourceLine := range sourceLines {
+		instruction, err := Scan(sourceLine)
+		lines[i] = Line{Instruction: instruction, Source: sourceLine, Err: err}
+		switch {
+		case err != nil:
+		case instruction.Type == ast.A_COMMAND || instruction.Type == ast.C_COMMAND:
+			address++
+		case instruction.Type == ast.L_COMMAND:
+			if st.Contains(instruction.Operand) {
+				lines[i].fail(sourceLine.Errorf(DUPLICATE_LABEL, "symbol %s is already defined", instruction.Operand))
+				break
+			}
+			st.AddLabel(instruction.Operand, address)
+		}
+	}
+	// .equ はラベルと、それより前の .equ を参照できる
+	for i := range lines {
+		line := &lines[i]
+		if !line.IsEqu() {
+			continue
+		}
+		value, err := line.Source.Evaluate(line.Operand, Resolve(st))
+		switch {
+		case err != nil:
+			line.fail(err)
+		case st.Contains(line.Name):
+			line.fail(line.Source.Errorf(DUPLICATE_LABEL, "symbol %s is already defined", line.Name))
+		default:
+			st.AddConstant(line.Name, value)
+		}
+	}
+	return lines
+}
+
+func (l *Line) fail(err *ParseError) {
+	l.Instruction, l.Err = Instruction{}, err
+}
+
+// Resolve returns function which resolves symbol in expression by symbol table. variables are not allocated by expressions.
+func Resolve(st *symboltable.SymbolTable) func(symbol string) (int, bool) {
+	return func(symbol string) (int, bool) {
+		value, ok := st.SymbolTableDict[symbol]
+		return value, ok
+	}
+}
+
+// ParseStatements parses assembly into statements including labels. returns Errors if assembly has errors.
+// symbol table has predefined symbols, labels and constants. variables are not allocated.
+func ParseStatements(input string, filename string) ([]Statement, *symboltable.SymbolTable, error) {
+	sourceLines, err := Preprocess(input, filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	st := symboltable.New()
+	errs := Errors{}
+	statements := []Statement{}
+	for _, line := range ScanLines(sourceLines, st) {
+		var command ast.Command
+		switch {
+		case line.Err != nil:
+			errs = append(errs, line.Err)
+		case line.Type == ast.A_COMMAND:
+			aCommand, err := line.aCommand(st)
+			if err != nil {
+				errs = append(errs, err)
+				break
+			}
+			command = aCommand
+		case line.Type == ast.C_COMMAND:
+			command = line.CCommand
+		case line.Type == ast.L_COMMAND:
+			command = &ast.LCommand{Symbol: line.Operand}
+		}
+		if command != nil {
+			statements = append(statements, Statement{Command: command, Source: line.Source})
+		}
+	}
+	if len(errs) > 0 {
+		return nil, nil, errs
+	}
+	return statements, st, nil
+}
+
+// aCommand evaluates operand of A command. value of variable is 0 because it is not allocated.
+func (l *Line) aCommand(st *symboltable.SymbolTable) (*ast.ACommand, *ParseError) {
+	if IsSymbol(l.Operand) {
+		value, _ := Resolve(st)(l.Operand)
+		return &ast.ACommand{ValueStr: l.Operand, Value: value}, nil
+	}
+	value, err := l.Source.Evaluate(l.Operand, Resolve(st))
+	if err != nil {
+		return nil, err
+	}
+	return &ast.ACommand{ValueStr: l.Operand, Value: value}, nil
+}
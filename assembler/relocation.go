@@ -0,0 +1,135 @@
+package assembler
+
+import (
+	"assembler/ast"
+	"assembler/code"
+	"assembler/object"
+	"assembler/parser"
+	"assembler/symboltable"
+	"strconv"
+)
+
+// AssembleObject translates assembly to relocatable object which is linked by hacklink.
+// labels and constants are local to the module and only those declared by ".global NAME" are exported.
+// symbols which are not defined in the module are imported only if they are declared by ".extern NAME",
+// and other symbols are variables allocated by linker. undefined symbol used as jump target is an error because it is not a variable.
+// A command using label, imported symbol or variable should be "@SYMBOL", "@SYMBOL+addend" or "@SYMBOL-addend" so that linker can relocate it.
+// each line is tokenized once by parser.ScanLines like AssembleProgram.
+func AssembleObject(input string, filename string) (*object.Object, error) {
+	sourceLines, err := parser.Preprocess(input, filename)
+	if err != nil {
+		return nil, err
+	}
+	st := symboltable.New()
+	lines := parser.ScanLines(sourceLines, st)
+	obj := object.New(filename)
+	errs := []indexedError{}
+	globals, externs := map[string]bool{}, map[string]bool{}
+	symbols := []string{} // imported symbols and variables in order of first use
+	declared := map[string]bool{}
+	jumpTargets := map[int]string{} // A commands followed by jump like "@SYMBOL" "0;JMP" and their symbols
+	previous, previousSymbol := -1, "" // previous A command which refers symbol not defined in module
+
+	for i, line := range lines {
+		directive, name, isLinkage := parser.Linkage(line.Source)
+		switch {
+		case line.Err != nil:
+			errs = append(errs, indexedError{i, line.Err})
+		case isLinkage && directive == ".global":
+			if kind, _ := st.Kind(name); kind != symboltable.LABEL && kind != symboltable.CONSTANT {
+				errs = append(errs, indexedError{i, line.Source.Errorf(parser.UNDEFINED_SYMBOL, "global symbol %s is not defined in module", name)})
+			}
+			globals[name] = true
+		case isLinkage:
+			if st.Contains(name) {
+				errs = append(errs, indexedError{i, line.Source.Errorf(parser.DUPLICATE_LABEL, "extern symbol %s is already defined", name)})
+			}
+			externs[name] = true
+		case line.IsEqu():
+			// ラベルのアドレスはリンクするまで決まらないので、定数の値にできない
+			for _, symbol := range parser.ExpressionSymbols(line.Operand) {
+				if kind, _ := st.Kind(symbol); kind == symboltable.LABEL {
+					errs = append(errs, indexedError{i, line.Source.Errorf(parser.NOT_RELOCATABLE, "constant %s can not refer label %s in object because address of label is decided by linker. use @%s+value instead", line.Name, symbol, symbol)})
+				}
+			}
+		case line.Type == ast.A_COMMAND:
+			previous = -1
+			word, err := relocate(line, st)
+			if err != nil {
+				errs = append(errs, indexedError{i, err})
+				break
+			}
+			if word.Kind == object.SYMBOL {
+				previous, previousSymbol = i, word.Symbol
+				if !declared[word.Symbol] {
+					declared[word.Symbol] = true
+					symbols = append(symbols, word.Symbol)
+				}
+			}
+			obj.Code = append(obj.Code, word)
+		case line.Type == ast.C_COMMAND:
+			if previous >= 0 && line.CCommand.Jump != "" {
+				jumpTargets[previous] = previousSymbol
+			}
+			previous = -1
+			word, _ := strconv.ParseUint(code.Binary(line.CCommand), 2, 16)
+			obj.Code = append(obj.Code, object.Word{Kind: object.ABSOLUTE, Value: int(word)})
+		}
+	}
+	// ラベルの書き間違いを変数や import にしないよう、.extern のないジャンプ先はエラーにする
+	for index, symbol := range jumpTargets {
+		if !externs[symbol] {
+			errs = append(errs, indexedError{index, lines[index].Source.Errorf(parser.UNDEFINED_SYMBOL, "label %s is not defined in module. declare \".extern %s\" if other module exports it", symbol, symbol)})
+		}
+	}
+	if len(errs) > 0 {
+		return nil, sortErrors(errs)
+	}
+	for _, symbol := range symbols {
+		if externs[symbol] {
+			obj.Imports = append(obj.Imports, symbol)
+		} else {
+			obj.Variables = append(obj.Variables, symbol)
+		}
+	}
+	for _, symbol := range st.Symbols() {
+		if globals[symbol.Name] {
+			obj.Exports = append(obj.Exports, object.Export{Kind: symbol.Kind, Name: symbol.Name, Value: symbol.Address})
+		}
+	}
+	return obj, nil
+}
+
+// isRelocated returns whether operand uses label or symbol which is not defined in module
+func isRelocated(st *symboltable.SymbolTable, operand string) bool {
+	for _, symbol := range parser.ExpressionSymbols(operand) {
+		if kind, ok := st.Kind(symbol); !ok || kind == symboltable.LABEL {
+			return true
+		}
+	}
+	return false
+}
+
+// relocate returns word of A command. it is relocated by linker if operand uses label, imported symbol or variable.
+func relocate(line parser.Line, st *symboltable.SymbolTable) (object.Word, *parser.ParseError) {
+	operand := line.Operand
+	if !isRelocated(st, operand) {
+		value, err := line.Source.Evaluate(operand, parser.Resolve(st))
+		if err != nil {
+			return object.Word{}, err
+		}
+		return object.Word{Kind: object.ABSOLUTE, Value: value}, nil
+	}
+	symbol, addendExpression, ok := parser.SplitRelocatable(operand)
+	if !ok || isRelocated(st, addendExpression) {
+		return object.Word{}, line.Source.Errorf(parser.NOT_RELOCATABLE, "%s can not be relocated. it should be SYMBOL, SYMBOL+value or SYMBOL-value", operand)
+	}
+	addend, err := line.Source.EvaluateAddend(addendExpression, parser.Resolve(st))
+	if err != nil {
+		return object.Word{}, err
+	}
+	if address, err := st.GetAddress(symbol); err == nil {
+		return object.Word{Kind: object.RELATIVE, Value: address + addend}, nil
+	}
+	return object.Word{Kind: object.SYMBOL, Symbol: symbol, Value: addend}, nil
+}
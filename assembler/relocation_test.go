@@ -0,0 +1,132 @@
+package assembler
+
+import (
+	"assembler/linker"
+	"assembler/object"
+	"assembler/parser"
+	"assembler/value"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestLinkSplitProgram(t *testing.T) {
+	asm, err := ioutil.ReadFile("asm/pong/Pong.asm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := assemble(string(asm))
+	if err != nil {
+		t.Fatalf("Assemble() returned error: %s", err)
+	}
+	// モジュールに分けてもリンクすれば連結してアセンブルしたものと同じになる
+	lines := strings.Split(string(asm), value.NEW_LINE)
+	objects := []*object.Object{}
+	for start, size := 0, len(lines)/4+1; start < len(lines); start += size {
+		end := start + size
+		if end > len(lines) {
+			end = len(lines)
+		}
+		// モジュールのラベルは .global で公開し、他のモジュールのラベルは .extern で参照する
+		declarations := []string{}
+		for i, line := range lines {
+			if !strings.HasPrefix(line, "(") {
+				continue
+			}
+			label := strings.Trim(strings.TrimSpace(line), "()")
+			if i >= start && i < end {
+				declarations = append(declarations, ".global "+label)
+			} else {
+				declarations = append(declarations, ".extern "+label)
+			}
+		}
+		module := append(declarations, lines[start:end]...)
+		obj, err := AssembleObject(strings.Join(module, value.NEW_LINE), "")
+		if err != nil {
+			t.Fatalf("AssembleObject() returned error: %s", err)
+		}
+		// ファイルに書いて読み直しても同じ
+		if obj, err = object.Parse("Pong.hobj", object.Format(obj)); err != nil {
+			t.Fatalf("object.Parse() returned error: %s", err)
+		}
+		objects = append(objects, obj)
+	}
+	result, err := linker.Link(objects)
+	if err != nil {
+		t.Fatalf("Link() returned error: %s", err)
+	}
+	if strings.Join(result.Binary, value.NEW_LINE) != strings.Join(expected, value.NEW_LINE) {
+		t.Fatalf("linked program should be same as assembled program")
+	}
+}
+
+func TestAssembleObject(t *testing.T) {
+	// TABLE は .global が無いのでモジュール内だけで使える。NEXT は .extern で宣言したので変数ではない
+	obj, err := AssembleObject(".global LOOP\n.global ROW\n.extern FUNC\n.extern NEXT\n.equ ROW 32\n(LOOP)\n@TABLE+ROW\n@LOOP+1\n@i\nD=M\n@SCREEN+ROW\n"+
+		"@FUNC\n0;JMP\n@NEXT\nD;JGT\n(TABLE)", "Main.asm")
+	if err != nil {
+		t.Fatalf("AssembleObject() returned error: %s", err)
+	}
+	expected := "HACKOBJ 2\r\nexport label LOOP 0\r\nexport constant ROW 32\r\nimport FUNC\r\nimport NEXT\r\nvariable i\r\n" +
+		"rel 41\r\nrel 1\r\nsym i 0\r\nword 1111110000010000\r\nword 0100000000100000\r\n" +
+		"sym FUNC 0\r\nword 1110101010000111\r\nsym NEXT 0\r\nword 1110001100000001\r\n"
+	if object.Format(obj) != expected {
+		t.Fatalf("object should be %q. got %q", expected, object.Format(obj))
+	}
+}
+
+func TestAssembleObjectError(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{"(LOOP)\n@LOOP*2", "line 2: @LOOP*2: LOOP*2 can not be relocated. it should be SYMBOL, SYMBOL+value or SYMBOL-value"},
+		{"@i+j", "line 1: @i+j: i+j can not be relocated. it should be SYMBOL, SYMBOL+value or SYMBOL-value"},
+		{"@i+1|2", "line 1: @i+1|2: i+1|2 can not be relocated. it should be SYMBOL, SYMBOL+value or SYMBOL-value"},
+		{"(LOOP)\n.equ NEXT LOOP+1", "line 2: .equ NEXT LOOP+1: constant NEXT can not refer label LOOP in object because address of label is decided by linker. use @LOOP+value instead"},
+		{".equ NEXT LOOP+1\n(LOOP)", "line 1: .equ NEXT LOOP+1: constant NEXT can not refer label LOOP in object because address of label is decided by linker. use @LOOP+value instead"},
+		{"(LOOP)\n@LOPP\n0;JMP", `line 2: @LOPP: label LOPP is not defined in module. declare ".extern LOPP" if other module exports it`},
+		{"@i\nD=M\n@i\nD;JGT", `line 3: @i: label i is not defined in module. declare ".extern i" if other module exports it`},
+		{".global LOOP\n@LOOP", "line 1: .global LOOP: global symbol LOOP is not defined in module"},
+		{".global SCREEN", "line 1: .global SCREEN: global symbol SCREEN is not defined in module"},
+		{"(LOOP)\n.extern LOOP", "line 2: .extern LOOP: extern symbol LOOP is already defined"},
+		{".extern 1ST", `line 1: .extern 1ST: .extern should be followed by a symbol. got "1ST"`},
+	}
+	for _, tt := range testCases {
+		_, err := AssembleObject(tt.input, "")
+		errs, ok := err.(parser.Errors)
+		if !ok || len(errs) == 0 || errs[0].Error() != tt.expected {
+			t.Errorf("AssembleObject(%q) should return %q. got %v", tt.input, tt.expected, err)
+		}
+	}
+}
+
+func TestLinkLocalLabels(t *testing.T) {
+	link := func(inputs ...string) (*linker.Result, error) {
+		objects := []*object.Object{}
+		for _, input := range inputs {
+			obj, err := AssembleObject(input, "")
+			if err != nil {
+				t.Fatalf("AssembleObject(%q) returned error: %s", input, err)
+			}
+			objects = append(objects, obj)
+		}
+		return linker.Link(objects)
+	}
+	// LOOP は各モジュールのローカルなラベルなので重複しない
+	result, err := link(".extern FUNC\n(LOOP)\n@LOOP\n0;JMP\n@FUNC\n0;JMP", ".global FUNC\n(FUNC)\n(LOOP)\n@LOOP\n0;JMP")
+	if err != nil {
+		t.Fatalf("Link() returned error: %s", err)
+	}
+	expected := []string{"0000000000000000", "1110101010000111", "0000000000000100", "1110101010000111", "0000000000000100", "1110101010000111"}
+	if strings.Join(result.Binary, value.NEW_LINE) != strings.Join(expected, value.NEW_LINE) {
+		t.Fatalf("linked program should be %v. got %v", expected, result.Binary)
+	}
+	// 公開されていないラベルは変数にならない
+	if _, err := link(".extern FUNCC\n@FUNCC\n0;JMP", ".global FUNC\n(FUNC)\n@FUNC\n0;JMP"); err == nil || err.Error() != ": symbol FUNCC is not exported by any module" {
+		t.Errorf("Link() should report misspelled label. got %v", err)
+	}
+	if _, err := link(".extern LOOP\n@LOOP\nD=A", "(LOOP)\n@LOOP\n0;JMP"); err == nil || err.Error() != ": symbol LOOP is not exported by any module" {
+		t.Errorf("Link() should report label which is not exported. got %v", err)
+	}
+}
@@ -0,0 +1,350 @@
+package rom
+
+import (
+	"assembler/value"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+type Format string
+
+const (
+	HACK      Format = "hack"     // 16 "0" or "1" in each line
+	BINARY    Format = "bin"      // raw big-endian 16-bit words
+	INTEL_HEX Format = "ihex"     // Intel HEX. each word is 2 bytes in big-endian
+	LOGISIM   Format = "logisim"  // Logisim "v2.0 raw" memory image
+	READMEMB  Format = "readmemb" // Verilog $readmemb. 16 "0" or "1" in each line
+	READMEMH  Format = "readmemh" // Verilog $readmemh. 4 hex digits in each line
+)
+
+// Formats is list of supported formats
+var Formats = []Format{HACK, BINARY, INTEL_HEX, LOGISIM, READMEMB, READMEMH}
+
+// extensions maps format to extension of its file
+var extensions = map[Format]string{
+	HACK:      ".hack",
+	BINARY:    ".bin",
+	INTEL_HEX: ".hex",
+	LOGISIM:   ".rom",
+	READMEMB:  ".memb",
+	READMEMH:  ".memh",
+}
+
+// MAX_WORDS is max number of words which Decode reads. address of word is 16 bits
+const MAX_WORDS = 1 << 16
+
+// INTEL_HEX_RECORD_SIZE is number of data bytes in each record of Intel HEX
+const INTEL_HEX_RECORD_SIZE = 16
+
+// LOGISIM_HEADER is first line of Logisim memory image
+const LOGISIM_HEADER = "v2.0 raw"
+
+// ParseFormat returns format of name. e.g. "ihex"
+func ParseFormat(name string) (Format, error) {
+	for _, format := range Formats {
+		if string(format) == name {
+			return format, nil
+		}
+	}
+	names := []string{}
+	for _, format := range Formats {
+		names = append(names, string(format))
+	}
+	return "", fmt.Errorf("unknown format %q. format should be one of %s", name, strings.Join(names, ", "))
+}
+
+// FormatOf returns format of filename by its extension. HACK is returned for unknown extension.
+func FormatOf(filename string) Format {
+	ext := strings.ToLower(filepath.Ext(filename))
+	for _, format := range Formats {
+		if extensions[format] == ext {
+			return format
+		}
+	}
+	return HACK
+}
+
+// Extension returns extension of file of format. e.g. ".hex"
+func Extension(format Format) string {
+	return extensions[format]
+}
+
+// Words converts machine language written by assembler to words
+func Words(binaryArr []string) []uint16 {
+	words := make([]uint16, 0, len(binaryArr))
+	for _, binary := range binaryArr {
+		word, _ := strconv.ParseUint(binary, 2, 16)
+		words = append(words, uint16(word))
+	}
+	return words
+}
+
+// Encode writes words in format
+func Encode(words []uint16, format Format) []byte {
+	switch format {
+	case BINARY:
+		data := make([]byte, 0, len(words)*2)
+		for _, word := range words {
+			data = append(data, byte(word>>8), byte(word))
+		}
+		return data
+	case INTEL_HEX:
+		return []byte(encodeIntelHex(words))
+	case LOGISIM:
+		return []byte(encodeLogisim(words))
+	case READMEMH:
+		return []byte(encodeLines(words, "%04x"))
+	default:
+		// HACK と READMEMB は同じ形式で書ける
+		return []byte(encodeLines(words, "%016b"))
+	}
+}
+
+func encodeLines(words []uint16, format string) string {
+	lines := make([]string, 0, len(words))
+	for _, word := range words {
+		lines = append(lines, fmt.Sprintf(format, word))
+	}
+	return strings.Join(lines, value.NEW_LINE)
+}
+
+// intelHexRecord returns record ":LLAAAATTDD...CC"
+func intelHexRecord(address int, recordType byte, data []byte) string {
+	record := append([]byte{byte(len(data)), byte(address >> 8), byte(address), recordType}, data...)
+	sum := byte(0)
+	for _, b := range record {
+		sum += b
+	}
+	return ":" + strings.ToUpper(hex.EncodeToString(append(record, -sum))) + value.NEW_LINE
+}
+
+func encodeIntelHex(words []uint16) string {
+	data := Encode(words, BINARY)
+	var out strings.Builder
+	for address := 0; address < len(data); address += INTEL_HEX_RECORD_SIZE {
+		// 64KB を超えるアドレスは extended linear address record で上位16ビットを指定する
+		if address > 0 && address%0x10000 == 0 {
+			out.WriteString(intelHexRecord(0, 0x04, []byte{byte(address >> 24), byte(address >> 16)}))
+		}
+		end := address + INTEL_HEX_RECORD_SIZE
+		if end > len(data) {
+			end = len(data)
+		}
+		out.WriteString(intelHexRecord(address&0xFFFF, 0x00, data[address:end]))
+	}
+	out.WriteString(intelHexRecord(0, 0x01, []byte{}))
+	return out.String()
+}
+
+func encodeLogisim(words []uint16) string {
+	var out strings.Builder
+	out.WriteString(LOGISIM_HEADER + value.NEW_LINE)
+	tokens := []string{}
+	for i := 0; i < len(words); {
+		run := 1
+		for i+run < len(words) && words[i+run] == words[i] {
+			run++
+		}
+		// Logisim と同じく、同じ値の繰り返しは "回数*値" にまとめる
+		if run >= 4 {
+			tokens = append(tokens, fmt.Sprintf("%d*%x", run, words[i]))
+		} else {
+			run = 1
+			tokens = append(tokens, fmt.Sprintf("%x", words[i]))
+		}
+		i += run
+	}
+	for i := 0; i < len(tokens); i += 8 {
+		end := i + 8
+		if end > len(tokens) {
+			end = len(tokens)
+		}
+		out.WriteString(strings.Join(tokens[i:end], " ") + value.NEW_LINE)
+	}
+	return out.String()
+}
+
+// Decode reads words written in format
+func Decode(data []byte, format Format) ([]uint16, error) {
+	switch format {
+	case BINARY:
+		if len(data)%2 != 0 {
+			return nil, fmt.Errorf("binary image has odd number of bytes %d", len(data))
+		}
+		words := make([]uint16, 0, len(data)/2)
+		for i := 0; i < len(data); i += 2 {
+			words = append(words, uint16(data[i])<<8|uint16(data[i+1]))
+		}
+		return words, nil
+	case INTEL_HEX:
+		return decodeIntelHex(string(data))
+	case LOGISIM:
+		return decodeLogisim(string(data))
+	case READMEMB:
+		return decodeReadmem(string(data), 2)
+	case READMEMH:
+		return decodeReadmem(string(data), 16)
+	default:
+		return decodeHack(string(data))
+	}
+}
+
+// decodeHack parses machine language(.hack). each line has 16 "0" or "1". empty lines are ignored.
+func decodeHack(hack string) ([]uint16, error) {
+	words := []uint16{}
+	for i, line := range strings.Split(hack, value.LF) {
+		line = strings.TrimSpace(line) // CRLFのCRを取り除く
+		if line == "" {
+			continue
+		}
+		word, err := strconv.ParseUint(line, 2, 16)
+		if err != nil || len(line) != 16 {
+			return nil, fmt.Errorf("line %d: %q should have 16 bits of 0 and 1", i+1, line)
+		}
+		words = append(words, uint16(word))
+	}
+	return words, nil
+}
+
+func decodeIntelHex(text string) ([]uint16, error) {
+	data := []byte{}
+	base := 0
+	for i, line := range strings.Split(text, value.LF) {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		record, err := hex.DecodeString(strings.TrimPrefix(line, ":"))
+		if !strings.HasPrefix(line, ":") || err != nil || len(record) < 5 || len(record) != int(record[0])+5 {
+			return nil, fmt.Errorf("line %d: %q is not Intel HEX record", i+1, line)
+		}
+		sum := byte(0)
+		for _, b := range record {
+			sum += b
+		}
+		if sum != 0 {
+			return nil, fmt.Errorf("line %d: checksum of %q is wrong", i+1, line)
+		}
+		address, recordType, recordData := int(record[1])<<8|int(record[2]), record[3], record[4:len(record)-1]
+		switch recordType {
+		case 0x00:
+			address += base
+			if address+len(recordData) > MAX_WORDS*2 {
+				return nil, fmt.Errorf("line %d: address %X is out of range", i+1, address)
+			}
+			for len(data) < address+len(recordData) {
+				data = append(data, 0)
+			}
+			copy(data[address:], recordData)
+		case 0x01:
+			if len(data)%2 != 0 {
+				return nil, fmt.Errorf("Intel HEX has odd number of bytes %d", len(data))
+			}
+			return Decode(data, BINARY)
+		case 0x02, 0x04:
+			if len(recordData) != 2 {
+				return nil, fmt.Errorf("line %d: address record %q should have 2 bytes", i+1, line)
+			}
+			base = int(recordData[0])<<8 | int(recordData[1])
+			if recordType == 0x02 {
+				base *= 16
+			} else {
+				base <<= 16
+			}
+		case 0x03, 0x05:
+			// 開始アドレスは ROM には関係ない
+		default:
+			return nil, fmt.Errorf("line %d: unknown record type %02X", i+1, recordType)
+		}
+	}
+	return nil, fmt.Errorf("Intel HEX should end with end of file record")
+}
+
+func decodeLogisim(text string) ([]uint16, error) {
+	lines := strings.Split(text, value.LF)
+	if strings.TrimSpace(lines[0]) != LOGISIM_HEADER {
+		return nil, fmt.Errorf("line 1: Logisim image should start with %q", LOGISIM_HEADER)
+	}
+	words := []uint16{}
+	for i, line := range lines[1:] {
+		if comment := strings.Index(line, "#"); comment >= 0 {
+			line = line[:comment]
+		}
+		for _, token := range strings.Fields(line) {
+			count, valueStr := 1, token
+			if star := strings.Index(token, "*"); star >= 0 {
+				n, err := strconv.Atoi(token[:star])
+				if err != nil || n < 0 || len(words)+n > MAX_WORDS {
+					return nil, fmt.Errorf("line %d: invalid count %q", i+2, token)
+				}
+				count, valueStr = n, token[star+1:]
+			}
+			word, err := strconv.ParseUint(valueStr, 16, 16)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %q should be 16-bit hex", i+2, token)
+			}
+			for j := 0; j < count; j++ {
+				words = append(words, uint16(word))
+			}
+		}
+	}
+	return words, nil
+}
+
+// decodeReadmem reads file for $readmemb(base 2) or $readmemh(base 16). "//" and "/* */" comments and "@address" are accepted.
+func decodeReadmem(text string, base int) ([]uint16, error) {
+	words := []uint16{}
+	address := 0
+	inComment := false
+	for i, line := range strings.Split(text, value.LF) {
+		tokens := []string{}
+		for line != "" {
+			if inComment {
+				end := strings.Index(line, "*/")
+				if end < 0 {
+					line = ""
+					break
+				}
+				line, inComment = line[end+2:], false
+				continue
+			}
+			lineComment, blockComment := strings.Index(line, "//"), strings.Index(line, "/*")
+			if lineComment >= 0 && (blockComment < 0 || lineComment < blockComment) {
+				tokens = append(tokens, strings.Fields(line[:lineComment])...)
+				break
+			}
+			if blockComment >= 0 {
+				tokens = append(tokens, strings.Fields(line[:blockComment])...)
+				line, inComment = line[blockComment+2:], true
+				continue
+			}
+			tokens = append(tokens, strings.Fields(line)...)
+			break
+		}
+		for _, token := range tokens {
+			token = strings.ReplaceAll(token, "_", "")
+			if strings.HasPrefix(token, "@") {
+				// アドレスは $readmemb でも16進数
+				a, err := strconv.ParseUint(token[1:], 16, 32)
+				if err != nil || a >= MAX_WORDS {
+					return nil, fmt.Errorf("line %d: invalid address %q", i+1, token)
+				}
+				address = int(a)
+				continue
+			}
+			word, err := strconv.ParseUint(token, base, 16)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %q should be 16-bit number of base %d", i+1, token, base)
+			}
+			for len(words) <= address {
+				words = append(words, 0)
+			}
+			words[address] = uint16(word)
+			address++
+		}
+	}
+	return words, nil
+}
@@ -0,0 +1,126 @@
+package rom
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var words = []uint16{0x0002, 0xEC10, 0x0003, 0xE090, 0x0000, 0xE308, 0, 0, 0, 0, 0, 0x7FFF, 0xFFFF}
+
+func TestEncodeDecode(t *testing.T) {
+	for _, format := range Formats {
+		decoded, err := Decode(Encode(words, format), format)
+		if err != nil {
+			t.Fatalf("Decode(%s) returned error: %s", format, err)
+		}
+		if !reflect.DeepEqual(decoded, words) {
+			t.Fatalf("Decode(Encode(words, %s)) should be %v. got %v", format, words, decoded)
+		}
+	}
+}
+
+func TestEncode(t *testing.T) {
+	testCases := []struct {
+		format   Format
+		expected string
+	}{
+		{HACK, "0000000000000010\r\n1110110000010000"},
+		{BINARY, "\x00\x02\xec\x10"},
+		{INTEL_HEX, ":0400000000 02EC10 FE\r\n:00000001FF\r\n"},
+		{LOGISIM, "v2.0 raw\r\n2 ec10\r\n"},
+		{READMEMB, "0000000000000010\r\n1110110000010000"},
+		{READMEMH, "0002\r\nec10"},
+	}
+	for _, tt := range testCases {
+		expected := strings.ReplaceAll(tt.expected, " ", "")
+		if tt.format == LOGISIM {
+			expected = tt.expected
+		}
+		if got := string(Encode(words[:2], tt.format)); got != expected {
+			t.Fatalf("Encode(%s) should be %q. got %q", tt.format, expected, got)
+		}
+	}
+	if got := string(Encode([]uint16{1, 0, 0, 0, 0, 2}, LOGISIM)); got != "v2.0 raw\r\n1 4*0 2\r\n" {
+		t.Fatalf("Logisim image should compress run of same value. got %q", got)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	testCases := []struct {
+		format   Format
+		input    string
+		expected []uint16
+	}{
+		{INTEL_HEX, ":020004000001F9\n:00000001FF\n", []uint16{0, 0, 1}},
+		{LOGISIM, "v2.0 raw\n# comment\n1 3*a\nffff\n", []uint16{1, 10, 10, 10, 0xFFFF}},
+		{READMEMB, "// comment\n0000_0000_0000_0001 /* block\ncomment */ 0000000000000010\n@4 1111111111111111\n", []uint16{1, 2, 0, 0, 0xFFFF}},
+		{READMEMH, "@1\nabcd // comment\n", []uint16{0, 0xABCD}},
+	}
+	for _, tt := range testCases {
+		decoded, err := Decode([]byte(tt.input), tt.format)
+		if err != nil {
+			t.Fatalf("Decode(%q, %s) returned error: %s", tt.input, tt.format, err)
+		}
+		if !reflect.DeepEqual(decoded, tt.expected) {
+			t.Fatalf("Decode(%q, %s) should be %v. got %v", tt.input, tt.format, tt.expected, decoded)
+		}
+	}
+}
+
+func TestDecodeError(t *testing.T) {
+	testCases := []struct {
+		format Format
+		input  string
+	}{
+		{HACK, "0000000000000000\r\n000000000000001\r\n"},
+		{BINARY, "\x00"},
+		{INTEL_HEX, ":0400000000020C10FE\n:00000001FF\n"},
+		{INTEL_HEX, ":0400000000020C10CE\n"},
+		{LOGISIM, "1 2 3\n"},
+		{LOGISIM, "v2.0 raw\n10000\n"},
+		{READMEMH, "@10000\n0\n"},
+		{READMEMB, "2\n"},
+	}
+	for _, tt := range testCases {
+		if _, err := Decode([]byte(tt.input), tt.format); err == nil {
+			t.Fatalf("Decode(%q, %s) should return error", tt.input, tt.format)
+		}
+	}
+}
+
+func TestLargeIntelHex(t *testing.T) {
+	large := make([]uint16, 40000)
+	large[len(large)-1] = 0x1234
+	hex := string(Encode(large, INTEL_HEX))
+	if !strings.Contains(hex, ":020000040001F9\r\n") {
+		t.Fatalf("Intel HEX over 64KB should have extended linear address record")
+	}
+	decoded, err := Decode([]byte(hex), INTEL_HEX)
+	if err != nil || !reflect.DeepEqual(decoded, large) {
+		t.Fatalf("Decode() should read Intel HEX over 64KB: %v", err)
+	}
+}
+
+func TestFormatOf(t *testing.T) {
+	testCases := []struct {
+		filename string
+		format   Format
+	}{
+		{"Pong.hack", HACK},
+		{"Pong.bin", BINARY},
+		{"Pong.HEX", INTEL_HEX},
+		{"Pong.rom", LOGISIM},
+		{"Pong.memb", READMEMB},
+		{"Pong.memh", READMEMH},
+		{"Pong.out", HACK},
+	}
+	for _, tt := range testCases {
+		if FormatOf(tt.filename) != tt.format {
+			t.Fatalf("FormatOf(%q) should be %s. got %s", tt.filename, tt.format, FormatOf(tt.filename))
+		}
+	}
+	if _, err := ParseFormat("srec"); err == nil {
+		t.Fatalf("ParseFormat() should return error for unknown format")
+	}
+}
@@ -1,11 +1,16 @@
 package symboltable
 
 import (
+	"assembler/value"
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
 )
 
 type SymbolTable struct {
 	SymbolTableDict map[string]int
+	symbolKinds     map[string]SymbolKind
 }
 
 func getInitialSymbolTable() map[string]int {
@@ -19,7 +24,11 @@ func getInitialSymbolTable() map[string]int {
 
 func New() *SymbolTable {
 	initialSymbolTable := getInitialSymbolTable()
-	return &SymbolTable{SymbolTableDict: initialSymbolTable}
+	symbolKinds := map[string]SymbolKind{}
+	for symbol := range initialSymbolTable {
+		symbolKinds[symbol] = PREDEFINED
+	}
+	return &SymbolTable{SymbolTableDict: initialSymbolTable, symbolKinds: symbolKinds}
 }
 
 func (st *SymbolTable) Contains(symbol string) bool {
@@ -39,3 +48,108 @@ func (st *SymbolTable) AddEntry(symbol string, address int) error {
 	st.SymbolTableDict[symbol] = address
 	return nil
 }
+
+// Kind returns kind of symbol. symbols added by AddEntry are classed as variable.
+func (st *SymbolTable) Kind(symbol string) (SymbolKind, bool) {
+	if !st.Contains(symbol) {
+		return "", false
+	}
+	kind, ok := st.symbolKinds[symbol]
+	if !ok {
+		return VARIABLE, true
+	}
+	return kind, true
+}
+
+// AddLabel adds symbol defined by "(LABEL)"
+func (st *SymbolTable) AddLabel(symbol string, address int) error {
+	st.symbolKinds[symbol] = LABEL
+	return st.AddEntry(symbol, address)
+}
+
+// AddConstant adds symbol defined by ".equ NAME value"
+func (st *SymbolTable) AddConstant(symbol string, value int) error {
+	st.symbolKinds[symbol] = CONSTANT
+	return st.AddEntry(symbol, value)
+}
+
+// AddVariable adds symbol allocated to RAM
+func (st *SymbolTable) AddVariable(symbol string, address int) error {
+	st.symbolKinds[symbol] = VARIABLE
+	return st.AddEntry(symbol, address)
+}
+
+// Symbols returns all symbols in order of predefined, label, constant and variable. symbols of same kind are sorted by address and name.
+// symbols added by AddEntry are classed as variable.
+func (st *SymbolTable) Symbols() []Symbol {
+	symbols := []Symbol{}
+	for name, address := range st.SymbolTableDict {
+		kind, _ := st.Kind(name)
+		symbols = append(symbols, Symbol{Kind: kind, Address: address, Name: name})
+	}
+	kindOrder := map[SymbolKind]int{PREDEFINED: 0, LABEL: 1, CONSTANT: 2, VARIABLE: 3}
+	sort.Slice(symbols, func(i, j int) bool {
+		a, b := symbols[i], symbols[j]
+		if a.Kind != b.Kind {
+			return kindOrder[a.Kind] < kindOrder[b.Kind]
+		}
+		if a.Address != b.Address {
+			return a.Address < b.Address
+		}
+		return a.Name < b.Name
+	})
+	return symbols
+}
+
+type SymbolKind string
+
+const (
+	PREDEFINED SymbolKind = "predefined"
+	LABEL      SymbolKind = "label"
+	CONSTANT   SymbolKind = "constant"
+	VARIABLE   SymbolKind = "variable"
+)
+
+// Symbol is entry of symbol file(.sym)
+type Symbol struct {
+	Kind    SymbolKind
+	Address int
+	Name    string
+}
+
+// ParseSymbolFile parses symbol file(.sym). each line is "<kind> <address> <name>". e.g. "label 10 LOOP", "variable 16 i"
+// kind is predefined, label, constant or variable and address is decimal. address of constant is its value.
+// empty lines and lines starting with "//" are ignored.
+func ParseSymbolFile(sym string) ([]Symbol, error) {
+	symbols := []Symbol{}
+	for i, line := range strings.Split(sym, value.LF) {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "//") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("line %d: %q should be \"<kind> <address> <name>\"", i+1, line)
+		}
+		kind := SymbolKind(fields[0])
+		if kind != PREDEFINED && kind != LABEL && kind != CONSTANT && kind != VARIABLE {
+			return nil, fmt.Errorf("line %d: unknown kind %q", i+1, fields[0])
+		}
+		address, err := strconv.Atoi(fields[1])
+		if err != nil || address < 0 {
+			return nil, fmt.Errorf("line %d: invalid address %q", i+1, fields[1])
+		}
+		symbols = append(symbols, Symbol{Kind: kind, Address: address, Name: fields[2]})
+	}
+	return symbols, nil
+}
+
+// FormatSymbolFile writes symbols in format of symbol file(.sym) which ParseSymbolFile reads
+func FormatSymbolFile(symbols []Symbol) string {
+	var out strings.Builder
+	out.WriteString("// kind address name" + value.NEW_LINE)
+	for _, symbol := range symbols {
+		out.WriteString(fmt.Sprintf("%s %d %s", symbol.Kind, symbol.Address, symbol.Name) + value.NEW_LINE)
+	}
+	return out.String()
+}
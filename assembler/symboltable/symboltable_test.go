@@ -49,3 +49,41 @@ func TestAddEntry(t *testing.T) {
 		}
 	}
 }
+
+func TestSymbols(t *testing.T) {
+	symbolTable := New()
+	symbolTable.AddVariable("i", 16)
+	symbolTable.AddLabel("LOOP", 4)
+	symbolTable.AddLabel("END", 2)
+	symbols := symbolTable.Symbols()
+	if len(symbols) != 23+3 {
+		t.Fatalf("Symbols() should return %d symbols. got %d", 23+3, len(symbols))
+	}
+	expected := []Symbol{{LABEL, 2, "END"}, {LABEL, 4, "LOOP"}, {VARIABLE, 16, "i"}}
+	for i, symbol := range symbols[23:] {
+		if symbol != expected[i] {
+			t.Errorf("symbol should be %+v. got %+v", expected[i], symbol)
+		}
+	}
+	if symbols[0] != (Symbol{PREDEFINED, 0, "R0"}) || symbols[1] != (Symbol{PREDEFINED, 0, "SP"}) {
+		t.Errorf("predefined symbols should be sorted by address and name. got %+v, %+v", symbols[0], symbols[1])
+	}
+}
+
+func TestFormatSymbolFile(t *testing.T) {
+	symbols := []Symbol{{PREDEFINED, 16384, "SCREEN"}, {LABEL, 10, "LOOP"}, {VARIABLE, 16, "i"}}
+	sym := FormatSymbolFile(symbols)
+	expected := "// kind address name\r\npredefined 16384 SCREEN\r\nlabel 10 LOOP\r\nvariable 16 i\r\n"
+	if sym != expected {
+		t.Fatalf("FormatSymbolFile() should be %q. got %q", expected, sym)
+	}
+	parsed, err := ParseSymbolFile(sym)
+	if err != nil {
+		t.Fatalf("ParseSymbolFile() returned error: %s", err)
+	}
+	for i := range symbols {
+		if parsed[i] != symbols[i] {
+			t.Errorf("symbol should be %+v. got %+v", symbols[i], parsed[i])
+		}
+	}
+}
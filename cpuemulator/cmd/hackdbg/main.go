@@ -0,0 +1,53 @@
+package main
+
+import (
+	"assembler/rom"
+	"cpuemulator/debugger"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// PROMPT is written before each command when stdin is terminal
+const PROMPT = "(hackdbg) "
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [-sym file.sym] [-f format] [-limit N] [-history N] <file.asm|file.hack>\n", os.Args[0])
+		fmt.Fprintln(os.Stderr, "debugs Hack program by commands read from stdin. \"help\" shows commands")
+		flag.PrintDefaults()
+	}
+	symFilename := flag.String("sym", "", "symbol file(.sym) written by hackasm -sym. labels and variables of .asm are used without it")
+	formatName := flag.String("f", "", "format of ROM image: hack, bin, ihex, logisim, readmemb or readmemh (default: decided by extension)")
+	maxCycles := flag.Uint64("limit", debugger.DEFAULT_MAX_CYCLES, "max number of instructions executed by a continue or next")
+	historySize := flag.Int("history", debugger.DEFAULT_HISTORY_SIZE, "number of instructions which reverse-step can undo")
+	flag.Parse()
+	if flag.NArg() != 1 || *historySize < 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	format := rom.Format("")
+	if *formatName != "" {
+		f, err := rom.ParseFormat(*formatName)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		format = f
+	}
+	d, err := debugger.LoadFile(flag.Arg(0), format, *symFilename)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	d.MaxCycles, d.HistorySize = *maxCycles, *historySize
+	prompt := ""
+	// スクリプトを流し込むときはプロンプトを出さない
+	if stat, err := os.Stdin.Stat(); err == nil && stat.Mode()&os.ModeCharDevice != 0 {
+		prompt = PROMPT
+	}
+	if err := d.Run(os.Stdin, os.Stdout, prompt); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
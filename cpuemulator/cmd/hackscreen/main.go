@@ -0,0 +1,179 @@
+package main
+
+import (
+	"assembler/rom"
+	"cpuemulator/debugger"
+	"cpuemulator/keyboard"
+	"cpuemulator/screen"
+	"errors"
+	"flag"
+	"fmt"
+	"image/png"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LIVE_EVERY is default number of instructions between frames in live mode
+const LIVE_EVERY = 100000
+
+// errInterrupted stops live mode by Ctrl-C
+var errInterrupted = errors.New("interrupted")
+
+// ramFlags is values of -ram flags. e.g. "R0=4"
+type ramFlags []string
+
+func (f *ramFlags) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *ramFlags) Set(value string) error {
+	if !strings.Contains(value, "=") {
+		return fmt.Errorf("%q should be ADDRESS=VALUE", value)
+	}
+	*f = append(*f, value)
+	return nil
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [-f format] [-ram ADDRESS=VALUE]... [-limit N] [-every N] [-png file.png] [-term mode] [-keys file | -live] <file.asm|file.hack>\n", os.Args[0])
+		fmt.Fprintln(os.Stderr, "runs Hack program and writes screen to PNG or terminal")
+		flag.PrintDefaults()
+	}
+	var rams ramFlags
+	flag.Var(&rams, "ram", "set RAM before running. e.g. -ram R0=4 -ram 256=-1")
+	formatName := flag.String("f", "", "format of ROM image: hack, bin, ihex, logisim, readmemb or readmemh (default: decided by extension)")
+	maxCycles := flag.Uint64("limit", 1000000, "max number of instructions executed")
+	every := flag.Uint64("every", 0, "take a frame every N instructions. only screen at end of program is taken by default")
+	pngFilename := flag.String("png", "", "PNG file to write. it should have verb of frame number like frame%04d.png with -every")
+	modeName := flag.String("term", "", "render screen to stdout by half or braille characters")
+	keysFilename := flag.String("keys", "", "keyboard script which presses keys at given cycles. e.g. \"10000 press left\"")
+	live := flag.Bool("live", false, fmt.Sprintf("map keys pressed in terminal to KBD and render screen every N instructions (default -every %d -term braille). Ctrl-C quits", LIVE_EVERY))
+	hold := flag.Duration("hold", keyboard.DEFAULT_HOLD, "how long key is pressed after terminal sends it in live mode")
+	fps := flag.Int("fps", 30, "max frames per second in live mode")
+	flag.Parse()
+	if flag.NArg() != 1 || (*pngFilename == "" && *modeName == "" && !*live) || (*live && *keysFilename != "") || *fps <= 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *live {
+		given := map[string]bool{}
+		flag.Visit(func(f *flag.Flag) { given[f.Name] = true })
+		// ライブモードではCtrl-Cまで実行し続ける
+		if !given["limit"] {
+			*maxCycles = math.MaxUint64
+		}
+		if *every == 0 {
+			*every = LIVE_EVERY
+		}
+		if *modeName == "" {
+			*modeName = string(screen.BRAILLE)
+		}
+	}
+	if *every != 0 && *pngFilename != "" && !strings.Contains(*pngFilename, "%") {
+		fmt.Fprintln(os.Stderr, "-png should have verb of frame number like frame%04d.png with -every")
+		os.Exit(2)
+	}
+	mode := screen.Mode("")
+	if *modeName != "" {
+		m, err := screen.ParseMode(*modeName)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		mode = m
+	}
+	format := rom.Format("")
+	if *formatName != "" {
+		f, err := rom.ParseFormat(*formatName)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		format = f
+	}
+	// ラベルや変数名でRAMを指定できるよう、デバッガの読み込みを使う
+	d, err := debugger.LoadFile(flag.Arg(0), format, "")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	for _, ram := range rams {
+		i := strings.Index(ram, "=")
+		address, err := d.ParseRAMAddress(ram[:i])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		value, err := strconv.ParseInt(ram[i+1:], 0, 16)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%q is not 16-bit value\n", ram[i+1:])
+			os.Exit(2)
+		}
+		d.CPU.RAM[address] = int16(value)
+	}
+	if *keysFilename != "" {
+		script, err := keyboard.ParseFile(*keysFilename)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		d.CPU.Keyboard = script
+	}
+	var terminal *keyboard.Terminal
+	if *live {
+		terminal, err = keyboard.OpenTerminal()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "live mode needs terminal: %s\n", err)
+			os.Exit(1)
+		}
+		defer terminal.Close()
+		terminal.Hold = *hold
+		d.CPU.Keyboard = terminal
+		fmt.Print("\x1b[2J") // 画面を消去
+	}
+	next := time.Now()
+	err = screen.Run(d.CPU, *maxCycles, *every, func(frame screen.Frame) error {
+		if terminal != nil {
+			if terminal.Interrupted() {
+				return errInterrupted
+			}
+			// 実行が速すぎるとゲームにならないので、fpsを上限にする
+			time.Sleep(time.Until(next))
+			next = time.Now().Add(time.Second / time.Duration(*fps))
+			fmt.Print("\x1b[H") // カーソルを左上に戻して上書きする
+		}
+		if mode != "" {
+			fmt.Printf("frame %d at %d cycles\n", frame.Index, frame.Cycles)
+			if err := screen.RenderImage(os.Stdout, frame.Image, mode); err != nil {
+				return err
+			}
+		}
+		if *pngFilename == "" {
+			return nil
+		}
+		filename := *pngFilename
+		if *every != 0 {
+			filename = fmt.Sprintf(*pngFilename, frame.Index)
+		}
+		file, err := os.Create(filename)
+		if err != nil {
+			return err
+		}
+		if err := png.Encode(file, frame.Image); err != nil {
+			file.Close()
+			return err
+		}
+		return file.Close()
+	})
+	if err != nil && err != errInterrupted {
+		if terminal != nil {
+			terminal.Close()
+		}
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,316 @@
+package debugger
+
+import (
+	"assembler/symboltable"
+	"bufio"
+	"cpuemulator/hackcpu"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ErrCommandFailed is returned by Run when any command of script fails
+var ErrCommandFailed = errors.New("some commands failed")
+
+// HELP is written by "help" command
+const HELP = `break|b LOCATION       stop when PC reaches ROM address or label
+delete|d LOCATION      delete breakpoint
+watch|w [-change] ADDR stop when RAM address(e.g. 256, RAM[256], SP, i) is written, or changed with -change
+unwatch ADDRESS        delete watchpoint
+info                   show breakpoints and watchpoints
+step|s [N]             execute N instructions
+next|n [N]             like step, but run "0;JMP" of call until it returns
+continue|c             execute until breakpoint, watchpoint or halt
+reverse-step|rs [N]    undo N instructions
+regs|r                 show registers and current instruction
+mem|x ADDRESS [N]      dump N words of RAM
+rom LOCATION [N]       show N instructions of ROM
+set TARGET VALUE       set A, D, PC or RAM address
+reset                  set PC to 0. RAM is kept
+help|h                 show this help
+quit|q                 quit`
+
+// Run executes commands read from r line by line and writes results to w. empty lines and lines starting with "#" are ignored.
+// prompt is written before each command if not empty. failed commands are reported to w and ErrCommandFailed is returned at the end.
+func (d *Debugger) Run(r io.Reader, w io.Writer, prompt string) error {
+	scanner := bufio.NewScanner(r)
+	failed := false
+	for {
+		fmt.Fprint(w, prompt)
+		if !scanner.Scan() {
+			break
+		}
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		quit, err := d.Execute(line, w)
+		if err != nil {
+			fmt.Fprintf(w, "error: %s\n", err)
+			failed = true
+		}
+		if quit {
+			break
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if failed {
+		return ErrCommandFailed
+	}
+	return nil
+}
+
+// Execute executes a command of debugger and writes result to w. it returns true for "quit".
+func (d *Debugger) Execute(line string, w io.Writer) (bool, error) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return false, nil
+	}
+	name, args := fields[0], fields[1:]
+	switch name {
+	case "break", "b":
+		return false, d.withAddress(args, d.ParseROMAddress, func(address uint16) error {
+			d.SetBreakpoint(address)
+			fmt.Fprintf(w, "breakpoint at %s\n", d.DescribeROM(address))
+			return nil
+		})
+	case "delete", "d":
+		return false, d.withAddress(args, d.ParseROMAddress, d.DeleteBreakpoint)
+	case "watch", "w":
+		mode := WRITE
+		if len(args) > 0 && args[0] == "-change" {
+			mode, args = CHANGE, args[1:]
+		}
+		return false, d.withAddress(args, d.ParseRAMAddress, func(address uint16) error {
+			d.Watch(address, mode)
+			d.writeWatchpoint(w, address)
+			return nil
+		})
+	case "unwatch":
+		return false, d.withAddress(args, d.ParseRAMAddress, d.Unwatch)
+	case "info":
+		d.info(w)
+		return false, nil
+	case "step", "s", "next", "n":
+		n, err := parseCount(args)
+		if err != nil {
+			return false, err
+		}
+		stop := Stop{Reason: STEPPED}
+		for i := uint64(0); i < n && stop.Reason == STEPPED; i++ {
+			if name == "step" || name == "s" {
+				stop = d.Step(1)
+			} else {
+				stop = d.Next()
+			}
+		}
+		d.writeStop(w, stop)
+		return false, nil
+	case "continue", "c":
+		if len(args) > 0 {
+			return false, fmt.Errorf("continue takes no arguments")
+		}
+		d.writeStop(w, d.Continue())
+		return false, nil
+	case "reverse-step", "rs":
+		n, err := parseCount(args)
+		if err != nil {
+			return false, err
+		}
+		if _, err := d.ReverseStep(n); err != nil {
+			return false, err
+		}
+		fmt.Fprintln(w, d.Location(d.CPU.PC))
+		return false, nil
+	case "regs", "r":
+		cpu := d.CPU
+		fmt.Fprintf(w, "A=%d D=%d M=%d PC=%d cycles=%d\n", cpu.A, cpu.D, cpu.RAM[uint16(cpu.A)&(hackcpu.RAM_SIZE-1)], cpu.PC, cpu.Cycles)
+		fmt.Fprintln(w, d.Location(cpu.PC))
+		return false, nil
+	case "mem", "x":
+		return false, d.dump(args, d.ParseRAMAddress, hackcpu.RAM_SIZE, func(address uint16) {
+			fmt.Fprintf(w, "%s = %d\n", d.DescribeRAM(address), d.CPU.RAM[address])
+		})
+	case "rom":
+		return false, d.dump(args, d.ParseROMAddress, hackcpu.ROM_SIZE, func(address uint16) {
+			fmt.Fprintln(w, d.Location(address))
+		})
+	case "set":
+		return false, d.set(args, w)
+	case "reset":
+		d.Reset()
+		fmt.Fprintln(w, d.Location(d.CPU.PC))
+		return false, nil
+	case "help", "h":
+		fmt.Fprintln(w, HELP)
+		return false, nil
+	case "quit", "q":
+		return true, nil
+	default:
+		return false, fmt.Errorf("unknown command %q. \"help\" shows commands", name)
+	}
+}
+
+func (d *Debugger) withAddress(args []string, parse func(string) (uint16, error), f func(uint16) error) error {
+	if len(args) != 1 {
+		return fmt.Errorf("address should be given")
+	}
+	address, err := parse(args[0])
+	if err != nil {
+		return err
+	}
+	return f(address)
+}
+
+// dump calls f for N words from address of args "ADDRESS [N]"
+func (d *Debugger) dump(args []string, parse func(string) (uint16, error), size int, f func(uint16)) error {
+	if len(args) == 0 || len(args) > 2 {
+		return fmt.Errorf("address and optional count should be given")
+	}
+	address, err := parse(args[0])
+	if err != nil {
+		return err
+	}
+	n, err := parseCount(args[1:])
+	if err != nil {
+		return err
+	}
+	for i := uint64(0); i < n && int(address)+int(i) < size; i++ {
+		f(address + uint16(i))
+	}
+	return nil
+}
+
+func (d *Debugger) set(args []string, w io.Writer) error {
+	if len(args) != 2 {
+		return fmt.Errorf("set should be \"set TARGET VALUE\"")
+	}
+	target, valueStr := args[0], args[1]
+	if target == "PC" {
+		address, err := d.ParseROMAddress(valueStr)
+		if err != nil {
+			return err
+		}
+		d.CPU.PC = address
+		d.ClearHistory()
+		fmt.Fprintln(w, d.Location(address))
+		return nil
+	}
+	value, err := parseNumber(valueStr)
+	if err != nil || value < -32768 || value > 65535 {
+		return fmt.Errorf("%q is not 16-bit value", valueStr)
+	}
+	switch target {
+	case "A":
+		d.CPU.A = int16(value)
+	case "D":
+		d.CPU.D = int16(value)
+	default:
+		address, err := d.ParseRAMAddress(target)
+		if err != nil {
+			return err
+		}
+		d.CPU.RAM[address] = int16(value)
+		fmt.Fprintf(w, "%s = %d\n", d.DescribeRAM(address), d.CPU.RAM[address])
+	}
+	// 実行していない変更は逆実行できないので、履歴を捨てる
+	d.ClearHistory()
+	return nil
+}
+
+func (d *Debugger) info(w io.Writer) {
+	breakpoints, watchpoints := d.Breakpoints(), d.Watchpoints()
+	if len(breakpoints) == 0 && len(watchpoints) == 0 {
+		fmt.Fprintln(w, "no breakpoints or watchpoints")
+	}
+	for _, address := range breakpoints {
+		fmt.Fprintf(w, "breakpoint at %s\n", d.DescribeROM(address))
+	}
+	for _, address := range watchpoints {
+		d.writeWatchpoint(w, address)
+	}
+}
+
+func (d *Debugger) writeWatchpoint(w io.Writer, address uint16) {
+	if mode, _ := d.WatchMode(address); mode == CHANGE {
+		fmt.Fprintf(w, "watchpoint at %s on change\n", d.DescribeRAM(address))
+		return
+	}
+	fmt.Fprintf(w, "watchpoint at %s\n", d.DescribeRAM(address))
+}
+
+// writeStop writes why execution stopped and current instruction
+func (d *Debugger) writeStop(w io.Writer, stop Stop) {
+	switch stop.Reason {
+	case BREAKPOINT:
+		fmt.Fprintf(w, "breakpoint at %s\n", d.DescribeROM(d.CPU.PC))
+	case WATCHPOINT:
+		write := stop.Write
+		fmt.Fprintf(w, "watchpoint %s: %d -> %d by %s\n", d.DescribeRAM(write.Address), write.Old, write.New, d.Location(write.PC))
+	case HALTED:
+		fmt.Fprintf(w, "program halted after %d cycles\n", d.CPU.Cycles)
+	case CYCLE_LIMIT:
+		fmt.Fprintf(w, "stopped by cycle limit %d\n", d.MaxCycles)
+	}
+	fmt.Fprintln(w, d.Location(d.CPU.PC))
+}
+
+// ParseROMAddress parses ROM address written in number or label
+func (d *Debugger) ParseROMAddress(str string) (uint16, error) {
+	if value, err := parseNumber(str); err == nil {
+		if value < 0 || value >= hackcpu.ROM_SIZE {
+			return 0, fmt.Errorf("ROM address %d is out of range 0..%d", value, hackcpu.ROM_SIZE-1)
+		}
+		return uint16(value), nil
+	}
+	symbol, ok := d.symbols[str]
+	if !ok || symbol.Kind != symboltable.LABEL {
+		return 0, fmt.Errorf("%q is neither ROM address nor label", str)
+	}
+	return uint16(symbol.Address), nil
+}
+
+// ParseRAMAddress parses RAM address written in number, "RAM[n]" or symbol. e.g. "256", "RAM[256]", "SP"
+func (d *Debugger) ParseRAMAddress(str string) (uint16, error) {
+	numberStr := str
+	if strings.HasPrefix(str, "RAM[") && strings.HasSuffix(str, "]") {
+		numberStr = str[len("RAM[") : len(str)-1]
+	}
+	value, err := parseNumber(numberStr)
+	if err != nil {
+		symbol, ok := d.symbols[str]
+		if !ok || symbol.Kind == symboltable.LABEL {
+			return 0, fmt.Errorf("%q is neither RAM address nor variable", str)
+		}
+		value = symbol.Address
+	}
+	if value < 0 || value >= hackcpu.RAM_SIZE {
+		return 0, fmt.Errorf("RAM address %d is out of range 0..%d", value, hackcpu.RAM_SIZE-1)
+	}
+	return uint16(value), nil
+}
+
+// parseNumber parses decimal and hex(0x4000)
+func parseNumber(str string) (int, error) {
+	if strings.HasPrefix(str, "0x") || strings.HasPrefix(str, "0X") {
+		value, err := strconv.ParseInt(str[2:], 16, 32)
+		return int(value), err
+	}
+	return strconv.Atoi(str)
+}
+
+// parseCount parses optional count of instructions or words. it is 1 if not given.
+func parseCount(args []string) (uint64, error) {
+	if len(args) == 0 {
+		return 1, nil
+	}
+	n, err := strconv.ParseUint(args[0], 10, 64)
+	if len(args) > 1 || err != nil || n == 0 {
+		return 0, fmt.Errorf("count should be positive number")
+	}
+	return n, nil
+}
@@ -0,0 +1,411 @@
+package debugger
+
+import (
+	"assembler"
+	"assembler/disassembler"
+	"assembler/rom"
+	"assembler/symboltable"
+	"cpuemulator/hackcpu"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// StopReason is reason why execution stopped
+type StopReason string
+
+const (
+	STEPPED     StopReason = "STEPPED"     // requested instructions are executed
+	BREAKPOINT  StopReason = "BREAKPOINT"  // PC reached breakpoint
+	WATCHPOINT  StopReason = "WATCHPOINT"  // instruction wrote watched RAM
+	HALTED      StopReason = "HALTED"      // program entered infinite loop of its end. e.g. "(END) @END 0;JMP"
+	CYCLE_LIMIT StopReason = "CYCLE_LIMIT" // MaxCycles instructions are executed without stop
+)
+
+// WatchMode is condition on which watchpoint stops execution
+type WatchMode string
+
+const (
+	WRITE  WatchMode = "WRITE"  // stop whenever instruction writes RAM, even if the value is the same
+	CHANGE WatchMode = "CHANGE" // stop only when instruction changes value of RAM
+)
+
+// DEFAULT_MAX_CYCLES is default limit of instructions executed by Continue and Next
+const DEFAULT_MAX_CYCLES = 10000000
+
+// DEFAULT_HISTORY_SIZE is default number of instructions which ReverseStep can undo
+const DEFAULT_HISTORY_SIZE = 10000
+
+// D_EQ_A is word of "D=A". call idiom loads return address by "@RET" and "D=A" before "0;JMP"
+const D_EQ_A = 0xEC10
+
+// Write is RAM written by an instruction
+type Write struct {
+	PC      uint16 // ROM address of instruction which wrote RAM
+	Address uint16
+	Old     int16
+	New     int16
+}
+
+// Stop is result of Step, Next and Continue
+type Stop struct {
+	Reason StopReason
+	Write  *Write // watched RAM written by last instruction. only for WATCHPOINT
+}
+
+// state is CPU before an instruction is executed. ReverseStep restores it.
+type state struct {
+	pc      uint16
+	a       int16
+	d       int16
+	cycles  uint64
+	halted  bool
+	kbd     int16 // KBD is overwritten by CPU.Keyboard before the instruction
+	write   Write
+	written bool
+}
+
+// Debugger executes program on CPU under control of breakpoints and watchpoints
+type Debugger struct {
+	CPU         *hackcpu.CPU
+	MaxCycles   uint64 // max number of instructions executed by a Continue or Next
+	HistorySize int    // max number of instructions which ReverseStep can undo
+	symbols     map[string]symboltable.Symbol
+	labels      []symboltable.Symbol // labels sorted by address
+	ramNames    map[uint16]string
+	source      map[uint16]string // source line of ROM address. instructions are disassembled if it is not given
+	returns     map[uint16]bool   // return addresses of call idiom
+	breakpoints map[uint16]bool
+	watchpoints map[uint16]WatchMode
+	history     []state
+}
+
+// New returns debugger of program loaded to cpu. program should be loaded before New. symbols (e.g. parsed .sym) may be nil.
+func New(cpu *hackcpu.CPU, symbols []symboltable.Symbol) *Debugger {
+	d := &Debugger{
+		CPU:         cpu,
+		MaxCycles:   DEFAULT_MAX_CYCLES,
+		HistorySize: DEFAULT_HISTORY_SIZE,
+		symbols:     map[string]symboltable.Symbol{},
+		ramNames:    map[uint16]string{},
+		source:      map[uint16]string{},
+		returns:     findReturns(cpu),
+		breakpoints: map[uint16]bool{},
+		watchpoints: map[uint16]WatchMode{},
+	}
+	if symbols == nil {
+		symbols = symboltable.New().Symbols()
+	}
+	for _, symbol := range symbols {
+		d.symbols[symbol.Name] = symbol
+		switch symbol.Kind {
+		case symboltable.LABEL:
+			d.labels = append(d.labels, symbol)
+		case symboltable.PREDEFINED, symboltable.VARIABLE:
+			// SP と R0 のように同じアドレスなら R0 以外の名前で表示する
+			address := uint16(symbol.Address)
+			if name, ok := d.ramNames[address]; !ok || isRegisterName(name) {
+				d.ramNames[address] = symbol.Name
+			}
+		}
+	}
+	sort.SliceStable(d.labels, func(i, j int) bool { return d.labels[i].Address < d.labels[j].Address })
+	return d
+}
+
+// isRegisterName returns whether name is virtual register R0..R15
+func isRegisterName(name string) bool {
+	return len(name) >= 2 && name[0] == 'R' && strings.Trim(name[1:], "0123456789") == ""
+}
+
+// LoadAsm assembles asm and returns debugger which shows its source and symbols
+func LoadAsm(asm string, filename string) (*Debugger, error) {
+	program, err := assembler.AssembleProgram(asm, filename)
+	if err != nil {
+		return nil, err
+	}
+	cpu := hackcpu.New()
+	if err := cpu.LoadWords(rom.Words(program.Binary)); err != nil {
+		return nil, err
+	}
+	d := New(cpu, program.SymbolTable.Symbols())
+	for _, line := range program.Listing {
+		if line.Address >= 0 {
+			d.source[uint16(line.Address)] = line.Source
+		}
+	}
+	return d, nil
+}
+
+// LoadFile loads program to debug. assembly(.asm) is assembled by LoadAsm.
+// other files are read as ROM image in format (decided by extension if empty) with symbol file(.sym) symFilename which may be empty.
+func LoadFile(filename string, format rom.Format, symFilename string) (*Debugger, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	if strings.ToLower(filepath.Ext(filename)) == ".asm" {
+		return LoadAsm(string(data), filename)
+	}
+	if format == "" {
+		format = rom.FormatOf(filename)
+	}
+	words, err := rom.Decode(data, format)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", filename, err)
+	}
+	var symbols []symboltable.Symbol
+	if symFilename != "" {
+		sym, err := ioutil.ReadFile(symFilename)
+		if err != nil {
+			return nil, err
+		}
+		if symbols, err = symboltable.ParseSymbolFile(string(sym)); err != nil {
+			return nil, fmt.Errorf("%s: %s", symFilename, err)
+		}
+	}
+	cpu := hackcpu.New()
+	if err := cpu.LoadWords(words); err != nil {
+		return nil, err
+	}
+	return New(cpu, symbols), nil
+}
+
+// exec executes an instruction and records it to history
+func (d *Debugger) exec() (Write, bool) {
+	cpu := d.CPU
+	instruction := cpu.ROM[cpu.PC]
+	s := state{pc: cpu.PC, a: cpu.A, d: cpu.D, cycles: cpu.Cycles, halted: cpu.Halted(), kbd: cpu.RAM[hackcpu.KBD]}
+	// dest に M がある C 命令だけが RAM に書き込む
+	if instruction&0x8000 != 0 && instruction&0x0008 != 0 {
+		address := uint16(cpu.A) & (hackcpu.RAM_SIZE - 1)
+		s.write, s.written = Write{PC: cpu.PC, Address: address, Old: cpu.RAM[address]}, true
+	}
+	cpu.Step()
+	if s.written {
+		s.write.New = cpu.RAM[s.write.Address]
+	}
+	if d.HistorySize > 0 {
+		d.history = append(d.history, s)
+		if len(d.history) > d.HistorySize {
+			d.history = d.history[len(d.history)-d.HistorySize:]
+		}
+	}
+	return s.write, s.written
+}
+
+// run executes at most n instructions until watched RAM is written, program halts, PC reaches breakpoint or done returns true.
+// done is called with address of executed instruction. limitReason is returned if n instructions are executed.
+func (d *Debugger) run(n uint64, done func(pc uint16) bool, limitReason StopReason) Stop {
+	for i := uint64(0); i < n; i++ {
+		pc := d.CPU.PC
+		write, written := d.exec()
+		switch {
+		case written && d.isWatched(write):
+			return Stop{Reason: WATCHPOINT, Write: &write}
+		case d.CPU.Halted():
+			return Stop{Reason: HALTED}
+		case done != nil && done(pc):
+			return Stop{Reason: STEPPED}
+		case d.breakpoints[d.CPU.PC]:
+			return Stop{Reason: BREAKPOINT}
+		}
+	}
+	return Stop{Reason: limitReason}
+}
+
+// Step executes n instructions. it stops early at breakpoint and watchpoint.
+func (d *Debugger) Step(n uint64) Stop {
+	return d.run(n, nil, STEPPED)
+}
+
+// Continue executes instructions until breakpoint, watchpoint, halt or MaxCycles instructions
+func (d *Debugger) Continue() Stop {
+	return d.run(d.MaxCycles, nil, CYCLE_LIMIT)
+}
+
+// Next executes an instruction like Step, but "0;JMP" of call idiom is executed until subroutine returns to next instruction.
+// calls in subroutine are counted, and return is unconditional jump to return address of any call.
+// so recursive calls which return to the same address are skipped.
+func (d *Debugger) Next() Stop {
+	if !d.IsCall(d.CPU.PC) {
+		return d.Step(1)
+	}
+	depth := 0
+	return d.run(d.MaxCycles, func(pc uint16) bool {
+		// 呼び出しで深くなり、無条件ジャンプでどこかの戻り先に着いたら浅くなる
+		if d.IsCall(pc) {
+			depth++
+		} else if isUnconditionalJump(d.CPU.ROM[pc]) && d.returns[d.CPU.PC] {
+			depth--
+		}
+		return depth == 0
+	}, CYCLE_LIMIT)
+}
+
+// IsCall returns whether instruction at pc is unconditional jump of call idiom.
+// caller loads return address, address of instruction after the jump, by "@RET" and "D=A".
+func (d *Debugger) IsCall(pc uint16) bool {
+	return isUnconditionalJump(d.CPU.ROM[pc]) && d.returns[(pc+1)&(hackcpu.ROM_SIZE-1)]
+}
+
+// findReturns finds return addresses of call idiom. return address is loaded by "@RET" and "D=A" and follows unconditional jump.
+func findReturns(cpu *hackcpu.CPU) map[uint16]bool {
+	returns := map[uint16]bool{}
+	for i := 0; i+1 < hackcpu.ROM_SIZE; i++ {
+		ret := cpu.ROM[i]
+		if ret&0x8000 == 0 && ret > 0 && cpu.ROM[i+1] == D_EQ_A && isUnconditionalJump(cpu.ROM[ret-1]) {
+			returns[ret] = true
+		}
+	}
+	return returns
+}
+
+// isUnconditionalJump returns whether word is C instruction which always jumps. e.g. "0;JMP"
+func isUnconditionalJump(word uint16) bool {
+	return word&0xE000 == 0xE000 && word&0x0007 == 0x0007
+}
+
+// ReverseStep undoes at most n instructions in history and returns number of undone instructions.
+// registers, RAM, cycles, KBD and halted state are restored.
+func (d *Debugger) ReverseStep(n uint64) (uint64, error) {
+	if len(d.history) == 0 {
+		return 0, fmt.Errorf("no history to reverse. history has last %d instructions", d.HistorySize)
+	}
+	undone := uint64(0)
+	for ; undone < n && len(d.history) > 0; undone++ {
+		s := d.history[len(d.history)-1]
+		d.history = d.history[:len(d.history)-1]
+		d.CPU.PC, d.CPU.A, d.CPU.D, d.CPU.Cycles = s.pc, s.a, s.d, s.cycles
+		d.CPU.SetHalted(s.halted)
+		d.CPU.RAM[hackcpu.KBD] = s.kbd
+		if s.written {
+			d.CPU.RAM[s.write.Address] = s.write.Old
+		}
+	}
+	return undone, nil
+}
+
+// Reset sets PC to 0 like reset input of CPU and clears history. registers and RAM are kept.
+func (d *Debugger) Reset() {
+	d.CPU.Reset()
+	d.ClearHistory()
+}
+
+// ClearHistory forgets history. it should be called when CPU is changed out of execution.
+func (d *Debugger) ClearHistory() {
+	d.history = nil
+}
+
+// SetBreakpoint stops execution when PC reaches address
+func (d *Debugger) SetBreakpoint(address uint16) {
+	d.breakpoints[address] = true
+}
+
+// DeleteBreakpoint deletes breakpoint at address
+func (d *Debugger) DeleteBreakpoint(address uint16) error {
+	if !d.breakpoints[address] {
+		return fmt.Errorf("no breakpoint at %s", d.DescribeROM(address))
+	}
+	delete(d.breakpoints, address)
+	return nil
+}
+
+// Breakpoints returns addresses of breakpoints in ascending order
+func (d *Debugger) Breakpoints() []uint16 {
+	return sortedAddresses(d.breakpoints)
+}
+
+// Watch stops execution when instruction writes RAM[address]. with CHANGE, writing the same value does not stop.
+// watching the address again replaces its mode.
+func (d *Debugger) Watch(address uint16, mode WatchMode) {
+	d.watchpoints[address] = mode
+}
+
+// Unwatch deletes watchpoint of RAM[address]
+func (d *Debugger) Unwatch(address uint16) error {
+	if _, ok := d.watchpoints[address]; !ok {
+		return fmt.Errorf("no watchpoint at %s", d.DescribeRAM(address))
+	}
+	delete(d.watchpoints, address)
+	return nil
+}
+
+// Watchpoints returns RAM addresses of watchpoints in ascending order
+func (d *Debugger) Watchpoints() []uint16 {
+	addresses := map[uint16]bool{}
+	for address := range d.watchpoints {
+		addresses[address] = true
+	}
+	return sortedAddresses(addresses)
+}
+
+// WatchMode returns mode of watchpoint at RAM[address]
+func (d *Debugger) WatchMode(address uint16) (WatchMode, bool) {
+	mode, ok := d.watchpoints[address]
+	return mode, ok
+}
+
+func (d *Debugger) isWatched(write Write) bool {
+	switch d.watchpoints[write.Address] {
+	case WRITE:
+		return true
+	case CHANGE:
+		// 同じ値の書き込みでは止まらない
+		return write.Old != write.New
+	}
+	return false
+}
+
+func sortedAddresses(set map[uint16]bool) []uint16 {
+	addresses := []uint16{}
+	for address := range set {
+		addresses = append(addresses, address)
+	}
+	sort.Slice(addresses, func(i, j int) bool { return addresses[i] < addresses[j] })
+	return addresses
+}
+
+// DescribeROM returns ROM address with nearest label before it. e.g. "12 (LOOP+2)"
+func (d *Debugger) DescribeROM(address uint16) string {
+	i := sort.Search(len(d.labels), func(i int) bool { return d.labels[i].Address > int(address) })
+	if i == 0 {
+		return fmt.Sprint(address)
+	}
+	label := d.labels[i-1]
+	if offset := int(address) - label.Address; offset > 0 {
+		return fmt.Sprintf("%d (%s+%d)", address, label.Name, offset)
+	}
+	return fmt.Sprintf("%d (%s)", address, label.Name)
+}
+
+// DescribeRAM returns RAM address with its name. e.g. "RAM[0] (SP)"
+func (d *Debugger) DescribeRAM(address uint16) string {
+	if name, ok := d.ramNames[address]; ok {
+		return fmt.Sprintf("RAM[%d] (%s)", address, name)
+	}
+	return fmt.Sprintf("RAM[%d]", address)
+}
+
+// Instruction returns source of instruction at ROM address. it is disassembled if source is not loaded.
+func (d *Debugger) Instruction(address uint16) string {
+	if source, ok := d.source[address]; ok {
+		return source
+	}
+	word := d.CPU.ROM[address]
+	if word&0x8000 == 0 {
+		return fmt.Sprintf("@%d", word)
+	}
+	cCommand, err := disassembler.DecodeCCommand(word)
+	if err != nil {
+		return fmt.Sprintf("illegal instruction %016b", word)
+	}
+	return strings.TrimSpace(cCommand.String())
+}
+
+// Location returns ROM address and its instruction. e.g. "12 (LOOP+2): D=M"
+func (d *Debugger) Location(address uint16) string {
+	return fmt.Sprintf("%s: %s", d.DescribeROM(address), d.Instruction(address))
+}
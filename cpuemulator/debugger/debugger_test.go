@@ -0,0 +1,351 @@
+package debugger
+
+import (
+	"bytes"
+	"cpuemulator/hackcpu"
+	"strings"
+	"testing"
+)
+
+// CALL_ASM calls INC twice. caller pushes return address and INC replaces it with incremented i.
+const CALL_ASM = `@256
+D=A
+@SP
+M=D
+@RET1
+D=A
+@SP
+AM=M+1
+A=A-1
+M=D
+@INC
+0;JMP
+(RET1)
+@RET2
+D=A
+@SP
+AM=M+1
+A=A-1
+M=D
+@INC
+0;JMP
+(RET2)
+(END)
+@END
+0;JMP
+// pops return address and pushes incremented i
+(INC)
+@SP
+AM=M-1
+D=M
+@R15
+M=D
+@i
+MD=M+1
+@SP
+AM=M+1
+A=A-1
+M=D
+@R15
+A=M
+0;JMP`
+
+func loadAsm(t *testing.T, asm string) *Debugger {
+	d, err := LoadAsm(asm, "")
+	if err != nil {
+		t.Fatalf("LoadAsm() returned error: %s", err)
+	}
+	return d
+}
+
+func TestContinueToBreakpoint(t *testing.T) {
+	d := loadAsm(t, CALL_ASM)
+	inc, err := d.ParseROMAddress("INC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.SetBreakpoint(inc)
+	for i := 0; i < 2; i++ {
+		if stop := d.Continue(); stop.Reason != BREAKPOINT || d.CPU.PC != inc {
+			t.Fatalf("Continue() should stop at breakpoint %d. got %s at %d", inc, stop.Reason, d.CPU.PC)
+		}
+	}
+	if stop := d.Continue(); stop.Reason != HALTED {
+		t.Fatalf("Continue() should stop by halt. got %s", stop.Reason)
+	}
+	if d.CPU.RAM[256] != 1 || d.CPU.RAM[257] != 2 {
+		t.Errorf("RAM[256], RAM[257] should be 1, 2. got %d, %d", d.CPU.RAM[256], d.CPU.RAM[257])
+	}
+}
+
+func TestWatchpoint(t *testing.T) {
+	d := loadAsm(t, CALL_ASM)
+	sp, _ := d.ParseRAMAddress("SP")
+	d.Watch(sp, WRITE)
+	expected := []Write{{PC: 3, Address: 0, Old: 0, New: 256}, {PC: 7, Address: 0, Old: 256, New: 257}, {PC: 23, Address: 0, Old: 257, New: 256}}
+	for _, write := range expected {
+		stop := d.Continue()
+		if stop.Reason != WATCHPOINT || *stop.Write != write {
+			t.Fatalf("Continue() should stop by write %+v. got %s %+v", write, stop.Reason, stop.Write)
+		}
+	}
+	d = loadAsm(t, CALL_ASM)
+	address, _ := d.ParseRAMAddress("RAM[256]")
+	d.Watch(address, WRITE)
+	if stop := d.Continue(); stop.Reason != WATCHPOINT || *stop.Write != (Write{PC: 9, Address: 256, Old: 0, New: 12}) {
+		t.Fatalf("Continue() should stop by push of return address to RAM[256]. got %s %+v", stop.Reason, stop.Write)
+	}
+}
+
+func TestWatchpointSameValue(t *testing.T) {
+	asm := "@i\nM=0\nM=1\nM=1\n(END)\n@END\n0;JMP"
+	d := loadAsm(t, asm)
+	address, _ := d.ParseRAMAddress("i")
+	d.Watch(address, WRITE)
+	expected := []Write{{PC: 1, Address: address, Old: 0, New: 0}, {PC: 2, Address: address, Old: 0, New: 1}, {PC: 3, Address: address, Old: 1, New: 1}}
+	for _, write := range expected {
+		if stop := d.Continue(); stop.Reason != WATCHPOINT || *stop.Write != write {
+			t.Fatalf("Continue() should stop by every write %+v. got %s %+v", write, stop.Reason, stop.Write)
+		}
+	}
+
+	d = loadAsm(t, asm)
+	d.Watch(address, CHANGE)
+	if stop := d.Continue(); stop.Reason != WATCHPOINT || *stop.Write != (Write{PC: 2, Address: address, Old: 0, New: 1}) {
+		t.Fatalf("Continue() should stop only when value of i is changed. got %s %+v", stop.Reason, stop.Write)
+	}
+	if stop := d.Continue(); stop.Reason != HALTED {
+		t.Fatalf("Continue() should not stop by writing the same value with CHANGE. got %s %+v", stop.Reason, stop.Write)
+	}
+}
+
+func TestNext(t *testing.T) {
+	d := loadAsm(t, CALL_ASM)
+	d.Step(11)
+	if !d.IsCall(d.CPU.PC) {
+		t.Fatalf("0;JMP at %d should be call", d.CPU.PC)
+	}
+	if stop := d.Next(); stop.Reason != STEPPED || d.CPU.PC != 12 {
+		t.Fatalf("Next() should return to 12. got %s at %d", stop.Reason, d.CPU.PC)
+	}
+	if d.CPU.RAM[16] != 1 {
+		t.Errorf("INC should be executed by Next(). i is %d", d.CPU.RAM[16])
+	}
+	// 戻り先が "@RET", "D=A" で読み込まれない 0;JMP は呼び出しではない
+	d.Step(10)
+	if d.IsCall(d.CPU.PC) {
+		t.Errorf("0;JMP at %d should not be call", d.CPU.PC)
+	}
+}
+
+func TestNextRecursion(t *testing.T) {
+	// COUNT は n が 0 になるまで自分自身を呼び出す。内側の呼び出しも同じ RETC に戻る
+	asm := `@256
+D=A
+@SP
+M=D
+@3
+D=A
+@n
+M=D
+@RET
+D=A
+@SP
+AM=M+1
+A=A-1
+M=D
+@COUNT
+0;JMP
+(RET)
+(END)
+@END
+0;JMP
+(COUNT)
+@n
+MD=M-1
+@BACK
+D;JEQ
+@RETC
+D=A
+@SP
+AM=M+1
+A=A-1
+M=D
+@COUNT
+0;JMP
+(RETC)
+(BACK)
+@SP
+AM=M-1
+A=M
+0;JMP`
+	d := loadAsm(t, asm)
+	retc, _ := d.ParseROMAddress("RETC")
+	d.SetBreakpoint(retc - 1)
+	d.Continue()
+	if d.CPU.RAM[0] != 258 {
+		t.Fatalf("first recursive call should be at SP=258. got %d", d.CPU.RAM[0])
+	}
+	d.DeleteBreakpoint(retc - 1)
+	if stop := d.Next(); stop.Reason != STEPPED || d.CPU.PC != retc || d.CPU.RAM[0] != 257 {
+		t.Fatalf("Next() should return to %d with SP=257. got %s at %d with SP=%d", retc, stop.Reason, d.CPU.PC, d.CPU.RAM[0])
+	}
+}
+
+func TestReverseStep(t *testing.T) {
+	d := loadAsm(t, CALL_ASM)
+	d.Step(10)
+	before := *d.CPU
+	d.Step(20)
+	if n, err := d.ReverseStep(20); err != nil || n != 20 {
+		t.Fatalf("ReverseStep() should undo 20 instructions. got %d, %v", n, err)
+	}
+	if *d.CPU != before {
+		t.Errorf("ReverseStep() should restore registers, RAM and cycles")
+	}
+	d.HistorySize = 5
+	d.Step(10)
+	if n, err := d.ReverseStep(10); err != nil || n != 5 {
+		t.Fatalf("ReverseStep() should undo 5 instructions in history. got %d, %v", n, err)
+	}
+	if _, err := d.ReverseStep(1); err == nil {
+		t.Errorf("ReverseStep() should return error when history is empty")
+	}
+}
+
+// constantKeyboard always presses the same key
+type constantKeyboard struct {
+	key int16
+}
+
+func (k *constantKeyboard) Key(cycles uint64) int16 {
+	return k.key
+}
+
+func TestReverseStepRestoresHaltAndKeyboard(t *testing.T) {
+	d := loadAsm(t, "@KBD\nD=M\n(END)\n@END\n0;JMP")
+	d.CPU.Keyboard = &constantKeyboard{key: 'A'}
+	before := *d.CPU
+	if stop := d.Continue(); stop.Reason != HALTED || d.CPU.RAM[hackcpu.KBD] != 'A' {
+		t.Fatalf("Continue() should halt with KBD 'A'. got %s with KBD %d", stop.Reason, d.CPU.RAM[hackcpu.KBD])
+	}
+	if _, err := d.ReverseStep(1); err != nil || d.CPU.Halted() {
+		t.Fatalf("ReverseStep() should restore CPU which is not halted. got %v, halted %t", err, d.CPU.Halted())
+	}
+	if _, err := d.ReverseStep(3); err != nil || *d.CPU != before {
+		t.Errorf("ReverseStep() should restore registers, RAM, cycles and KBD. got %v", err)
+	}
+}
+
+func TestRun(t *testing.T) {
+	d := loadAsm(t, CALL_ASM)
+	script := `# stop in first call of INC
+break INC
+watch i
+info
+continue
+continue
+x i
+next
+mem SP 2
+delete INC
+unwatch i
+step 4
+regs
+reverse-step 2
+rom RET2 3
+set i 10
+continue
+x 256 2
+quit
+step
+`
+	expected := `breakpoint at 22 (INC)
+watchpoint at RAM[16] (i)
+breakpoint at 22 (INC)
+watchpoint at RAM[16] (i)
+breakpoint at 22 (INC)
+22 (INC): @SP
+watchpoint RAM[16] (i): 0 -> 1 by 28 (INC+6): MD=M+1
+29 (INC+7): @SP
+RAM[16] (i) = 1
+30 (INC+8): AM=M+1
+RAM[0] (SP) = 256
+RAM[1] (LCL) = 0
+34 (INC+12): A=M
+A=15 D=1 M=12 PC=34 cycles=24
+34 (INC+12): A=M
+32 (INC+10): M=D
+20 (RET2): @END
+21 (RET2+1): 0;JMP
+22 (INC): @SP
+RAM[16] (i) = 10
+program halted after 50 cycles
+20 (RET2): @END
+RAM[256] = 1
+RAM[257] = 11
+`
+	var out bytes.Buffer
+	if err := d.Run(strings.NewReader(script), &out, ""); err != nil {
+		t.Fatalf("Run() returned error: %s\n%s", err, out.String())
+	}
+	if out.String() != expected {
+		t.Errorf("output should be\n%s\ngot\n%s", expected, out.String())
+	}
+}
+
+func TestRunWatchChange(t *testing.T) {
+	d := loadAsm(t, "@i\nM=0\nM=1\nM=1\n(END)\n@END\n0;JMP")
+	script := "watch -change i\ninfo\ncontinue\ncontinue\nwatch i\ninfo\n"
+	expected := `watchpoint at RAM[16] (i) on change
+watchpoint at RAM[16] (i) on change
+watchpoint RAM[16] (i): 0 -> 1 by 2: M=1
+3: M=1
+program halted after 6 cycles
+4 (END): @END
+watchpoint at RAM[16] (i)
+watchpoint at RAM[16] (i)
+`
+	var out bytes.Buffer
+	if err := d.Run(strings.NewReader(script), &out, ""); err != nil {
+		t.Fatalf("Run() returned error: %s\n%s", err, out.String())
+	}
+	if out.String() != expected {
+		t.Errorf("output should be\n%s\ngot\n%s", expected, out.String())
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	d := loadAsm(t, CALL_ASM)
+	script := "break NOWHERE\nwatch INC\nwatch RAM[40000]\nstep 0\nreverse-step\ndelete 3\nhoge\nset X\n"
+	expected := `error: "NOWHERE" is neither ROM address nor label
+error: "INC" is neither RAM address nor variable
+error: RAM address 40000 is out of range 0..32767
+error: count should be positive number
+error: no history to reverse. history has last 10000 instructions
+error: no breakpoint at 3
+error: unknown command "hoge". "help" shows commands
+error: set should be "set TARGET VALUE"
+`
+	var out bytes.Buffer
+	if err := d.Run(strings.NewReader(script), &out, ""); err != ErrCommandFailed {
+		t.Fatalf("Run() should return ErrCommandFailed. got %v", err)
+	}
+	if out.String() != expected {
+		t.Errorf("output should be\n%s\ngot\n%s", expected, out.String())
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	d, err := LoadFile("../../hardware/computer/Max.hack", "", "")
+	if err != nil {
+		t.Fatalf("LoadFile() returned error: %s", err)
+	}
+	d.CPU.RAM[0], d.CPU.RAM[1] = 3, 5
+	if stop := d.Continue(); stop.Reason != HALTED || d.CPU.RAM[2] != 5 {
+		t.Fatalf("Max.hack should halt with RAM[2]=5. got %s, %d", stop.Reason, d.CPU.RAM[2])
+	}
+	if instruction := d.Instruction(3); instruction != "D=D-M" {
+		t.Errorf("instruction 3 should be disassembled to D=D-M. got %q", instruction)
+	}
+}
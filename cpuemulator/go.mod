@@ -0,0 +1,7 @@
+module cpuemulator
+
+go 1.16
+
+require assembler v0.0.0
+
+replace assembler => ../assembler
@@ -0,0 +1,228 @@
+package hackcpu
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	ROM_SIZE    = 32768
+	RAM_SIZE    = 32768
+	SCREEN      = 16384 // base address of screen memory map
+	SCREEN_SIZE = 8192  // 512 x 256 pixels / 16 bits
+	KBD         = 24576 // address of keyboard memory map
+)
+
+// ErrCycleLimit is returned by Run when program does not halt in given cycles
+var ErrCycleLimit = errors.New("cycle limit is exceeded")
+
+// Keyboard gives key code of KBD for each instruction. (e.g. keyboard.Script)
+type Keyboard interface {
+	// Key returns key code which is pressed after cycles instructions are executed. 0 means no key is pressed.
+	Key(cycles uint64) int16
+}
+
+// CPU is emulator of Hack computer. It implements semantics of hardware/computer/CPU.hdl with ROM32K and RAM.
+type CPU struct {
+	ROM    [ROM_SIZE]uint16
+	RAM    [RAM_SIZE]int16
+	A      int16
+	D      int16
+	PC     uint16
+	Cycles uint64 // number of executed instructions
+	// Keyboard sets KBD before each instruction if not nil. otherwise KBD is changed only by SetKey.
+	Keyboard Keyboard
+	halted   bool
+}
+
+func New() *CPU {
+	return &CPU{}
+}
+
+// Load writes program to ROM from address 0. each element is instruction written in 16 "0" or "1". (e.g. output of assembler.Assemble)
+// rest of ROM is cleared, CPU is reset and cycle count is cleared.
+func (cpu *CPU) Load(program []string) error {
+	words := make([]uint16, len(program))
+	for i, binary := range program {
+		instruction, err := parseInstruction(binary)
+		if err != nil {
+			return fmt.Errorf("instruction %d: %s", i, err)
+		}
+		words[i] = instruction
+	}
+	return cpu.LoadWords(words)
+}
+
+// LoadWords writes instructions to ROM from address 0 like Load. (e.g. words decoded by assembler/rom)
+func (cpu *CPU) LoadWords(words []uint16) error {
+	if len(words) > ROM_SIZE {
+		return fmt.Errorf("program has %d instructions. ROM can hold only %d instructions", len(words), ROM_SIZE)
+	}
+	rom := [ROM_SIZE]uint16{}
+	copy(rom[:], words)
+	cpu.ROM = rom
+	cpu.Cycles = 0
+	cpu.Reset()
+	return nil
+}
+
+// LoadHack writes program in .hack format (e.g. file written by assembler.AssembleAsmFile) to ROM.
+// empty lines are ignored.
+func (cpu *CPU) LoadHack(hack string) error {
+	program := []string{}
+	for _, line := range strings.Split(hack, "\n") {
+		line = strings.TrimSpace(line) // CRLFのCRを取り除く
+		if line == "" {
+			continue
+		}
+		program = append(program, line)
+	}
+	return cpu.Load(program)
+}
+
+func parseInstruction(binary string) (uint16, error) {
+	if len(binary) != 16 {
+		return 0, fmt.Errorf("%q should have 16 bits", binary)
+	}
+	instruction := uint16(0)
+	for _, bit := range binary {
+		switch bit {
+		case '0':
+			instruction <<= 1
+		case '1':
+			instruction = instruction<<1 | 1
+		default:
+			return 0, fmt.Errorf("%q should consist of 0 and 1", binary)
+		}
+	}
+	return instruction, nil
+}
+
+// Reset sets PC to 0 like reset input of CPU. registers and RAM are kept.
+func (cpu *CPU) Reset() {
+	cpu.PC = 0
+	cpu.halted = false
+}
+
+// Step executes an instruction at PC
+func (cpu *CPU) Step() {
+	if cpu.Keyboard != nil {
+		cpu.RAM[KBD] = cpu.Keyboard.Key(cpu.Cycles)
+	}
+	instruction := cpu.ROM[cpu.PC]
+	cpu.Cycles++
+	if instruction&0x8000 == 0 { // A命令
+		cpu.A = int16(instruction)
+		cpu.PC = nextPC(cpu.PC)
+		cpu.halted = false
+		return
+	}
+	// C命令 111a cccc ccdd djjj
+	address := uint16(cpu.A) & (RAM_SIZE - 1)
+	y := cpu.A
+	if instruction&0x1000 != 0 {
+		y = cpu.RAM[address]
+	}
+	out := alu(cpu.D, y, instruction>>6)
+	if instruction&0x0008 != 0 { // M
+		cpu.RAM[address] = out
+	}
+	pc := cpu.PC
+	jump := isJump(out, instruction)
+	if jump {
+		cpu.PC = uint16(cpu.A) & (ROM_SIZE - 1)
+	} else {
+		cpu.PC = nextPC(cpu.PC)
+	}
+	if instruction&0x0020 != 0 { // A
+		cpu.A = out
+	}
+	if instruction&0x0010 != 0 { // D
+		cpu.D = out
+	}
+	cpu.halted = jump && instruction&0x0038 == 0 && cpu.isLoop(cpu.PC, pc)
+}
+
+// isLoop returns whether jump from pc to target never exits. e.g. "(END) @END 0;JMP" which Hack programs end with.
+// jump instruction should not change any register or memory.
+func (cpu *CPU) isLoop(target uint16, pc uint16) bool {
+	if target == pc {
+		return true
+	}
+	// "@target"の次の命令からtargetにジャンプする場合、Aレジスタは変わらない
+	return nextPC(target) == pc && cpu.ROM[target] == target
+}
+
+// Run executes instructions until program halts (enters infinite loop) or maxCycles instructions are executed.
+// ErrCycleLimit is returned if program does not halt.
+func (cpu *CPU) Run(maxCycles uint64) error {
+	for i := uint64(0); i < maxCycles; i++ {
+		cpu.Step()
+		if cpu.halted {
+			return nil
+		}
+	}
+	return ErrCycleLimit
+}
+
+// Halted returns whether program is in infinite loop which Hack programs end with. e.g. "(END) @END 0;JMP"
+func (cpu *CPU) Halted() bool {
+	return cpu.halted
+}
+
+// SetHalted restores whether program is halted. (e.g. by debugger which undoes instructions)
+func (cpu *CPU) SetHalted(halted bool) {
+	cpu.halted = halted
+}
+
+// Screen returns screen memory map. each row of screen consists of 32 words and LSB of word is left pixel.
+func (cpu *CPU) Screen() []int16 {
+	return cpu.RAM[SCREEN : SCREEN+SCREEN_SIZE]
+}
+
+// SetKey writes key code to keyboard memory map. 0 means no key is pressed.
+func (cpu *CPU) SetKey(keyCode int16) {
+	cpu.RAM[KBD] = keyCode
+}
+
+func nextPC(pc uint16) uint16 {
+	return (pc + 1) & (ROM_SIZE - 1)
+}
+
+// alu computes output of ALU. control has zx,nx,zy,ny,f,no bits in its lower 6 bits.
+func alu(x int16, y int16, control uint16) int16 {
+	if control&0x20 != 0 { // zx
+		x = 0
+	}
+	if control&0x10 != 0 { // nx
+		x = ^x
+	}
+	if control&0x08 != 0 { // zy
+		y = 0
+	}
+	if control&0x04 != 0 { // ny
+		y = ^y
+	}
+	var out int16
+	if control&0x02 != 0 { // f
+		out = x + y
+	} else {
+		out = x & y
+	}
+	if control&0x01 != 0 { // no
+		out = ^out
+	}
+	return out
+}
+
+func isJump(out int16, instruction uint16) bool {
+	switch {
+	case out < 0:
+		return instruction&0x4 != 0
+	case out == 0:
+		return instruction&0x2 != 0
+	default:
+		return instruction&0x1 != 0
+	}
+}
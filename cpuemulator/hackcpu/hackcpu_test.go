@@ -0,0 +1,184 @@
+package hackcpu
+
+import (
+	"io/ioutil"
+	"testing"
+)
+
+func loadHackFile(t *testing.T, hackFilename string) *CPU {
+	hack, err := ioutil.ReadFile(hackFilename)
+	if err != nil {
+		t.Fatalf("failed to read %s: %s", hackFilename, err)
+	}
+	cpu := New()
+	if err := cpu.LoadHack(string(hack)); err != nil {
+		t.Fatalf("failed to load %s: %s", hackFilename, err)
+	}
+	return cpu
+}
+
+func TestMax(t *testing.T) {
+	testCases := []struct {
+		ram0           int16
+		ram1           int16
+		expectedRam2   int16
+		expectedCycles uint64
+	}{
+		{3, 5, 5, 14},
+		{23456, 12345, 23456, 12},
+		{-3, -5, -3, 12},
+	}
+	cpu := loadHackFile(t, "../../hardware/computer/Max.hack")
+	for _, tt := range testCases {
+		cpu.Reset()
+		cpu.Cycles = 0
+		cpu.RAM[0], cpu.RAM[1] = tt.ram0, tt.ram1
+		if err := cpu.Run(1000); err != nil {
+			t.Fatalf("Run() returned error: %s", err)
+		}
+		if cpu.RAM[2] != tt.expectedRam2 {
+			t.Errorf("max(%d,%d): RAM[2] should be %d. got %d", tt.ram0, tt.ram1, tt.expectedRam2, cpu.RAM[2])
+		}
+		if cpu.Cycles != tt.expectedCycles {
+			t.Errorf("max(%d,%d): Cycles should be %d. got %d", tt.ram0, tt.ram1, tt.expectedCycles, cpu.Cycles)
+		}
+	}
+}
+
+func TestAdd(t *testing.T) {
+	cpu := loadHackFile(t, "../../hardware/computer/Add.hack")
+	// Add.hack has no infinite loop at the end. ComputerAdd.tst runs it for 6 cycles.
+	for i := 0; i < 6; i++ {
+		cpu.Step()
+	}
+	if cpu.RAM[0] != 5 {
+		t.Errorf("RAM[0] should be 5. got %d", cpu.RAM[0])
+	}
+	if cpu.A != 0 || cpu.D != 5 || cpu.PC != 6 {
+		t.Errorf("A,D,PC should be 0,5,6. got %d,%d,%d", cpu.A, cpu.D, cpu.PC)
+	}
+}
+
+func TestRect(t *testing.T) {
+	cpu := loadHackFile(t, "../../hardware/computer/Rect.hack")
+	cpu.RAM[0] = 4
+	if err := cpu.Run(1000); err != nil {
+		t.Fatalf("Run() returned error: %s", err)
+	}
+	screen := cpu.Screen()
+	for i, word := range screen {
+		expected := int16(0)
+		if i%32 == 0 && i/32 < 4 { // 左上の幅16px,高さ4pxの長方形
+			expected = -1
+		}
+		if word != expected {
+			t.Fatalf("Screen()[%d] should be %d. got %d", i, expected, word)
+		}
+	}
+}
+
+func TestRunCycleLimit(t *testing.T) {
+	cpu := New()
+	// (LOOP) @0 M=M+1 @LOOP 0;JMP は RAM[0]を増やし続ける
+	cpu.Load([]string{"0000000000000000", "1111110111001000", "0000000000000000", "1110101010000111"})
+	if err := cpu.Run(100); err != ErrCycleLimit {
+		t.Fatalf("Run() should return ErrCycleLimit. got %v", err)
+	}
+	if cpu.Cycles != 100 {
+		t.Errorf("Cycles should be 100. got %d", cpu.Cycles)
+	}
+	if cpu.RAM[0] != 25 {
+		t.Errorf("RAM[0] should be 25. got %d", cpu.RAM[0])
+	}
+}
+
+func TestComp(t *testing.T) {
+	testCases := []struct {
+		comp     string // acccccc
+		expected int16
+	}{
+		{"0101010", 0},
+		{"0111111", 1},
+		{"0111010", -1},
+		{"0001100", 17},
+		{"0110000", 3},
+		{"0001101", ^17},
+		{"0110001", ^3},
+		{"0001111", -17},
+		{"0110011", -3},
+		{"0011111", 18},
+		{"0110111", 4},
+		{"0001110", 16},
+		{"0110010", 2},
+		{"0000010", 20},
+		{"0010011", 14},
+		{"0000111", -14},
+		{"0000000", 17 & 3},
+		{"0010101", 17 | 3},
+		{"1110000", 100},
+		{"1110001", ^100},
+		{"1110011", -100},
+		{"1110111", 101},
+		{"1110010", 99},
+		{"1000010", 117},
+		{"1010011", -83},
+		{"1000111", 83},
+		{"1000000", 17 & 100},
+		{"1010101", 17 | 100},
+	}
+	for _, tt := range testCases {
+		cpu := New()
+		// D=comp
+		if err := cpu.Load([]string{"111" + tt.comp + "010000"}); err != nil {
+			t.Fatalf("Load() returned error: %s", err)
+		}
+		cpu.A, cpu.D, cpu.RAM[3] = 3, 17, 100
+		cpu.Step()
+		if cpu.D != tt.expected {
+			t.Errorf("comp %s: D should be %d. got %d", tt.comp, tt.expected, cpu.D)
+		}
+	}
+}
+
+func TestJump(t *testing.T) {
+	testCases := []struct {
+		jump     string
+		d        int16
+		expected bool
+	}{
+		{"000", 1, false},
+		{"001", 1, true}, {"001", 0, false},
+		{"010", 0, true}, {"010", -1, false},
+		{"011", 0, true}, {"011", -1, false},
+		{"100", -1, true}, {"100", 0, false},
+		{"101", 1, true}, {"101", 0, false},
+		{"110", 0, true}, {"110", 1, false},
+		{"111", 1, true},
+	}
+	for _, tt := range testCases {
+		cpu := New()
+		// D;jump
+		cpu.Load([]string{"1110001100000" + tt.jump})
+		cpu.A, cpu.D = 10, tt.d
+		cpu.Step()
+		if jumped := cpu.PC == 10; jumped != tt.expected {
+			t.Errorf("D=%d;%s: jump should be %t. got PC=%d", tt.d, tt.jump, tt.expected, cpu.PC)
+		}
+	}
+}
+
+func TestLoadError(t *testing.T) {
+	testCases := []struct {
+		program       []string
+		expectedError string
+	}{
+		{[]string{"0000000000000000", "000"}, `instruction 1: "000" should have 16 bits`},
+		{[]string{"000000000000000a"}, `instruction 0: "000000000000000a" should consist of 0 and 1`},
+	}
+	for _, tt := range testCases {
+		err := New().Load(tt.program)
+		if err == nil || err.Error() != tt.expectedError {
+			t.Errorf("Load(%v) should return error %q. got %v", tt.program, tt.expectedError, err)
+		}
+	}
+}
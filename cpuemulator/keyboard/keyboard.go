@@ -0,0 +1,151 @@
+package keyboard
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// key codes of Hack keyboard. printable characters are ASCII codes.
+const (
+	NEWLINE   = 128
+	BACKSPACE = 129
+	LEFT      = 130
+	UP        = 131
+	RIGHT     = 132
+	DOWN      = 133
+	HOME      = 134
+	END       = 135
+	PAGE_UP   = 136
+	PAGE_DOWN = 137
+	INSERT    = 138
+	DELETE    = 139
+	ESC       = 140
+	F1        = 141 // F1..F12 are 141..152
+	F12       = 152
+)
+
+// keyNames maps name of key in script to its key code. F1..F12 are handled by ParseKey.
+var keyNames = map[string]int16{
+	"space":     ' ',
+	"newline":   NEWLINE,
+	"backspace": BACKSPACE,
+	"left":      LEFT,
+	"up":        UP,
+	"right":     RIGHT,
+	"down":      DOWN,
+	"home":      HOME,
+	"end":       END,
+	"pageup":    PAGE_UP,
+	"pagedown":  PAGE_DOWN,
+	"insert":    INSERT,
+	"delete":    DELETE,
+	"esc":       ESC,
+}
+
+// ParseKey returns key code of key written in script.
+// key is a printable character (A), quoted character ('#'), name of key (space, newline, left, f1) or key code (130).
+func ParseKey(key string) (int16, error) {
+	if len(key) == 3 && key[0] == '\'' && key[2] == '\'' {
+		key = key[1:2]
+	} else if code, ok := keyNames[strings.ToLower(key)]; ok {
+		return code, nil
+	}
+	if len(key) == 1 && key[0] >= ' ' && key[0] <= '~' {
+		return int16(key[0]), nil
+	}
+	lower := strings.ToLower(key)
+	if strings.HasPrefix(lower, "f") {
+		if n, err := strconv.Atoi(lower[1:]); err == nil && n >= 1 && n <= 12 {
+			return int16(F1 + n - 1), nil
+		}
+	}
+	if code, err := strconv.Atoi(key); err == nil && code > 0 && code <= F12 {
+		return int16(code), nil
+	}
+	return 0, fmt.Errorf("unknown key %q", key)
+}
+
+// Event changes KBD after Cycle instructions are executed. Key is 0 for release.
+type Event struct {
+	Cycle uint64
+	Key   int16
+}
+
+// Script replays keyboard events. it implements hackcpu.Keyboard, so set it to CPU.Keyboard.
+// KBD is decided only by number of executed instructions, so execution is deterministic and can be restarted.
+type Script struct {
+	Events []Event // sorted by Cycle
+}
+
+// Key returns key of last event whose Cycle is less than or equal to cycles
+func (s *Script) Key(cycles uint64) int16 {
+	i := sort.Search(len(s.Events), func(i int) bool { return s.Events[i].Cycle > cycles })
+	if i == 0 {
+		return 0
+	}
+	return s.Events[i-1].Key
+}
+
+// Parse parses keyboard script. each line is "CYCLE press KEY" or "CYCLE release". e.g. "10000 press A", "+2000 release"
+// "+N" is N cycles after previous event. events should be sorted by cycle. empty lines and comments starting with "#" are ignored.
+func Parse(script string, filename string) (*Script, error) {
+	events := []Event{}
+	previous := uint64(0)
+	for i, line := range strings.Split(script, "\n") {
+		fields := strings.Fields(line)
+		// "#" で始まるフィールド以降はコメント。キーの '#' は引用符で書く
+		for j, field := range fields {
+			if strings.HasPrefix(field, "#") {
+				fields = fields[:j]
+				break
+			}
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		event, err := parseEvent(fields, previous)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %s", filename, i+1, err)
+		}
+		events = append(events, event)
+		previous = event.Cycle
+	}
+	return &Script{Events: events}, nil
+}
+
+func parseEvent(fields []string, previous uint64) (Event, error) {
+	cycleStr := fields[0]
+	relative := strings.HasPrefix(cycleStr, "+")
+	cycle, err := strconv.ParseUint(strings.TrimPrefix(cycleStr, "+"), 10, 64)
+	if err != nil {
+		return Event{}, fmt.Errorf("%q is not cycle", cycleStr)
+	}
+	if relative {
+		cycle += previous
+	} else if cycle < previous {
+		return Event{}, fmt.Errorf("cycle %d is before previous event at %d", cycle, previous)
+	}
+	switch {
+	case len(fields) == 3 && fields[1] == "press":
+		key, err := ParseKey(fields[2])
+		if err != nil {
+			return Event{}, err
+		}
+		return Event{Cycle: cycle, Key: key}, nil
+	case len(fields) == 2 && fields[1] == "release":
+		return Event{Cycle: cycle}, nil
+	}
+	return Event{}, fmt.Errorf("event should be \"CYCLE press KEY\" or \"CYCLE release\"")
+}
+
+// ParseFile parses keyboard script file
+func ParseFile(filename string) (*Script, error) {
+	script, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(string(script), filename)
+}
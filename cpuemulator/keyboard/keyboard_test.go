@@ -0,0 +1,122 @@
+package keyboard
+
+import (
+	"assembler"
+	"cpuemulator/hackcpu"
+	"io/ioutil"
+	"reflect"
+	"testing"
+)
+
+func TestParseKey(t *testing.T) {
+	tests := map[string]int16{
+		"A": 65, "a": 97, "7": 55, "'#'": 35, "space": 32, "newline": 128, "Backspace": 129,
+		"left": 130, "up": 131, "right": 132, "down": 133, "home": 134, "end": 135, "pageup": 136, "pagedown": 137,
+		"insert": 138, "delete": 139, "esc": 140, "f1": 141, "F12": 152, "f": 102, "130": 130,
+	}
+	for key, expected := range tests {
+		code, err := ParseKey(key)
+		if err != nil || code != expected {
+			t.Errorf("ParseKey(%q) should be %d. got %d, %v", key, expected, code, err)
+		}
+	}
+	for _, key := range []string{"", "f13", "153", "ctrl", "'ab'"} {
+		if _, err := ParseKey(key); err == nil {
+			t.Errorf("ParseKey(%q) should return error", key)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	script := `# Pong: move bat to left
+10000 press left
++2000 release  # 12000
+
+12000 press '#'
+20000 press newline
+30000 release
+`
+	s, err := Parse(script, "pong.kbd")
+	if err != nil {
+		t.Fatalf("Parse() returned error: %s", err)
+	}
+	expected := []Event{{10000, LEFT}, {12000, 0}, {12000, '#'}, {20000, NEWLINE}, {30000, 0}}
+	if !reflect.DeepEqual(s.Events, expected) {
+		t.Fatalf("events should be %v. got %v", expected, s.Events)
+	}
+	keys := map[uint64]int16{0: 0, 9999: 0, 10000: LEFT, 11999: LEFT, 12000: '#', 19999: '#', 20000: NEWLINE, 30000: 0, 1 << 40: 0}
+	for cycles, key := range keys {
+		if s.Key(cycles) != key {
+			t.Errorf("Key(%d) should be %d. got %d", cycles, key, s.Key(cycles))
+		}
+	}
+}
+
+func TestParseError(t *testing.T) {
+	tests := map[string]string{
+		"10 press A\n5 release": "test.kbd:2: cycle 5 is before previous event at 10",
+		"10 press":              "test.kbd:1: event should be \"CYCLE press KEY\" or \"CYCLE release\"",
+		"10 release A":          "test.kbd:1: event should be \"CYCLE press KEY\" or \"CYCLE release\"",
+		"\nten press A":         "test.kbd:2: \"ten\" is not cycle",
+		"10 press ctrl":         "test.kbd:1: unknown key \"ctrl\"",
+	}
+	for script, expected := range tests {
+		if _, err := Parse(script, "test.kbd"); err == nil || err.Error() != expected {
+			t.Errorf("Parse(%q) should return error %q. got %v", script, expected, err)
+		}
+	}
+}
+
+func TestDecode(t *testing.T) {
+	keys, interrupted := Decode([]byte("a\x1b[D\x1b[A\r\x7f\x1bOP\x1b[24~\x1b[99~\x1b[3~\x1b"))
+	expected := []int16{'a', LEFT, UP, NEWLINE, BACKSPACE, F1, F12, DELETE, ESC}
+	if !reflect.DeepEqual(keys, expected) || interrupted {
+		t.Errorf("Decode() should return %v. got %v, %v", expected, keys, interrupted)
+	}
+	if _, interrupted := Decode([]byte{'q', 0x03}); !interrupted {
+		t.Errorf("Decode() should detect Ctrl-C")
+	}
+}
+
+// batPosition runs Pong with keyboard and returns x of left end of bat
+func batPosition(t *testing.T, keyboard hackcpu.Keyboard) int {
+	asm, err := ioutil.ReadFile("../../assembler/asm/pong/Pong.asm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	program, err := assembler.AssembleProgram(string(asm), "Pong.asm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cpu := hackcpu.New()
+	cpu.Load(program.Binary)
+	cpu.Keyboard = keyboard
+	if err := cpu.Run(10000000); err != hackcpu.ErrCycleLimit {
+		t.Fatalf("Pong should not halt. got %v", err)
+	}
+	// バットは画面下部の240行目付近に描かれる
+	row := cpu.Screen()[235*32 : 236*32]
+	for x := 0; x < 512; x++ {
+		if uint16(row[x/16])>>(x%16)&1 != 0 {
+			return x
+		}
+	}
+	t.Fatalf("bat is not drawn")
+	return 0
+}
+
+func TestPong(t *testing.T) {
+	script, err := Parse("5000000 press left\n8000000 release", "pong.kbd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if x := batPosition(t, script); x != 0 {
+		t.Errorf("bat should be moved to left end by left key. got x=%d", x)
+	}
+	if x := batPosition(t, &Script{}); x < 400 {
+		t.Errorf("bat should move to right without keys. got x=%d", x)
+	}
+	if x := batPosition(t, script); x != 0 {
+		t.Errorf("replay of script should be deterministic. got x=%d", x)
+	}
+}
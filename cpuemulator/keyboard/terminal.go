@@ -0,0 +1,161 @@
+package keyboard
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const (
+	// DEFAULT_HOLD is how long key is regarded as pressed after terminal sends it.
+	// terminals send only key presses and repeat them after about 500ms while key is held.
+	DEFAULT_HOLD = 600 * time.Millisecond
+	// CHECK_INTERVAL is number of instructions between checks of terminal input. time.Now is too slow to call every instruction.
+	CHECK_INTERVAL = 1000
+)
+
+// escapeSequences maps escape sequences sent by terminals (xterm, VT100) to key codes
+var escapeSequences = map[string]int16{
+	"\x1b[A": UP, "\x1b[B": DOWN, "\x1b[C": RIGHT, "\x1b[D": LEFT,
+	"\x1bOA": UP, "\x1bOB": DOWN, "\x1bOC": RIGHT, "\x1bOD": LEFT,
+	"\x1b[H": HOME, "\x1b[F": END, "\x1bOH": HOME, "\x1bOF": END, "\x1b[1~": HOME, "\x1b[4~": END,
+	"\x1b[2~": INSERT, "\x1b[3~": DELETE, "\x1b[5~": PAGE_UP, "\x1b[6~": PAGE_DOWN,
+	"\x1bOP": F1, "\x1bOQ": F1 + 1, "\x1bOR": F1 + 2, "\x1bOS": F1 + 3,
+	"\x1b[15~": F1 + 4, "\x1b[17~": F1 + 5, "\x1b[18~": F1 + 6, "\x1b[19~": F1 + 7,
+	"\x1b[20~": F1 + 8, "\x1b[21~": F1 + 9, "\x1b[23~": F1 + 10, "\x1b[24~": F12,
+}
+
+// Decode converts bytes read from terminal to key codes. interrupted is true if Ctrl-C or Ctrl-D is included.
+// unknown escape sequences and control characters are ignored.
+func Decode(input []byte) (keys []int16, interrupted bool) {
+	for i := 0; i < len(input); i++ {
+		b := input[i]
+		switch {
+		case b == 0x03 || b == 0x04:
+			interrupted = true
+		case b == '\r' || b == '\n':
+			keys = append(keys, NEWLINE)
+		case b == 0x7f || b == 0x08:
+			keys = append(keys, BACKSPACE)
+		case b >= ' ' && b <= '~':
+			keys = append(keys, int16(b))
+		case b == 0x1b:
+			rest := string(input[i:])
+			matched := false
+			for sequence, key := range escapeSequences {
+				if strings.HasPrefix(rest, sequence) {
+					keys = append(keys, key)
+					i += len(sequence) - 1
+					matched = true
+					break
+				}
+			}
+			// ESCキー単体か、知らないシーケンスの先頭
+			if !matched && (len(rest) == 1 || (rest[1] != '[' && rest[1] != 'O')) {
+				keys = append(keys, ESC)
+			} else if !matched {
+				i += skipSequence(rest) - 1
+			}
+		}
+	}
+	return keys, interrupted
+}
+
+// skipSequence returns length of unknown escape sequence "ESC [ params final" or "ESC O x"
+func skipSequence(sequence string) int {
+	for i := 2; i < len(sequence); i++ {
+		if sequence[i] >= 0x40 && sequence[i] <= 0x7e {
+			return i + 1
+		}
+	}
+	return len(sequence)
+}
+
+// Terminal maps keys pressed in terminal to KBD. it implements hackcpu.Keyboard.
+// key is kept pressed for Hold after it is sent, because terminals do not send release of keys.
+type Terminal struct {
+	Hold        time.Duration
+	input       chan []byte
+	key         int16
+	pressedAt   time.Time
+	calls       uint64
+	interrupted bool
+	sttyState   string
+}
+
+// OpenTerminal puts stdin terminal in non-canonical mode without echo by stty and starts reading keys.
+// Close should be called to restore terminal.
+func OpenTerminal() (*Terminal, error) {
+	state, err := stty("-g")
+	if err != nil {
+		return nil, err
+	}
+	// rawではなく-icanonにして、出力の改行はCRLFに変換させる
+	if _, err := stty("-icanon", "-echo", "-isig", "-ixon", "min", "1"); err != nil {
+		return nil, err
+	}
+	input := make(chan []byte, 64)
+	go func() {
+		for {
+			buf := make([]byte, 64)
+			n, err := os.Stdin.Read(buf)
+			if n > 0 {
+				input <- buf[:n]
+			}
+			if err != nil {
+				close(input)
+				return
+			}
+		}
+	}()
+	return &Terminal{Hold: DEFAULT_HOLD, input: input, sttyState: strings.TrimSpace(state)}, nil
+}
+
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	return string(out), err
+}
+
+// Key returns key pressed in terminal. input is checked once in CHECK_INTERVAL calls.
+func (t *Terminal) Key(cycles uint64) int16 {
+	t.calls++
+	if t.calls%CHECK_INTERVAL != 1 {
+		return t.key
+	}
+	now := time.Now()
+	for drained := false; !drained; {
+		select {
+		case input, ok := <-t.input:
+			if !ok {
+				// stdinが閉じられたら終了させる
+				t.input, t.interrupted = nil, true
+				continue
+			}
+			keys, interrupted := Decode(input)
+			t.interrupted = t.interrupted || interrupted
+			if len(keys) > 0 {
+				t.key, t.pressedAt = keys[len(keys)-1], now
+			}
+		default:
+			drained = true
+		}
+	}
+	if t.key != 0 && now.Sub(t.pressedAt) > t.Hold {
+		t.key = 0
+	}
+	return t.key
+}
+
+// Interrupted returns whether Ctrl-C or Ctrl-D is pressed or stdin is closed
+func (t *Terminal) Interrupted() bool {
+	return t.interrupted
+}
+
+// Close restores terminal mode
+func (t *Terminal) Close() error {
+	_, err := stty(t.sttyState)
+	return err
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"assembler"
+	"cpuemulator/tstscript"
+	"flag"
+	"fmt"
+	"os"
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s <file.tst>...\n", os.Args[0])
+		fmt.Fprintln(os.Stderr, "runs test scripts on Hack CPU emulator, writes .out files and compares them with .cmp files")
+		flag.PrintDefaults()
+	}
+	noOutput := flag.Bool("no-output", false, "do not write .out files")
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	failed := false
+	for _, tstFilename := range flag.Args() {
+		if _, err := tstscript.RunFile(tstFilename, !*noOutput, assembler.AssembleProgram); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", tstFilename, err)
+			failed = true
+			continue
+		}
+		fmt.Printf("%s: End of script - Comparison ended successfully\n", tstFilename)
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,154 @@
+package screen
+
+import (
+	"cpuemulator/hackcpu"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"os"
+	"strings"
+)
+
+const (
+	WIDTH         = 512
+	HEIGHT        = 256
+	WORDS_PER_ROW = WIDTH / 16
+)
+
+// Palette is palette of decoded image. index 0 is white (bit 0) and index 1 is black (bit 1) like screen of CPUEmulator.
+var Palette = color.Palette{color.White, color.Black}
+
+type Mode string
+
+const (
+	HALF_BLOCK Mode = "half"    // a character shows 1x2 pixels by "▀", "▄" and "█". 512x128 characters
+	BRAILLE    Mode = "braille" // a character shows 2x4 pixels by braille pattern. 256x64 characters
+)
+
+// Modes is list of supported modes of terminal rendering
+var Modes = []Mode{HALF_BLOCK, BRAILLE}
+
+// ParseMode returns mode of name. e.g. "braille"
+func ParseMode(name string) (Mode, error) {
+	for _, mode := range Modes {
+		if string(mode) == name {
+			return mode, nil
+		}
+	}
+	names := []string{}
+	for _, mode := range Modes {
+		names = append(names, string(mode))
+	}
+	return "", fmt.Errorf("unknown mode %q. mode should be one of %s", name, strings.Join(names, ", "))
+}
+
+// Decode converts screen memory map (e.g. hackcpu.CPU.Screen()) to image. each row consists of 32 words and bit 0 of word is left pixel.
+// words shorter than hackcpu.SCREEN_SIZE are treated as white.
+func Decode(words []int16) *image.Paletted {
+	img := image.NewPaletted(image.Rect(0, 0, WIDTH, HEIGHT), Palette)
+	for i, word := range words {
+		if i >= hackcpu.SCREEN_SIZE {
+			break
+		}
+		offset := i/WORDS_PER_ROW*img.Stride + i%WORDS_PER_ROW*16
+		for bit := 0; bit < 16; bit++ {
+			img.Pix[offset+bit] = uint8(uint16(word) >> bit & 1)
+		}
+	}
+	return img
+}
+
+// WritePNG writes screen memory map to w as PNG
+func WritePNG(w io.Writer, words []int16) error {
+	return png.Encode(w, Decode(words))
+}
+
+// SavePNG writes screen memory map to PNG file
+func SavePNG(filename string, words []int16) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if err := WritePNG(file, words); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// Render writes screen memory map to w as text for terminal. black pixels are drawn by characters.
+func Render(w io.Writer, words []int16, mode Mode) error {
+	return RenderImage(w, Decode(words), mode)
+}
+
+// RenderImage writes image returned by Decode (e.g. Frame.Image) to w as text for terminal
+func RenderImage(w io.Writer, img *image.Paletted, mode Mode) error {
+	var b strings.Builder
+	switch mode {
+	case HALF_BLOCK:
+		halfBlocks := []rune{' ', '▀', '▄', '█'}
+		for y := 0; y < HEIGHT; y += 2 {
+			for x := 0; x < WIDTH; x++ {
+				b.WriteRune(halfBlocks[pixel(img, x, y)|pixel(img, x, y+1)<<1])
+			}
+			b.WriteByte('\n')
+		}
+	case BRAILLE:
+		// 点の番号 1,2,3,7 が左列、4,5,6,8 が右列
+		dots := [4][2]rune{{0x01, 0x08}, {0x02, 0x10}, {0x04, 0x20}, {0x40, 0x80}}
+		for y := 0; y < HEIGHT; y += 4 {
+			for x := 0; x < WIDTH; x += 2 {
+				r := rune(0x2800)
+				for dy := 0; dy < 4; dy++ {
+					for dx := 0; dx < 2; dx++ {
+						if pixel(img, x+dx, y+dy) != 0 {
+							r |= dots[dy][dx]
+						}
+					}
+				}
+				b.WriteRune(r)
+			}
+			b.WriteByte('\n')
+		}
+	default:
+		return fmt.Errorf("unknown mode %q", mode)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func pixel(img *image.Paletted, x int, y int) int {
+	return int(img.Pix[y*img.Stride+x])
+}
+
+// Frame is snapshot of screen taken while running program
+type Frame struct {
+	Index  int    // 0 for first frame
+	Cycles uint64 // number of instructions executed before the frame
+	Image  *image.Paletted
+}
+
+// Run executes program of cpu like hackcpu.CPU.Run and calls f with a frame every N cycles and with last frame when program halts or maxCycles is reached.
+// every 0 means only last frame. error of f stops execution and is returned. hackcpu.ErrCycleLimit is not returned, because last frame is usually what is wanted.
+func Run(cpu *hackcpu.CPU, maxCycles uint64, every uint64, f func(Frame) error) error {
+	index := 0
+	emit := func() error {
+		frame := Frame{Index: index, Cycles: cpu.Cycles, Image: Decode(cpu.Screen())}
+		index++
+		return f(frame)
+	}
+	for i := uint64(1); i <= maxCycles; i++ {
+		cpu.Step()
+		if cpu.Halted() {
+			break
+		}
+		if every != 0 && i%every == 0 && i != maxCycles {
+			if err := emit(); err != nil {
+				return err
+			}
+		}
+	}
+	return emit()
+}
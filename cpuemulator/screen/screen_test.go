@@ -0,0 +1,169 @@
+package screen
+
+import (
+	"bytes"
+	"cpuemulator/hackcpu"
+	"flag"
+	"image"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update golden images in testdata/")
+
+func loadHackFile(t *testing.T, filename string) *hackcpu.CPU {
+	hack, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cpu := hackcpu.New()
+	if err := cpu.LoadHack(string(hack)); err != nil {
+		t.Fatalf("LoadHack() returned error: %s", err)
+	}
+	return cpu
+}
+
+// assertGolden compares img with PNG file in testdata/. it is rewritten with -update flag.
+func assertGolden(t *testing.T, filename string, img *image.Paletted) {
+	if *update {
+		var b bytes.Buffer
+		if err := png.Encode(&b, img); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filename, b.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	file, err := os.Open(filename)
+	if err != nil {
+		t.Fatalf("%s. run go test with -update to create golden image", err)
+	}
+	defer file.Close()
+	golden, err := png.Decode(file)
+	if err != nil {
+		t.Fatalf("%s: %s", filename, err)
+	}
+	if golden.Bounds() != img.Bounds() {
+		t.Fatalf("%s has size %v. got %v", filename, golden.Bounds(), img.Bounds())
+	}
+	for y := 0; y < HEIGHT; y++ {
+		for x := 0; x < WIDTH; x++ {
+			r, _, _, _ := golden.At(x, y).RGBA()
+			expected := uint8(0)
+			if r < 0x8000 {
+				expected = 1
+			}
+			if img.ColorIndexAt(x, y) != expected {
+				t.Fatalf("pixel (%d, %d) should be %d like %s. got %d", x, y, expected, filename, img.ColorIndexAt(x, y))
+			}
+		}
+	}
+}
+
+func TestDecode(t *testing.T) {
+	words := make([]int16, hackcpu.SCREEN_SIZE)
+	words[0] = 1                    // 左上の点
+	words[WORDS_PER_ROW+1] = -32768 // 2行目の32px目
+	words[hackcpu.SCREEN_SIZE-1] = 0x0006
+	img := Decode(words)
+	black := map[image.Point]bool{{0, 0}: true, {31, 1}: true, {WIDTH - 15, HEIGHT - 1}: true, {WIDTH - 14, HEIGHT - 1}: true}
+	for y := 0; y < HEIGHT; y++ {
+		for x := 0; x < WIDTH; x++ {
+			expected := uint8(0)
+			if black[image.Point{x, y}] {
+				expected = 1
+			}
+			if img.ColorIndexAt(x, y) != expected {
+				t.Fatalf("pixel (%d, %d) should be %d. got %d", x, y, expected, img.ColorIndexAt(x, y))
+			}
+		}
+	}
+	if Decode(nil).ColorIndexAt(0, 0) != 0 {
+		t.Errorf("Decode(nil) should return white image")
+	}
+}
+
+func TestRectGolden(t *testing.T) {
+	cpu := loadHackFile(t, "../../hardware/computer/Rect.hack")
+	cpu.RAM[0] = 4
+	var frame Frame
+	if err := Run(cpu, 1000, 0, func(f Frame) error { frame = f; return nil }); err != nil {
+		t.Fatalf("Run() returned error: %s", err)
+	}
+	assertGolden(t, "testdata/Rect.png", frame.Image)
+
+	var b bytes.Buffer
+	if err := WritePNG(&b, cpu.Screen()); err != nil {
+		t.Fatalf("WritePNG() returned error: %s", err)
+	}
+	img, err := png.Decode(&b)
+	if err != nil {
+		t.Fatalf("WritePNG() wrote invalid PNG: %s", err)
+	}
+	if r, _, _, _ := img.At(15, 3).RGBA(); r != 0 {
+		t.Errorf("pixel (15, 3) of PNG should be black")
+	}
+}
+
+func TestRender(t *testing.T) {
+	words := make([]int16, hackcpu.SCREEN_SIZE)
+	words[0] = 0x000F               // 1行目の左4px
+	words[WORDS_PER_ROW] = 0x0005   // 2行目の1,3px目
+	words[WORDS_PER_ROW*3] = 0x0002 // 4行目の2px目
+	tests := []struct {
+		mode     Mode
+		expected string
+		lines    int
+	}{
+		{HALF_BLOCK, "█▀█▀" + strings.Repeat(" ", WIDTH-4), HEIGHT / 2},
+		{BRAILLE, "⢋⠋" + strings.Repeat("⠀", WIDTH/2-2), HEIGHT / 4},
+	}
+	for _, tt := range tests {
+		var b bytes.Buffer
+		if err := Render(&b, words, tt.mode); err != nil {
+			t.Fatalf("Render(%s) returned error: %s", tt.mode, err)
+		}
+		lines := strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+		if len(lines) != tt.lines {
+			t.Fatalf("Render(%s) should write %d lines. got %d", tt.mode, tt.lines, len(lines))
+		}
+		if lines[0] != tt.expected {
+			t.Errorf("first line of Render(%s) should be %q. got %q", tt.mode, tt.expected, lines[0])
+		}
+	}
+	if err := Render(&bytes.Buffer{}, words, "ascii"); err == nil {
+		t.Errorf("Render() should return error for unknown mode")
+	}
+}
+
+func TestRunFrames(t *testing.T) {
+	cpu := loadHackFile(t, "../../hardware/computer/Rect.hack")
+	cpu.RAM[0] = 4
+	frames := []Frame{}
+	if err := Run(cpu, 1000, 10, func(f Frame) error { frames = append(frames, f); return nil }); err != nil {
+		t.Fatalf("Run() returned error: %s", err)
+	}
+	last := frames[len(frames)-1]
+	if !cpu.Halted() || last.Cycles != cpu.Cycles || len(frames) != int(cpu.Cycles-1)/10+1 {
+		t.Fatalf("Run() should take a frame every 10 cycles and last frame at halt. got %d frames, last at %d cycles", len(frames), last.Cycles)
+	}
+	for i, frame := range frames[:len(frames)-1] {
+		if frame.Index != i || frame.Cycles != uint64(i+1)*10 {
+			t.Fatalf("frame %d should be taken at %d cycles. got index %d at %d cycles", i, (i+1)*10, frame.Index, frame.Cycles)
+		}
+	}
+	if frames[0].Image.ColorIndexAt(0, 3) != 0 || last.Image.ColorIndexAt(0, 3) != 1 {
+		t.Errorf("rectangle should be drawn progressively")
+	}
+
+	cpu = loadHackFile(t, "../../hardware/computer/Rect.hack")
+	cpu.RAM[0] = 4
+	count := 0
+	stop := os.ErrClosed
+	if err := Run(cpu, 1000, 1, func(f Frame) error { count++; return stop }); err != stop || count != 1 {
+		t.Errorf("Run() should stop by error of f. got %v after %d frames", err, count)
+	}
+}
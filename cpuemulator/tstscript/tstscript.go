@@ -0,0 +1,646 @@
+package tstscript
+
+import (
+	"assembler"
+	"bytes"
+	"cpuemulator/hackcpu"
+	"cpuemulator/value"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// DEFAULT_MAX_REPEAT is default limit of iterations of "repeat" without count
+const DEFAULT_MAX_REPEAT = 10000000
+
+// Statement is command of test script. e.g. "set RAM[0] 3," or "repeat 14 { ... }"
+type Statement struct {
+	Line  int // line number in script
+	Words []string
+	Body  []Statement // statements in block of "repeat" and "while"
+}
+
+// ComparisonError is returned when output line differs from compare-to file
+type ComparisonError struct {
+	Line     int // line number in .out and .cmp
+	Expected string
+	Actual   string
+}
+
+func (e *ComparisonError) Error() string {
+	return fmt.Sprintf("comparison failure at line %d. expected %q, got %q", e.Line, e.Expected, e.Actual)
+}
+
+// Runner executes test script of nand2tetris (.tst) on Hack CPU emulator.
+// It supports scripts of CPUEmulator (RAM[0], A, D, PC, ticktock) and scripts which load Computer.hdl (RAM16K[0], ARegister[], DRegister[], PC[], reset, tick, tock).
+type Runner struct {
+	CPU *hackcpu.CPU
+	// Dir is dir which file names in script are relative to
+	Dir string
+	// WriteOutput is whether output is written to the file given by "output-file"
+	WriteOutput bool
+	// Assemble is used to load .asm file. (e.g. assembler.AssembleProgram) .asm can not be loaded if it is nil.
+	Assemble func(asm string, filename string) (*assembler.Program, error)
+	// MaxRepeat is max number of iterations of "repeat" without count, which repeats until program halts
+	MaxRepeat int
+
+	outputList      []outputColumn
+	output          bytes.Buffer
+	outputFilename  string
+	compareLines    []string
+	outputLineCount int
+	time            int
+	tickPhase       bool // "tick" is executed but "tock" is not executed yet
+	reset           bool
+}
+
+func NewRunner(dir string) *Runner {
+	return &Runner{CPU: hackcpu.New(), Dir: dir, MaxRepeat: DEFAULT_MAX_REPEAT}
+}
+
+// RunFile runs test script file. files in script are relative to dir of the script.
+func RunFile(tstFilename string, writeOutput bool, assemble func(asm string, filename string) (*assembler.Program, error)) (*Runner, error) {
+	tst, err := ioutil.ReadFile(tstFilename)
+	if err != nil {
+		return nil, err
+	}
+	r := NewRunner(filepath.Dir(tstFilename))
+	r.WriteOutput = writeOutput
+	r.Assemble = assemble
+	return r, r.Run(string(tst))
+}
+
+// Run parses and executes script. ComparisonError is returned at first line which differs from compare-to file.
+func (r *Runner) Run(script string) error {
+	statements, err := Parse(script)
+	if err != nil {
+		return err
+	}
+	err = r.execStatements(statements)
+	if writeErr := r.writeOutput(); err == nil {
+		err = writeErr
+	}
+	return err
+}
+
+// Output returns lines written by "output-list" and "output"
+func (r *Runner) Output() string {
+	return r.output.String()
+}
+
+func (r *Runner) writeOutput() error {
+	if !r.WriteOutput || r.outputFilename == "" {
+		return nil
+	}
+	return ioutil.WriteFile(r.outputFilename, r.output.Bytes(), 0644)
+}
+
+func (r *Runner) execStatements(statements []Statement) error {
+	for _, stmt := range statements {
+		if err := r.execStatement(stmt); err != nil {
+			if _, ok := err.(*ComparisonError); ok {
+				return err
+			}
+			if !strings.HasPrefix(err.Error(), "line ") {
+				err = fmt.Errorf("line %d: %s", stmt.Line, err)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Runner) execStatement(stmt Statement) error {
+	words := stmt.Words
+	switch words[0] {
+	case "repeat":
+		if len(words) == 1 {
+			return r.repeatUntilHalt(stmt.Body)
+		}
+		if len(words) != 2 {
+			return fmt.Errorf("repeat should be \"repeat {count} {\" or \"repeat {\"")
+		}
+		count, err := strconv.Atoi(words[1])
+		if err != nil {
+			return fmt.Errorf("invalid repeat count %q", words[1])
+		}
+		for i := 0; i < count; i++ {
+			if err := r.execStatements(stmt.Body); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "while":
+		if len(words) != 4 {
+			return fmt.Errorf("while should be \"while {variable} {operator} {value} {\"")
+		}
+		for {
+			ok, err := r.condition(words[1], words[2], words[3])
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return nil
+			}
+			if err := r.execStatements(stmt.Body); err != nil {
+				return err
+			}
+		}
+	case "load":
+		if len(words) != 2 {
+			return fmt.Errorf("load should have a file name")
+		}
+		return r.load(words[1])
+	case "ROM32K":
+		if len(words) != 3 || words[1] != "load" {
+			return fmt.Errorf("ROM32K should be \"ROM32K load {file name}\"")
+		}
+		return r.load(words[2])
+	case "output-file":
+		if len(words) != 2 {
+			return fmt.Errorf("output-file should have a file name")
+		}
+		r.outputFilename = filepath.Join(r.Dir, words[1])
+		return nil
+	case "compare-to":
+		if len(words) != 2 {
+			return fmt.Errorf("compare-to should have a file name")
+		}
+		cmp, err := ioutil.ReadFile(filepath.Join(r.Dir, words[1]))
+		if err != nil {
+			return err
+		}
+		r.compareLines = strings.Split(strings.ReplaceAll(string(cmp), value.CR, ""), value.LF)
+		return nil
+	case "output-list":
+		r.outputList = []outputColumn{}
+		for _, word := range words[1:] {
+			column, err := parseOutputColumn(word)
+			if err != nil {
+				return err
+			}
+			r.outputList = append(r.outputList, column)
+		}
+		return r.writeLine(r.headerLine())
+	case "output":
+		line, err := r.outputLine()
+		if err != nil {
+			return err
+		}
+		return r.writeLine(line)
+	case "set":
+		if len(words) != 3 {
+			return fmt.Errorf("set should be \"set {variable} {value}\"")
+		}
+		v, err := parseValue(words[2])
+		if err != nil {
+			return err
+		}
+		return r.set(words[1], v)
+	case "tick":
+		r.tick()
+		return nil
+	case "tock":
+		r.tock()
+		return nil
+	case "ticktock":
+		r.tick()
+		r.tock()
+		return nil
+	case "echo", "clear-echo", "breakpoint", "clear-breakpoints":
+		return nil // 画面表示のためのコマンドなので何もしない
+	}
+	return fmt.Errorf("unknown command %q", words[0])
+}
+
+// repeatUntilHalt executes "repeat {" without count. CPU emulator repeats it forever, so it stops when program halts.
+func (r *Runner) repeatUntilHalt(body []Statement) error {
+	for i := 0; i < r.MaxRepeat; i++ {
+		if r.CPU.Halted() {
+			return nil
+		}
+		if err := r.execStatements(body); err != nil {
+			return err
+		}
+	}
+	return fmt.Errorf("program does not halt in %d iterations of repeat", r.MaxRepeat)
+}
+
+func (r *Runner) load(filename string) error {
+	switch filepath.Ext(filename) {
+	case ".hdl":
+		if filepath.Base(filename) != "Computer.hdl" {
+			return fmt.Errorf("%s can not be emulated. only Computer.hdl is supported", filename)
+		}
+		r.CPU = hackcpu.New()
+		return nil
+	case ".hack":
+		hack, err := ioutil.ReadFile(filepath.Join(r.Dir, filename))
+		if err != nil {
+			return err
+		}
+		return r.CPU.LoadHack(string(hack))
+	case ".asm":
+		if r.Assemble == nil {
+			return fmt.Errorf("%s can not be loaded without assembler", filename)
+		}
+		asm, err := ioutil.ReadFile(filepath.Join(r.Dir, filename))
+		if err != nil {
+			return err
+		}
+		program, err := r.Assemble(string(asm), filename)
+		if err != nil {
+			return err
+		}
+		return r.CPU.Load(program.Binary)
+	}
+	return fmt.Errorf("%s can not be loaded", filename)
+}
+
+// tick is first half of clock cycle. registers are updated at tock.
+func (r *Runner) tick() {
+	r.tickPhase = true
+}
+
+func (r *Runner) tock() {
+	r.CPU.Step()
+	if r.reset {
+		r.CPU.Reset()
+	}
+	r.tickPhase = false
+	r.time++
+}
+
+func (r *Runner) timeString() string {
+	if r.tickPhase {
+		return fmt.Sprintf("%d+", r.time)
+	}
+	return strconv.Itoa(r.time)
+}
+
+func (r *Runner) condition(name string, operator string, valueString string) (bool, error) {
+	left, err := r.get(name)
+	if err != nil {
+		return false, err
+	}
+	right, err := parseValue(valueString)
+	if err != nil {
+		return false, err
+	}
+	switch operator {
+	case "=":
+		return left == right, nil
+	case "<>":
+		return left != right, nil
+	case "<":
+		return left < right, nil
+	case ">":
+		return left > right, nil
+	case "<=":
+		return left <= right, nil
+	case ">=":
+		return left >= right, nil
+	}
+	return false, fmt.Errorf("unknown operator %q", operator)
+}
+
+// get returns value of variable. e.g. "RAM[0]", "A", "ARegister[]", "PC[]", "reset"
+func (r *Runner) get(name string) (int16, error) {
+	address, memory, err := r.resolve(name)
+	if err != nil {
+		return 0, err
+	}
+	switch memory {
+	case "A":
+		return r.CPU.A, nil
+	case "D":
+		return r.CPU.D, nil
+	case "PC":
+		return int16(r.CPU.PC), nil
+	case "reset":
+		if r.reset {
+			return 1, nil
+		}
+		return 0, nil
+	case "ROM":
+		return int16(r.CPU.ROM[address]), nil
+	}
+	return r.CPU.RAM[address], nil
+}
+
+func (r *Runner) set(name string, v int16) error {
+	address, memory, err := r.resolve(name)
+	if err != nil {
+		return err
+	}
+	switch memory {
+	case "A":
+		r.CPU.A = v
+	case "D":
+		r.CPU.D = v
+	case "PC":
+		r.CPU.PC = uint16(v) & (hackcpu.ROM_SIZE - 1)
+	case "reset":
+		r.reset = v != 0
+	case "ROM":
+		r.CPU.ROM[address] = uint16(v)
+	default:
+		r.CPU.RAM[address] = v
+	}
+	return nil
+}
+
+// resolve returns kind of memory ("A","D","PC","reset","ROM","RAM") and address of variable
+func (r *Runner) resolve(name string) (int, string, error) {
+	base, index, hasIndex := name, "", false
+	if i := strings.Index(name, "["); i != -1 && strings.HasSuffix(name, "]") {
+		base, index, hasIndex = name[:i], name[i+1:len(name)-1], true
+	}
+	switch base {
+	case "A", "ARegister":
+		return 0, "A", nil
+	case "D", "DRegister":
+		return 0, "D", nil
+	case "PC":
+		return 0, "PC", nil
+	case "reset":
+		return 0, "reset", nil
+	case "Keyboard":
+		return hackcpu.KBD, "RAM", nil
+	}
+	memories := map[string]struct {
+		memory string
+		offset int
+		size   int
+	}{
+		"RAM":    {"RAM", 0, hackcpu.RAM_SIZE},
+		"RAM16K": {"RAM", 0, 16384},
+		"Screen": {"RAM", hackcpu.SCREEN, hackcpu.SCREEN_SIZE},
+		"ROM":    {"ROM", 0, hackcpu.ROM_SIZE},
+		"ROM32K": {"ROM", 0, hackcpu.ROM_SIZE},
+	}
+	m, ok := memories[base]
+	if !ok || !hasIndex {
+		return 0, "", fmt.Errorf("unknown variable %q", name)
+	}
+	address, err := strconv.Atoi(index)
+	if err != nil || address < 0 || address >= m.size {
+		return 0, "", fmt.Errorf("invalid address of %q", name)
+	}
+	return m.offset + address, m.memory, nil
+}
+
+// parseValue parses value of script. e.g. "-1", "%B0011000000111001", "%X7FFF", "%D123"
+func parseValue(s string) (int16, error) {
+	base, digits := 10, s
+	if len(s) > 2 && s[0] == '%' {
+		switch s[1] {
+		case 'B':
+			base = 2
+		case 'X':
+			base = 16
+		case 'D':
+			base = 10
+		default:
+			return 0, fmt.Errorf("invalid value %q", s)
+		}
+		digits = s[2:]
+	}
+	v, err := strconv.ParseInt(digits, base, 32)
+	if err != nil || v < -32768 || v > 65535 {
+		return 0, fmt.Errorf("invalid value %q", s)
+	}
+	return int16(v), nil
+}
+
+// outputColumn is element of output-list. e.g. "RAM[0]%D1.6.1"
+type outputColumn struct {
+	name   string
+	format byte // 'D','B','X','S'
+	left   int  // number of spaces at left
+	width  int
+	right  int // number of spaces at right
+}
+
+func parseOutputColumn(s string) (outputColumn, error) {
+	i := strings.LastIndex(s, "%")
+	if i == -1 {
+		return outputColumn{name: s, format: 'B', left: 1, width: 16, right: 1}, nil
+	}
+	column := outputColumn{name: s[:i]}
+	spec := s[i+1:]
+	if len(spec) < 2 {
+		return column, fmt.Errorf("invalid output format %q", s)
+	}
+	column.format = spec[0]
+	if strings.IndexByte("DBXS", column.format) == -1 {
+		return column, fmt.Errorf("invalid output format %q", s)
+	}
+	padding := strings.Split(spec[1:], ".")
+	if len(padding) != 3 {
+		return column, fmt.Errorf("invalid output format %q", s)
+	}
+	numbers := []*int{&column.left, &column.width, &column.right}
+	for j, p := range padding {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return column, fmt.Errorf("invalid output format %q", s)
+		}
+		*numbers[j] = n
+	}
+	return column, nil
+}
+
+func (r *Runner) headerLine() string {
+	var out strings.Builder
+	out.WriteString("|")
+	for _, column := range r.outputList {
+		total := column.left + column.width + column.right
+		name := column.name
+		if len(name) > total {
+			name = name[:total]
+		}
+		left := (total - len(name)) / 2
+		out.WriteString(strings.Repeat(" ", left) + name + strings.Repeat(" ", total-len(name)-left) + "|")
+	}
+	return out.String()
+}
+
+func (r *Runner) outputLine() (string, error) {
+	var out strings.Builder
+	out.WriteString("|")
+	for _, column := range r.outputList {
+		s, err := r.formatColumn(column)
+		if err != nil {
+			return "", err
+		}
+		out.WriteString(strings.Repeat(" ", column.left) + s + strings.Repeat(" ", column.right) + "|")
+	}
+	return out.String(), nil
+}
+
+func (r *Runner) formatColumn(column outputColumn) (string, error) {
+	if column.name == "time" {
+		return fmt.Sprintf("%-*s", column.width, r.timeString()), nil
+	}
+	v, err := r.get(column.name)
+	if err != nil {
+		return "", err
+	}
+	switch column.format {
+	case 'B':
+		return lowDigits(strconv.FormatUint(uint64(uint16(v)), 2), column.width, "0"), nil
+	case 'X':
+		return lowDigits(strings.ToUpper(strconv.FormatUint(uint64(uint16(v)), 16)), column.width, "0"), nil
+	case 'S':
+		return fmt.Sprintf("%-*d", column.width, v), nil
+	}
+	return fmt.Sprintf("%*d", column.width, v), nil
+}
+
+// lowDigits returns last width digits of s. s is padded with pad if it is shorter than width.
+func lowDigits(s string, width int, pad string) string {
+	if len(s) >= width {
+		return s[len(s)-width:]
+	}
+	return strings.Repeat(pad, width-len(s)) + s
+}
+
+func (r *Runner) writeLine(line string) error {
+	r.output.WriteString(line + value.NEW_LINE)
+	r.outputLineCount++
+	if r.compareLines == nil {
+		return nil
+	}
+	if r.outputLineCount > len(r.compareLines) {
+		return &ComparisonError{Line: r.outputLineCount, Expected: "", Actual: line}
+	}
+	expected := r.compareLines[r.outputLineCount-1]
+	if !matchLine(line, expected) {
+		return &ComparisonError{Line: r.outputLineCount, Expected: expected, Actual: line}
+	}
+	return nil
+}
+
+// matchLine compares line with expected line. "*" in expected line matches any character.
+func matchLine(line string, expected string) bool {
+	if len(line) != len(expected) {
+		return false
+	}
+	for i := range line {
+		if expected[i] != '*' && expected[i] != line[i] {
+			return false
+		}
+	}
+	return true
+}
+
+type scriptToken struct {
+	text string
+	line int
+}
+
+// Parse parses test script into statements. commands are terminated by ",", ";" or "!".
+func Parse(script string) ([]Statement, error) {
+	tokens, err := tokenize(script)
+	if err != nil {
+		return nil, err
+	}
+	statements, rest, err := parseStatements(tokens)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("line %d: unexpected %q", rest[0].line, rest[0].text)
+	}
+	return statements, nil
+}
+
+// parseStatements parses statements until "}" or end of tokens and returns rest of tokens
+func parseStatements(tokens []scriptToken) ([]Statement, []scriptToken, error) {
+	statements := []Statement{}
+	for len(tokens) > 0 && tokens[0].text != "}" {
+		stmt := Statement{Line: tokens[0].line, Words: []string{}}
+		for len(tokens) > 0 && !isTerminator(tokens[0].text) && tokens[0].text != "{" && tokens[0].text != "}" {
+			stmt.Words = append(stmt.Words, tokens[0].text)
+			tokens = tokens[1:]
+		}
+		if len(tokens) == 0 {
+			return nil, nil, fmt.Errorf("line %d: command should be terminated by \",\", \";\" or \"!\"", stmt.Line)
+		}
+		switch {
+		case tokens[0].text == "{":
+			if len(stmt.Words) == 0 || (stmt.Words[0] != "repeat" && stmt.Words[0] != "while") {
+				return nil, nil, fmt.Errorf("line %d: unexpected \"{\"", tokens[0].line)
+			}
+			body, rest, err := parseStatements(tokens[1:])
+			if err != nil {
+				return nil, nil, err
+			}
+			if len(rest) == 0 {
+				return nil, nil, fmt.Errorf("line %d: block is not closed by \"}\"", stmt.Line)
+			}
+			stmt.Body = body
+			tokens = rest[1:]
+		case tokens[0].text == "}":
+			return nil, nil, fmt.Errorf("line %d: command should be terminated by \",\", \";\" or \"!\"", stmt.Line)
+		default:
+			tokens = tokens[1:]
+			if len(stmt.Words) == 0 {
+				continue // 空のコマンド
+			}
+		}
+		statements = append(statements, stmt)
+	}
+	return statements, tokens, nil
+}
+
+func isTerminator(s string) bool {
+	return s == "," || s == ";" || s == "!"
+}
+
+// tokenize splits script into words and symbols. comments are removed and quoted string is a token.
+func tokenize(script string) ([]scriptToken, error) {
+	tokens := []scriptToken{}
+	line := 1
+	for i := 0; i < len(script); {
+		c := script[i]
+		switch {
+		case c == '\n':
+			line++
+			i++
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+		case strings.HasPrefix(script[i:], "//"):
+			for i < len(script) && script[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(script[i:], "/*"):
+			end := strings.Index(script[i+2:], "*/")
+			if end == -1 {
+				return nil, fmt.Errorf("line %d: comment is not closed", line)
+			}
+			line += strings.Count(script[i:i+2+end], "\n")
+			i += end + 4
+		case c == '"':
+			end := strings.IndexByte(script[i+1:], '"')
+			if end == -1 {
+				return nil, fmt.Errorf("line %d: string is not closed", line)
+			}
+			tokens = append(tokens, scriptToken{script[i : i+end+2], line})
+			i += end + 2
+		case strings.IndexByte(",;!{}", c) != -1:
+			tokens = append(tokens, scriptToken{string(c), line})
+			i++
+		default:
+			start := i
+			for i < len(script) && strings.IndexByte(" \t\r\n,;!{}\"", script[i]) == -1 && !strings.HasPrefix(script[i:], "//") && !strings.HasPrefix(script[i:], "/*") {
+				i++
+			}
+			tokens = append(tokens, scriptToken{script[start:i], line})
+		}
+	}
+	return tokens, nil
+}
@@ -0,0 +1,162 @@
+package tstscript
+
+import (
+	"assembler"
+	"strings"
+	"testing"
+)
+
+func TestRunComputerScripts(t *testing.T) {
+	scripts := []string{
+		"ComputerAdd.tst",
+		"ComputerAdd-external.tst",
+		"ComputerMax.tst",
+		"ComputerMax-external.tst",
+		"ComputerRect.tst",
+		"ComputerRect-external.tst",
+	}
+	for _, script := range scripts {
+		if _, err := RunFile("../../hardware/computer/"+script, false, nil); err != nil {
+			t.Errorf("%s: %s", script, err)
+		}
+	}
+}
+
+func TestRunCPUEmulatorScript(t *testing.T) {
+	// Max.hack を CPUEmulator の変数名で実行する
+	script := `
+load Max.hack,
+output-list RAM[0]%D2.6.2 RAM[1]%D2.6.2 RAM[2]%D2.6.2 A%X1.4.1 D%B1.4.1 PC%S1.3.1;
+set RAM[0] %X0003, set RAM[1] %B101,
+/* 結果が書き込まれるまで実行する */
+while RAM[2] = 0 {
+    ticktock;
+}
+output;
+set PC 0, set RAM[0] -3, set RAM[1] -5, set RAM[2] 0,
+repeat 12 {
+    ticktock;
+}
+output;
+`
+	expected := strings.Join([]string{
+		"|  RAM[0]  |  RAM[1]  |  RAM[2]  |  A   |  D   | PC  |",
+		"|       3  |       5  |       5  | 0002 | 0101 | 14  |",
+		"|      -3  |      -5  |      -3  | 000E | 1101 | 14  |",
+	}, "\r\n") + "\r\n"
+	r := NewRunner("../../hardware/computer")
+	if err := r.Run(script); err != nil {
+		t.Fatalf("Run() returned error: %s", err)
+	}
+	if r.Output() != expected {
+		t.Fatalf("Output() should be %q. got %q", expected, r.Output())
+	}
+}
+
+func TestRunAsm(t *testing.T) {
+	// Max.asm を assembler で機械語にして、終了まで実行する
+	script := `
+load Max.asm,
+output-list RAM[0]%D2.6.2 RAM[1]%D2.6.2 RAM[2]%D2.6.2;
+set RAM[0] 3, set RAM[1] 5,
+repeat {
+    ticktock;
+}
+output;
+`
+	expected := strings.Join([]string{
+		"|  RAM[0]  |  RAM[1]  |  RAM[2]  |",
+		"|       3  |       5  |       5  |",
+	}, "\r\n") + "\r\n"
+	r := NewRunner("../../assembler/asm/max")
+	r.Assemble = assembler.AssembleProgram
+	if err := r.Run(script); err != nil {
+		t.Fatalf("Run() returned error: %s", err)
+	}
+	if r.Output() != expected {
+		t.Fatalf("Output() should be %q. got %q", expected, r.Output())
+	}
+}
+
+func TestRepeatUntilHalt(t *testing.T) {
+	// Max.hack を終了するまで実行する
+	script := `
+load Max.hack,
+output-list RAM[2]%D2.6.2;
+set RAM[0] 3, set RAM[1] 5,
+repeat {
+    ticktock;
+}
+output;
+`
+	expected := "|  RAM[2]  |\r\n|       5  |\r\n"
+	r := NewRunner("../../hardware/computer")
+	if err := r.Run(script); err != nil {
+		t.Fatalf("Run() returned error: %s", err)
+	}
+	if r.Output() != expected {
+		t.Fatalf("Output() should be %q. got %q", expected, r.Output())
+	}
+}
+
+func TestRepeatWithoutCountLimit(t *testing.T) {
+	r := NewRunner(".")
+	r.MaxRepeat = 10
+	// "D=D+1" "@0" "0;JMP" は終了しない
+	if err := r.CPU.Load([]string{"1110011111010000", "0000000000000000", "1110101010000111"}); err != nil {
+		t.Fatal(err)
+	}
+	script := "repeat {\n ticktock;\n}"
+	if err := r.Run(script); err == nil || err.Error() != "line 1: program does not halt in 10 iterations of repeat" {
+		t.Fatalf("Run() should stop repeat without count at MaxRepeat. got %v", err)
+	}
+}
+
+func TestComparisonError(t *testing.T) {
+	r := NewRunner("../../hardware/computer")
+	// ComputerAdd.cmp の2行目は time 0 の出力
+	script := "load Computer.hdl, ROM32K load Add.hack, compare-to ComputerAdd.cmp," +
+		"output-list time%S1.4.1 reset%B2.1.2 ARegister[0]%D1.7.1 DRegister[0]%D1.7.1 PC[]%D0.4.0 RAM16K[0]%D1.7.1 RAM16K[1]%D1.7.1 RAM16K[2]%D1.7.1;" +
+		"set ARegister[0] 1, output;"
+	err := r.Run(script)
+	cmpErr, ok := err.(*ComparisonError)
+	if !ok {
+		t.Fatalf("Run() should return ComparisonError. got %v", err)
+	}
+	if cmpErr.Line != 2 || cmpErr.Actual != "| 0    |  0  |       1 |       0 |   0|       0 |       0 |       0 |" {
+		t.Errorf("unexpected ComparisonError: %s", cmpErr)
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	testCases := []struct {
+		script        string
+		expectedError string
+	}{
+		{"set RAM[0] 1", `line 1: command should be terminated by ",", ";" or "!"`},
+		{"repeat 2 {\n ticktock;", `line 1: block is not closed by "}"`},
+		{"\nset X 1;", `line 2: unknown variable "X"`},
+		{"set RAM[32768] 1;", `line 1: invalid address of "RAM[32768]"`},
+		{"set RAM[0] %Q1;", `line 1: invalid value "%Q1"`},
+		{"load CPU.hdl;", `line 1: CPU.hdl can not be emulated. only Computer.hdl is supported`},
+		{"load Max.asm;", `line 1: Max.asm can not be loaded without assembler`},
+		{"output-list RAM[0]%D1.6;", `line 1: invalid output format "RAM[0]%D1.6"`},
+		{"vmstep;", `line 1: unknown command "vmstep"`},
+		{"repeat 2 3 {\n ticktock;\n}", `line 1: repeat should be "repeat {count} {" or "repeat {"`},
+	}
+	for _, tt := range testCases {
+		err := NewRunner(".").Run(tt.script)
+		if err == nil || err.Error() != tt.expectedError {
+			t.Errorf("Run(%q) should return error %q. got %v", tt.script, tt.expectedError, err)
+		}
+	}
+}
+
+func TestMatchLine(t *testing.T) {
+	if !matchLine("|  12 |  3 |", "|  12 |  * |") {
+		t.Errorf("\"*\" should match any character")
+	}
+	if matchLine("|  12 |  3 |", "|  12 |  4 |") {
+		t.Errorf("different lines should not match")
+	}
+}
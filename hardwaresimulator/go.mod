@@ -0,0 +1,3 @@
+module hardwaresimulator
+
+go 1.16
@@ -0,0 +1,419 @@
+package hdl
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Pin is pin of chip interface. e.g. "a[16]" in "IN a[16];"
+type Pin struct {
+	Name  string
+	Width int
+}
+
+// PinRef is pin written in connection of part. e.g. "a", "a[3]", "a[0..7]"
+type PinRef struct {
+	Name string
+	From int
+	To   int
+	Sub  bool // whether sub bus is specified. From and To are valid only if Sub is true
+}
+
+func (ref PinRef) String() string {
+	if !ref.Sub {
+		return ref.Name
+	}
+	if ref.From == ref.To {
+		return fmt.Sprintf("%s[%d]", ref.Name, ref.From)
+	}
+	return fmt.Sprintf("%s[%d..%d]", ref.Name, ref.From, ref.To)
+}
+
+// IsConstant returns whether pin is "true" or "false"
+func (ref PinRef) IsConstant() bool {
+	return ref.Name == "true" || ref.Name == "false"
+}
+
+// Connection is "inner=outer" in part. inner is pin of part and outer is pin of chip which has the part.
+type Connection struct {
+	Inner PinRef
+	Outer PinRef
+}
+
+// Part is a chip used in PARTS. e.g. "Nand(a=a, b=b, out=out);"
+type Part struct {
+	Name        string
+	Connections []Connection
+	Line        int
+}
+
+// Chip is chip declared by "CHIP Name { ... }"
+type Chip struct {
+	Name    string
+	In      []Pin
+	Out     []Pin
+	Parts   []Part
+	Builtin string   // name of builtin implementation. e.g. "BUILTIN Nand;"
+	Clocked []string // input pins which are read only at clock. e.g. "CLOCKED in, load;"
+}
+
+// Pin returns pin of chip interface and whether it is input pin
+func (c *Chip) Pin(name string) (Pin, bool, bool) {
+	for _, pin := range c.In {
+		if pin.Name == name {
+			return pin, true, true
+		}
+	}
+	for _, pin := range c.Out {
+		if pin.Name == name {
+			return pin, false, true
+		}
+	}
+	return Pin{}, false, false
+}
+
+// IsClocked returns whether input pin is read only at clock
+func (c *Chip) IsClocked(name string) bool {
+	for _, clocked := range c.Clocked {
+		if clocked == name {
+			return true
+		}
+	}
+	return false
+}
+
+const MAX_WIDTH = 16
+
+type tokenType string
+
+const (
+	IDENTIFIER tokenType = "IDENTIFIER"
+	NUMBER     tokenType = "NUMBER"
+	SYMBOL     tokenType = "SYMBOL"
+	EOF        tokenType = "EOF"
+)
+
+type hdlToken struct {
+	Type    tokenType
+	Literal string
+	Line    int
+}
+
+// Parser parses HDL of nand2tetris
+type Parser struct {
+	filename string
+	tokens   []hdlToken
+	position int
+}
+
+func New(filename string, input string) (*Parser, error) {
+	tokens, err := tokenize(input)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%s", filename, err)
+	}
+	return &Parser{filename: filename, tokens: tokens}, nil
+}
+
+// Parse parses HDL file. filename is used in error message.
+func Parse(filename string, input string) (*Chip, error) {
+	p, err := New(filename, input)
+	if err != nil {
+		return nil, err
+	}
+	return p.ParseChip()
+}
+
+func (p *Parser) current() hdlToken {
+	return p.tokens[p.position]
+}
+
+func (p *Parser) advance() hdlToken {
+	tok := p.tokens[p.position]
+	if tok.Type != EOF {
+		p.position++
+	}
+	return tok
+}
+
+func (p *Parser) errorf(format string, a ...interface{}) error {
+	return fmt.Errorf("%s:%d: %s", p.filename, p.current().Line, fmt.Sprintf(format, a...))
+}
+
+func (p *Parser) expect(literal string) error {
+	if p.current().Literal != literal || p.current().Type == EOF {
+		return p.errorf("expected %q. got %q", literal, p.current().Literal)
+	}
+	p.advance()
+	return nil
+}
+
+func (p *Parser) expectIdentifier() (string, error) {
+	if p.current().Type != IDENTIFIER {
+		return "", p.errorf("expected identifier. got %q", p.current().Literal)
+	}
+	return p.advance().Literal, nil
+}
+
+func (p *Parser) expectNumber() (int, error) {
+	if p.current().Type != NUMBER {
+		return 0, p.errorf("expected number. got %q", p.current().Literal)
+	}
+	return strconv.Atoi(p.advance().Literal)
+}
+
+// ParseChip parses "CHIP Name { IN ...; OUT ...; PARTS: ... }"
+func (p *Parser) ParseChip() (*Chip, error) {
+	if err := p.expect("CHIP"); err != nil {
+		return nil, err
+	}
+	name, err := p.expectIdentifier()
+	if err != nil {
+		return nil, err
+	}
+	chip := &Chip{Name: name, In: []Pin{}, Out: []Pin{}, Parts: []Part{}, Clocked: []string{}}
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	for p.current().Literal != "}" {
+		switch p.current().Literal {
+		case "IN":
+			p.advance()
+			if chip.In, err = p.parsePins(chip); err != nil {
+				return nil, err
+			}
+		case "OUT":
+			p.advance()
+			if chip.Out, err = p.parsePins(chip); err != nil {
+				return nil, err
+			}
+		case "PARTS":
+			p.advance()
+			if err := p.expect(":"); err != nil {
+				return nil, err
+			}
+			if chip.Parts, err = p.parseParts(); err != nil {
+				return nil, err
+			}
+		case "BUILTIN":
+			p.advance()
+			if chip.Builtin, err = p.expectIdentifier(); err != nil {
+				return nil, err
+			}
+			if err := p.expect(";"); err != nil {
+				return nil, err
+			}
+		case "CLOCKED":
+			p.advance()
+			if chip.Clocked, err = p.parseNames(); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, p.errorf("expected IN, OUT, PARTS, BUILTIN or CLOCKED. got %q", p.current().Literal)
+		}
+	}
+	p.advance()
+	if p.current().Type != EOF {
+		return nil, p.errorf("unexpected %q after chip", p.current().Literal)
+	}
+	return chip, nil
+}
+
+// parsePins parses "a[16], b, c;"
+func (p *Parser) parsePins(chip *Chip) ([]Pin, error) {
+	pins := []Pin{}
+	for {
+		name, err := p.expectIdentifier()
+		if err != nil {
+			return nil, err
+		}
+		if _, _, ok := chip.Pin(name); ok || containsPin(pins, name) {
+			return nil, p.errorf("pin %s is declared twice", name)
+		}
+		pin := Pin{Name: name, Width: 1}
+		if p.current().Literal == "[" {
+			p.advance()
+			if pin.Width, err = p.expectNumber(); err != nil {
+				return nil, err
+			}
+			if pin.Width < 1 || pin.Width > MAX_WIDTH {
+				return nil, p.errorf("width of %s should be 1~%d. got %d", name, MAX_WIDTH, pin.Width)
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+		}
+		pins = append(pins, pin)
+		if p.current().Literal == ";" {
+			p.advance()
+			return pins, nil
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func containsPin(pins []Pin, name string) bool {
+	for _, pin := range pins {
+		if pin.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// parseNames parses "in, load;"
+func (p *Parser) parseNames() ([]string, error) {
+	names := []string{}
+	for {
+		name, err := p.expectIdentifier()
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+		if p.current().Literal == ";" {
+			p.advance()
+			return names, nil
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// parseParts parses parts until "}"
+func (p *Parser) parseParts() ([]Part, error) {
+	parts := []Part{}
+	for p.current().Type == IDENTIFIER && p.current().Literal != "BUILTIN" && p.current().Literal != "CLOCKED" {
+		part := Part{Line: p.current().Line, Connections: []Connection{}}
+		part.Name = p.advance().Literal
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		for {
+			connection, err := p.parseConnection()
+			if err != nil {
+				return nil, err
+			}
+			part.Connections = append(part.Connections, connection)
+			if p.current().Literal == ")" {
+				p.advance()
+				break
+			}
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		if err := p.expect(";"); err != nil {
+			return nil, err
+		}
+		parts = append(parts, part)
+	}
+	return parts, nil
+}
+
+func (p *Parser) parseConnection() (Connection, error) {
+	inner, err := p.parsePinRef()
+	if err != nil {
+		return Connection{}, err
+	}
+	if inner.IsConstant() {
+		return Connection{}, p.errorf("%s can not be used as pin of part", inner.Name)
+	}
+	if err := p.expect("="); err != nil {
+		return Connection{}, err
+	}
+	outer, err := p.parsePinRef()
+	if err != nil {
+		return Connection{}, err
+	}
+	if outer.IsConstant() && outer.Sub {
+		return Connection{}, p.errorf("%s can not have sub bus", outer.Name)
+	}
+	return Connection{Inner: inner, Outer: outer}, nil
+}
+
+// parsePinRef parses "a", "a[3]" or "a[0..7]"
+func (p *Parser) parsePinRef() (PinRef, error) {
+	name, err := p.expectIdentifier()
+	if err != nil {
+		return PinRef{}, err
+	}
+	ref := PinRef{Name: name}
+	if p.current().Literal != "[" {
+		return ref, nil
+	}
+	p.advance()
+	ref.Sub = true
+	if ref.From, err = p.expectNumber(); err != nil {
+		return ref, err
+	}
+	ref.To = ref.From
+	if p.current().Literal == ".." {
+		p.advance()
+		if ref.To, err = p.expectNumber(); err != nil {
+			return ref, err
+		}
+	}
+	if ref.To < ref.From {
+		return ref, p.errorf("invalid sub bus %s", ref)
+	}
+	return ref, p.expect("]")
+}
+
+func isLetter(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || c == '_'
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+// tokenize splits HDL into tokens. comments are removed.
+func tokenize(input string) ([]hdlToken, error) {
+	tokens := []hdlToken{}
+	line := 1
+	for i := 0; i < len(input); {
+		c := input[i]
+		switch {
+		case c == '\n':
+			line++
+			i++
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+		case strings.HasPrefix(input[i:], "//"):
+			for i < len(input) && input[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(input[i:], "/*"):
+			end := strings.Index(input[i+2:], "*/")
+			if end == -1 {
+				return nil, fmt.Errorf("%d: comment is not closed", line)
+			}
+			line += strings.Count(input[i:i+2+end], "\n")
+			i += end + 4
+		case strings.HasPrefix(input[i:], ".."):
+			tokens = append(tokens, hdlToken{SYMBOL, "..", line})
+			i += 2
+		case strings.IndexByte("{}()[],;=:", c) != -1:
+			tokens = append(tokens, hdlToken{SYMBOL, string(c), line})
+			i++
+		case isLetter(c):
+			start := i
+			for i < len(input) && (isLetter(input[i]) || isDigit(input[i])) {
+				i++
+			}
+			tokens = append(tokens, hdlToken{IDENTIFIER, input[start:i], line})
+		case isDigit(c):
+			start := i
+			for i < len(input) && isDigit(input[i]) {
+				i++
+			}
+			tokens = append(tokens, hdlToken{NUMBER, input[start:i], line})
+		default:
+			return nil, fmt.Errorf("%d: unexpected character %q", line, c)
+		}
+	}
+	return append(tokens, hdlToken{EOF, "", line}), nil
+}
@@ -0,0 +1,80 @@
+package hdl
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseChip(t *testing.T) {
+	input := `// comment
+/** Mux with
+ *  sub bus */
+CHIP Test {
+    IN a[16], sel;
+    OUT out[8], zr;
+
+    PARTS:
+    Mux16(a=a, b=false, sel=sel, out[0..7]=out, out[15]=top);
+    Not(in=top, out=zr);
+}`
+	expected := &Chip{
+		Name: "Test",
+		In:   []Pin{{"a", 16}, {"sel", 1}},
+		Out:  []Pin{{"out", 8}, {"zr", 1}},
+		Parts: []Part{
+			{Name: "Mux16", Line: 9, Connections: []Connection{
+				{PinRef{Name: "a"}, PinRef{Name: "a"}},
+				{PinRef{Name: "b"}, PinRef{Name: "false"}},
+				{PinRef{Name: "sel"}, PinRef{Name: "sel"}},
+				{PinRef{Name: "out", From: 0, To: 7, Sub: true}, PinRef{Name: "out"}},
+				{PinRef{Name: "out", From: 15, To: 15, Sub: true}, PinRef{Name: "top"}},
+			}},
+			{Name: "Not", Line: 10, Connections: []Connection{
+				{PinRef{Name: "in"}, PinRef{Name: "top"}},
+				{PinRef{Name: "out"}, PinRef{Name: "zr"}},
+			}},
+		},
+		Clocked: []string{},
+	}
+	chip, err := Parse("Test.hdl", input)
+	if err != nil {
+		t.Fatalf("Parse() returned error: %s", err)
+	}
+	if !reflect.DeepEqual(chip, expected) {
+		t.Fatalf("Parse() should return %+v. got %+v", expected, chip)
+	}
+}
+
+func TestParseBuiltin(t *testing.T) {
+	chip, err := Parse("DFF.hdl", "CHIP DFF { IN in; OUT out; BUILTIN DFF; CLOCKED in; }")
+	if err != nil {
+		t.Fatalf("Parse() returned error: %s", err)
+	}
+	if chip.Builtin != "DFF" || !chip.IsClocked("in") || chip.IsClocked("out") {
+		t.Fatalf("unexpected chip %+v", chip)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	testCases := []struct {
+		input         string
+		expectedError string
+	}{
+		{"CHIP X { IN a; OUT out; PARTS: Not(in=a, out=out) }", `X.hdl:1: expected ";". got "}"`},
+		{"CHIP X {\n IN a[17]; }", `X.hdl:2: width of a should be 1~16. got 17`},
+		{"CHIP X { IN a, a; }", `X.hdl:1: pin a is declared twice`},
+		{"CHIP X { IN a;\n PARTS: Not(in=a[3..1]); }", `X.hdl:2: invalid sub bus a[3..1]`},
+		{"CHIP X { PARTS: Not(true=a); }", `X.hdl:1: true can not be used as pin of part`},
+		{"CHIP X { PARTS: Not(in=true[0]); }", `X.hdl:1: true can not have sub bus`},
+		{"CHIP X { IN a; } }", `X.hdl:1: unexpected "}" after chip`},
+		{"CHIP X { IN a#; }", `X.hdl:1: unexpected character '#'`},
+		{"CHIP X { IN a; /* }", `X.hdl:1: comment is not closed`},
+		{"CHIP X { IN a;", `X.hdl:1: expected IN, OUT, PARTS, BUILTIN or CLOCKED. got ""`},
+	}
+	for _, tt := range testCases {
+		_, err := Parse("X.hdl", tt.input)
+		if err == nil || err.Error() != tt.expectedError {
+			t.Errorf("Parse(%q) should return error %q. got %v", tt.input, tt.expectedError, err)
+		}
+	}
+}
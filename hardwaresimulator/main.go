@@ -0,0 +1,67 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"hardwaresimulator/tstscript"
+	"os"
+	"path/filepath"
+)
+
+// getTstFileList returns .tst passed by argument or .tst files in the dir passed by argument
+func getTstFileList(path string) ([]string, error) {
+	fileInfo, err := os.Stat(path)
+	if err != nil {
+		return []string{}, err
+	}
+	if !fileInfo.IsDir() {
+		return []string{path}, nil
+	}
+	tstFileList, err := filepath.Glob(filepath.Join(path, "*.tst"))
+	if err != nil {
+		return []string{}, err
+	}
+	if len(tstFileList) == 0 {
+		return []string{}, fmt.Errorf("no .tst file in %s", path)
+	}
+	return tstFileList, nil
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s <file.tst or dir>...\n", os.Args[0])
+		fmt.Fprintln(os.Stderr, "runs test scripts of Hardware Simulator, writes .out files and compares them with .cmp files")
+		flag.PrintDefaults()
+	}
+	noOutput := flag.Bool("no-output", false, "do not write .out files")
+	pressKeys := flag.Bool("press-keys", false, "hold down keys which scripts ask for by echo (e.g. Memory.tst)")
+	flag.Parse()
+	var echo func(r *tstscript.Runner, message string)
+	if *pressKeys {
+		echo = tstscript.PressRequestedKey
+	}
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	failed := false
+	for _, path := range flag.Args() {
+		tstFileList, err := getTstFileList(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			failed = true
+			continue
+		}
+		for _, tstFilename := range tstFileList {
+			if _, err := tstscript.RunFile(tstFilename, !*noOutput, echo); err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %s\n", tstFilename, err)
+				failed = true
+				continue
+			}
+			fmt.Printf("%s: End of script - Comparison ended successfully\n", tstFilename)
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
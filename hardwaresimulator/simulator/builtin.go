@@ -0,0 +1,259 @@
+package simulator
+
+import (
+	"fmt"
+	"hardwaresimulator/hdl"
+)
+
+// Device is chip implemented in Go. in and out have values of pins in order of IN and OUT of its interface.
+type Device interface {
+	Eval(in []uint16, out []uint16)
+}
+
+// ClockedDevice is Device which has state. Tick reads inputs and Tock commits new state to outputs.
+type ClockedDevice interface {
+	Device
+	Tick(in []uint16)
+	Tock()
+}
+
+// Memory is Device whose state can be read and written by test script. e.g. RAM16K[0], ARegister[]
+type Memory interface {
+	Device
+	Size() int
+	Get(address int) uint16
+	Set(address int, value uint16)
+}
+
+type combinational func(in []uint16, out []uint16)
+
+func (f combinational) Eval(in []uint16, out []uint16) {
+	f(in, out)
+}
+
+func bit(b bool) uint16 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// register is Bit, Register, ARegister and DRegister. pins are in, load.
+// state is changed at tick and output is changed at tock like builtin chips of Hardware Simulator.
+type register struct {
+	value uint16
+	out   uint16
+}
+
+func (r *register) Eval(in []uint16, out []uint16) {
+	out[0] = r.out
+}
+
+func (r *register) Tick(in []uint16) {
+	if in[1] != 0 {
+		r.value = in[0]
+	}
+}
+
+func (r *register) Tock() {
+	r.out = r.value
+}
+
+func (r *register) Size() int { return 1 }
+
+func (r *register) Get(address int) uint16 { return r.value }
+
+func (r *register) Set(address int, value uint16) {
+	r.value, r.out = value, value
+}
+
+// counter is PC. pins are in, load, inc, reset.
+type counter struct {
+	register
+}
+
+func (c *counter) Tick(in []uint16) {
+	switch {
+	case in[3] != 0:
+		c.value = 0
+	case in[1] != 0:
+		c.value = in[0]
+	case in[2] != 0:
+		c.value++
+	}
+}
+
+// ram is RAM8 ~ RAM16K and Screen. pins are in, load, address.
+// word is written at tick and out is changed by address without clock.
+type ram struct {
+	words []uint16
+}
+
+func (r *ram) Eval(in []uint16, out []uint16) {
+	out[0] = r.words[in[2]]
+}
+
+func (r *ram) Tick(in []uint16) {
+	if in[1] != 0 {
+		r.words[in[2]] = in[0]
+	}
+}
+
+func (r *ram) Tock() {}
+
+func (r *ram) Size() int { return len(r.words) }
+
+func (r *ram) Get(address int) uint16 { return r.words[address] }
+
+func (r *ram) Set(address int, value uint16) { r.words[address] = value }
+
+// rom is ROM32K. pin is address.
+type rom struct {
+	words []uint16
+}
+
+func (r *rom) Eval(in []uint16, out []uint16) {
+	out[0] = r.words[in[0]]
+}
+
+func (r *rom) Size() int { return len(r.words) }
+
+func (r *rom) Get(address int) uint16 { return r.words[address] }
+
+func (r *rom) Set(address int, value uint16) { r.words[address] = value }
+
+// keyboard has key code of pressed key
+type keyboard struct {
+	key uint16
+}
+
+func (k *keyboard) Eval(in []uint16, out []uint16) {
+	out[0] = k.key
+}
+
+func (k *keyboard) Size() int { return 1 }
+
+func (k *keyboard) Get(address int) uint16 { return k.key }
+
+func (k *keyboard) Set(address int, value uint16) { k.key = value }
+
+func alu(in []uint16, out []uint16) {
+	x, y := in[0], in[1]
+	if in[2] != 0 { // zx
+		x = 0
+	}
+	if in[3] != 0 { // nx
+		x = ^x
+	}
+	if in[4] != 0 { // zy
+		y = 0
+	}
+	if in[5] != 0 { // ny
+		y = ^y
+	}
+	o := x & y
+	if in[6] != 0 { // f
+		o = x + y
+	}
+	if in[7] != 0 { // no
+		o = ^o
+	}
+	out[0], out[1], out[2] = o, bit(o == 0), bit(int16(o) < 0)
+}
+
+// mux selects one of inputs by last input(sel)
+func mux(in []uint16, out []uint16) {
+	out[0] = in[in[len(in)-1]]
+}
+
+// dmux writes input(in) to one of outputs selected by sel
+func dmux(in []uint16, out []uint16) {
+	for i := range out {
+		out[i] = 0
+	}
+	out[in[1]] = in[0]
+}
+
+type builtin struct {
+	hdl       string
+	newDevice func() Device
+}
+
+// builtins are chips which are used when .hdl is not found. Nand and DFF are primitives of simulator.
+var builtins = map[string]builtin{
+	"Nand": {"IN a, b; OUT out;", nil},
+	"DFF":  {"IN in; OUT out; CLOCKED in;", nil},
+	"Not": {"IN in; OUT out;", func() Device {
+		return combinational(func(in, out []uint16) { out[0] = in[0] ^ 1 })
+	}},
+	"And": {"IN a, b; OUT out;", func() Device {
+		return combinational(func(in, out []uint16) { out[0] = in[0] & in[1] })
+	}},
+	"Or": {"IN a, b; OUT out;", func() Device {
+		return combinational(func(in, out []uint16) { out[0] = in[0] | in[1] })
+	}},
+	"Xor": {"IN a, b; OUT out;", func() Device {
+		return combinational(func(in, out []uint16) { out[0] = in[0] ^ in[1] })
+	}},
+	"Mux":  {"IN a, b, sel; OUT out;", func() Device { return combinational(mux) }},
+	"DMux": {"IN in, sel; OUT a, b;", func() Device { return combinational(dmux) }},
+	"Not16": {"IN in[16]; OUT out[16];", func() Device {
+		return combinational(func(in, out []uint16) { out[0] = ^in[0] })
+	}},
+	"And16": {"IN a[16], b[16]; OUT out[16];", func() Device {
+		return combinational(func(in, out []uint16) { out[0] = in[0] & in[1] })
+	}},
+	"Or16": {"IN a[16], b[16]; OUT out[16];", func() Device {
+		return combinational(func(in, out []uint16) { out[0] = in[0] | in[1] })
+	}},
+	"Mux16":     {"IN a[16], b[16], sel; OUT out[16];", func() Device { return combinational(mux) }},
+	"Mux4Way16": {"IN a[16], b[16], c[16], d[16], sel[2]; OUT out[16];", func() Device { return combinational(mux) }},
+	"Mux8Way16": {"IN a[16], b[16], c[16], d[16], e[16], f[16], g[16], h[16], sel[3]; OUT out[16];", func() Device { return combinational(mux) }},
+	"Or8Way": {"IN in[8]; OUT out;", func() Device {
+		return combinational(func(in, out []uint16) { out[0] = bit(in[0] != 0) })
+	}},
+	"DMux4Way": {"IN in, sel[2]; OUT a, b, c, d;", func() Device { return combinational(dmux) }},
+	"DMux8Way": {"IN in, sel[3]; OUT a, b, c, d, e, f, g, h;", func() Device { return combinational(dmux) }},
+	"HalfAdder": {"IN a, b; OUT sum, carry;", func() Device {
+		return combinational(func(in, out []uint16) { out[0], out[1] = in[0]^in[1], in[0]&in[1] })
+	}},
+	"FullAdder": {"IN a, b, c; OUT sum, carry;", func() Device {
+		return combinational(func(in, out []uint16) {
+			s := in[0] + in[1] + in[2]
+			out[0], out[1] = s&1, s>>1
+		})
+	}},
+	"Add16": {"IN a[16], b[16]; OUT out[16];", func() Device {
+		return combinational(func(in, out []uint16) { out[0] = in[0] + in[1] })
+	}},
+	"Inc16": {"IN in[16]; OUT out[16];", func() Device {
+		return combinational(func(in, out []uint16) { out[0] = in[0] + 1 })
+	}},
+	"ALU":       {"IN x[16], y[16], zx, nx, zy, ny, f, no; OUT out[16], zr, ng;", func() Device { return combinational(alu) }},
+	"Bit":       {"IN in, load; OUT out; CLOCKED in, load;", func() Device { return &register{} }},
+	"Register":  {"IN in[16], load; OUT out[16]; CLOCKED in, load;", func() Device { return &register{} }},
+	"ARegister": {"IN in[16], load; OUT out[16]; CLOCKED in, load;", func() Device { return &register{} }},
+	"DRegister": {"IN in[16], load; OUT out[16]; CLOCKED in, load;", func() Device { return &register{} }},
+	"PC":        {"IN in[16], load, inc, reset; OUT out[16]; CLOCKED in, load, inc, reset;", func() Device { return &counter{} }},
+	"RAM8":      {"IN in[16], load, address[3]; OUT out[16]; CLOCKED in, load;", func() Device { return &ram{words: make([]uint16, 8)} }},
+	"RAM64":     {"IN in[16], load, address[6]; OUT out[16]; CLOCKED in, load;", func() Device { return &ram{words: make([]uint16, 64)} }},
+	"RAM512":    {"IN in[16], load, address[9]; OUT out[16]; CLOCKED in, load;", func() Device { return &ram{words: make([]uint16, 512)} }},
+	"RAM4K":     {"IN in[16], load, address[12]; OUT out[16]; CLOCKED in, load;", func() Device { return &ram{words: make([]uint16, 4096)} }},
+	"RAM16K":    {"IN in[16], load, address[14]; OUT out[16]; CLOCKED in, load;", func() Device { return &ram{words: make([]uint16, 16384)} }},
+	"Screen":    {"IN in[16], load, address[13]; OUT out[16]; CLOCKED in, load;", func() Device { return &ram{words: make([]uint16, 8192)} }},
+	"ROM32K":    {"IN address[15]; OUT out[16];", func() Device { return &rom{words: make([]uint16, 32768)} }},
+	"Keyboard":  {"OUT out[16];", func() Device { return &keyboard{} }},
+}
+
+// builtinChip returns interface of builtin chip
+func builtinChip(name string) (*hdl.Chip, bool) {
+	b, ok := builtins[name]
+	if !ok {
+		return nil, false
+	}
+	chip, err := hdl.Parse(name+".hdl", fmt.Sprintf("CHIP %s { %s BUILTIN %s; }", name, b.hdl, name))
+	if err != nil {
+		panic(err) // builtinsのHDLは常に正しい
+	}
+	return chip, true
+}
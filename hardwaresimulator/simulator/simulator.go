@@ -0,0 +1,612 @@
+package simulator
+
+import (
+	"fmt"
+	"hardwaresimulator/hdl"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	FALSE_WIRE = 0
+	TRUE_WIRE  = 1
+)
+
+// Loader finds chips by name. .hdl in Dirs is used first and builtin chip is used if .hdl is not found like Hardware Simulator of nand2tetris.
+type Loader struct {
+	Dirs  []string
+	chips map[string]*hdl.Chip
+}
+
+func NewLoader(dirs ...string) *Loader {
+	return &Loader{Dirs: dirs, chips: map[string]*hdl.Chip{}}
+}
+
+// Chip returns chip declared in <name>.hdl or builtin chip
+func (l *Loader) Chip(name string) (*hdl.Chip, error) {
+	if chip, ok := l.chips[name]; ok {
+		return chip, nil
+	}
+	for _, dir := range l.Dirs {
+		hdlFilename := filepath.Join(dir, name+".hdl")
+		input, err := ioutil.ReadFile(hdlFilename)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		chip, err := hdl.Parse(hdlFilename, string(input))
+		if err != nil {
+			return nil, err
+		}
+		if chip.Name != name {
+			return nil, fmt.Errorf("%s declares chip %s", hdlFilename, chip.Name)
+		}
+		if chip.Builtin != "" {
+			if _, ok := builtins[chip.Builtin]; !ok {
+				return nil, fmt.Errorf("%s: builtin chip %s is not found", hdlFilename, chip.Builtin)
+			}
+		}
+		l.chips[name] = chip
+		return chip, nil
+	}
+	chip, ok := builtinChip(name)
+	if !ok {
+		return nil, fmt.Errorf("chip %s is not found", name)
+	}
+	l.chips[name] = chip
+	return chip, nil
+}
+
+type nand struct {
+	a, b, out int32
+}
+
+type dff struct {
+	in, out int32
+	next    bool
+}
+
+type device struct {
+	name   string
+	device Device
+	in     [][]int32 // wires of each input pin
+	out    [][]int32 // wires of each output pin
+	inVal  []uint16
+	outVal []uint16
+	comb   []bool // whether input pin changes outputs without clock
+}
+
+// Simulator simulates chip by flattening it to Nand, DFF and builtin devices.
+// Evaluation is event driven. only gates whose inputs are changed are evaluated in topological order.
+type Simulator struct {
+	Chip    *hdl.Chip
+	pins    map[string][]int32 // pins of chip and its internal pins
+	values  []bool
+	nands   []nand
+	dffs    []dff
+	devices []*device
+
+	// nodes are nands and devices. node i (i >= len(nands)) is devices[i-len(nands)]
+	level       []int32
+	fanoutStart []int32
+	fanout      []int32
+	queue       [][]int32
+	scheduled   []bool
+
+	// used only while chip is built
+	loader   *Loader
+	parent   []int32
+	building map[string]bool
+}
+
+// Load builds simulator of chip in hdlFilename. parts are searched in dir of hdlFilename and dirs.
+func Load(hdlFilename string, dirs ...string) (*Simulator, error) {
+	name := strings.TrimSuffix(filepath.Base(hdlFilename), filepath.Ext(hdlFilename))
+	loader := NewLoader(append([]string{filepath.Dir(hdlFilename)}, dirs...)...)
+	if _, err := loader.Chip(name); err != nil {
+		return nil, err
+	}
+	return New(loader, name)
+}
+
+// New builds simulator of chip which is found by loader
+func New(loader *Loader, name string) (*Simulator, error) {
+	chip, err := loader.Chip(name)
+	if err != nil {
+		return nil, err
+	}
+	s := &Simulator{Chip: chip, loader: loader, parent: []int32{FALSE_WIRE, TRUE_WIRE}, building: map[string]bool{}}
+	bindings := map[string][]int32{}
+	for _, pin := range append(append([]hdl.Pin{}, chip.In...), chip.Out...) {
+		bindings[pin.Name] = s.newWires(pin.Width)
+	}
+	pins, err := s.build(chip, bindings)
+	if err != nil {
+		return nil, err
+	}
+	s.pins = pins
+	if err := s.compact(); err != nil {
+		return nil, err
+	}
+	s.loader, s.parent, s.building = nil, nil, nil
+	// 全てのゲートを一度評価して初期状態にする
+	for node := range s.level {
+		s.schedule(int32(node))
+	}
+	s.Eval()
+	return s, nil
+}
+
+func (s *Simulator) newWires(width int) []int32 {
+	wires := make([]int32, width)
+	for i := range wires {
+		wires[i] = int32(len(s.parent))
+		s.parent = append(s.parent, wires[i])
+	}
+	return wires
+}
+
+func (s *Simulator) find(wire int32) int32 {
+	for s.parent[wire] != wire {
+		s.parent[wire] = s.parent[s.parent[wire]]
+		wire = s.parent[wire]
+	}
+	return wire
+}
+
+func (s *Simulator) union(a int32, b int32) {
+	a, b = s.find(a), s.find(b)
+	if a == b {
+		return
+	}
+	if a < b { // 定数の配線(0,1)が代表になるようにする
+		s.parent[b] = a
+	} else {
+		s.parent[a] = b
+	}
+}
+
+// build instantiates chip whose pins are connected to bindings. it returns pins and internal pins of chip.
+func (s *Simulator) build(chip *hdl.Chip, bindings map[string][]int32) (map[string][]int32, error) {
+	if chip.Builtin != "" {
+		return bindings, s.buildBuiltin(chip, bindings)
+	}
+	if s.building[chip.Name] {
+		return nil, fmt.Errorf("chip %s uses itself", chip.Name)
+	}
+	s.building[chip.Name] = true
+	defer delete(s.building, chip.Name)
+
+	scope := map[string][]int32{}
+	for name, wires := range bindings {
+		scope[name] = wires
+	}
+	driven := map[string][]bool{}
+	for _, pin := range chip.Out {
+		driven[pin.Name] = make([]bool, pin.Width)
+	}
+	for _, part := range chip.Parts {
+		partChip, err := s.loader.Chip(part.Name)
+		if err != nil {
+			return nil, fmt.Errorf("%s line %d: %s", chip.Name, part.Line, err)
+		}
+		partBindings, err := s.connect(chip, part, partChip, scope, driven)
+		if err != nil {
+			return nil, fmt.Errorf("%s line %d: %s", chip.Name, part.Line, err)
+		}
+		if _, err := s.build(partChip, partBindings); err != nil {
+			return nil, err
+		}
+	}
+	return scope, nil
+}
+
+// connect resolves wires connected to each pin of part. internal pins are added to scope when they are used first.
+func (s *Simulator) connect(chip *hdl.Chip, part hdl.Part, partChip *hdl.Chip, scope map[string][]int32, driven map[string][]bool) (map[string][]int32, error) {
+	partBindings := map[string][]int32{}
+	for _, pin := range append(append([]hdl.Pin{}, partChip.In...), partChip.Out...) {
+		wires := make([]int32, pin.Width)
+		for i := range wires {
+			wires[i] = -1
+		}
+		partBindings[pin.Name] = wires
+	}
+	for _, connection := range part.Connections {
+		inner, outer := connection.Inner, connection.Outer
+		innerPin, innerIsInput, ok := partChip.Pin(inner.Name)
+		if !ok {
+			return nil, fmt.Errorf("%s has no pin %s", part.Name, inner.Name)
+		}
+		from, to := 0, innerPin.Width-1
+		if inner.Sub {
+			from, to = inner.From, inner.To
+		}
+		if to >= innerPin.Width {
+			return nil, fmt.Errorf("%s is out of range of %s[%d]", inner, inner.Name, innerPin.Width)
+		}
+		width := to - from + 1
+		outerWires, err := s.resolveOuter(chip, outer, width, innerIsInput, scope, driven)
+		if err != nil {
+			return nil, err
+		}
+		for i, wire := range outerWires {
+			current := partBindings[inner.Name][from+i]
+			switch {
+			case current == -1:
+				partBindings[inner.Name][from+i] = wire
+			case innerIsInput:
+				return nil, fmt.Errorf("%s of %s is connected twice", inner, part.Name)
+			default:
+				s.union(current, wire)
+			}
+		}
+	}
+	for _, pin := range partChip.In {
+		for i, wire := range partBindings[pin.Name] {
+			if wire == -1 { // 接続されていない入力は false
+				partBindings[pin.Name][i] = FALSE_WIRE
+			}
+		}
+	}
+	for _, pin := range partChip.Out {
+		for i, wire := range partBindings[pin.Name] {
+			if wire == -1 {
+				partBindings[pin.Name][i] = s.newWires(1)[0]
+			}
+		}
+	}
+	return partBindings, nil
+}
+
+// resolveOuter returns wires of outer pin of connection which has width bits
+func (s *Simulator) resolveOuter(chip *hdl.Chip, outer hdl.PinRef, width int, innerIsInput bool, scope map[string][]int32, driven map[string][]bool) ([]int32, error) {
+	if outer.IsConstant() {
+		if !innerIsInput {
+			return nil, fmt.Errorf("output can not be connected to %s", outer.Name)
+		}
+		wire := int32(FALSE_WIRE)
+		if outer.Name == "true" {
+			wire = TRUE_WIRE
+		}
+		wires := make([]int32, width)
+		for i := range wires {
+			wires[i] = wire
+		}
+		return wires, nil
+	}
+	pin, isInput, isPin := chip.Pin(outer.Name)
+	if isPin && isInput && !innerIsInput {
+		return nil, fmt.Errorf("input pin %s can not be connected to output of part", outer.Name)
+	}
+	if isPin && !isInput && innerIsInput {
+		return nil, fmt.Errorf("output pin %s can not be connected to input of part", outer.Name)
+	}
+	if !isPin && outer.Sub {
+		return nil, fmt.Errorf("internal pin %s can not have sub bus", outer)
+	}
+	wires, ok := scope[outer.Name]
+	if !ok { // 内部ピンは最初に使われた時の幅で作る
+		wires = s.newWires(width)
+		scope[outer.Name] = wires
+		driven[outer.Name] = make([]bool, width)
+	}
+	from, to := 0, len(wires)-1
+	if outer.Sub {
+		from, to = outer.From, outer.To
+		if to >= len(wires) {
+			return nil, fmt.Errorf("%s is out of range of %s[%d]", outer, outer.Name, pin.Width)
+		}
+	}
+	if to-from+1 != width {
+		return nil, fmt.Errorf("width of %s is %d. but %d is expected", outer, to-from+1, width)
+	}
+	if !innerIsInput {
+		for i := from; i <= to; i++ {
+			if driven[outer.Name][i] {
+				return nil, fmt.Errorf("%s is driven by multiple parts", outer)
+			}
+			driven[outer.Name][i] = true
+		}
+	}
+	return wires[from : to+1], nil
+}
+
+func (s *Simulator) buildBuiltin(chip *hdl.Chip, bindings map[string][]int32) error {
+	switch chip.Builtin {
+	case "Nand":
+		s.nands = append(s.nands, nand{a: bindings["a"][0], b: bindings["b"][0], out: bindings["out"][0]})
+		return nil
+	case "DFF":
+		s.dffs = append(s.dffs, dff{in: bindings["in"][0], out: bindings["out"][0]})
+		return nil
+	}
+	impl, ok := builtins[chip.Builtin]
+	if !ok || impl.newDevice == nil {
+		return fmt.Errorf("builtin chip %s is not found", chip.Builtin)
+	}
+	d := &device{name: chip.Name, device: impl.newDevice(), in: [][]int32{}, out: [][]int32{}, comb: []bool{}}
+	for _, pin := range chip.In {
+		d.in = append(d.in, bindings[pin.Name])
+		d.comb = append(d.comb, !chip.IsClocked(pin.Name))
+	}
+	for _, pin := range chip.Out {
+		d.out = append(d.out, bindings[pin.Name])
+	}
+	d.inVal, d.outVal = make([]uint16, len(d.in)), make([]uint16, len(d.out))
+	s.devices = append(s.devices, d)
+	return nil
+}
+
+// compact renumbers wires connected by union, and sorts gates topologically
+func (s *Simulator) compact() error {
+	ids := make([]int32, len(s.parent))
+	for i := range ids {
+		ids[i] = -1
+	}
+	ids[FALSE_WIRE], ids[TRUE_WIRE] = FALSE_WIRE, TRUE_WIRE
+	count := int32(2)
+	rename := func(wire int32) int32 {
+		root := s.find(wire)
+		if ids[root] == -1 {
+			ids[root] = count
+			count++
+		}
+		return ids[root]
+	}
+	for i := range s.nands {
+		n := &s.nands[i]
+		n.a, n.b, n.out = rename(n.a), rename(n.b), rename(n.out)
+	}
+	for i := range s.dffs {
+		s.dffs[i].in, s.dffs[i].out = rename(s.dffs[i].in), rename(s.dffs[i].out)
+	}
+	for _, d := range s.devices {
+		for _, wires := range append(append([][]int32{}, d.in...), d.out...) {
+			for i := range wires {
+				wires[i] = rename(wires[i])
+			}
+		}
+	}
+	for name, wires := range s.pins {
+		renamed := make([]int32, len(wires))
+		for i := range wires {
+			renamed[i] = rename(wires[i])
+		}
+		s.pins[name] = renamed
+	}
+	s.values = make([]bool, count)
+	s.values[TRUE_WIRE] = true
+	return s.levelize(count)
+}
+
+// inputs returns wires which change outputs of node without clock
+func (s *Simulator) inputs(node int32) []int32 {
+	if int(node) < len(s.nands) {
+		return []int32{s.nands[node].a, s.nands[node].b}
+	}
+	d := s.devices[int(node)-len(s.nands)]
+	wires := []int32{}
+	for i, pin := range d.in {
+		if d.comb[i] {
+			wires = append(wires, pin...)
+		}
+	}
+	return wires
+}
+
+// levelize computes level of each node. node is evaluated after all nodes which drive its inputs.
+func (s *Simulator) levelize(wireCount int32) error {
+	nodeCount := len(s.nands) + len(s.devices)
+	driver := make([]int32, wireCount)
+	for i := range driver {
+		driver[i] = -1
+	}
+	for i, n := range s.nands {
+		driver[n.out] = int32(i)
+	}
+	for i, d := range s.devices {
+		for _, wires := range d.out {
+			for _, wire := range wires {
+				driver[wire] = int32(len(s.nands) + i)
+			}
+		}
+	}
+	s.level = make([]int32, nodeCount)
+	state := make([]byte, nodeCount) // 0: 未訪問 1: 訪問中 2: 完了
+	var visit func(node int32) error
+	visit = func(node int32) error {
+		switch state[node] {
+		case 1:
+			return fmt.Errorf("%s has combinational loop", s.Chip.Name)
+		case 2:
+			return nil
+		}
+		state[node] = 1
+		level := int32(0)
+		for _, wire := range s.inputs(node) {
+			if driver[wire] == -1 {
+				continue
+			}
+			if err := visit(driver[wire]); err != nil {
+				return err
+			}
+			if s.level[driver[wire]]+1 > level {
+				level = s.level[driver[wire]] + 1
+			}
+		}
+		s.level[node] = level
+		state[node] = 2
+		return nil
+	}
+	maxLevel := int32(0)
+	fanoutCount := make([]int32, wireCount+1)
+	for node := int32(0); node < int32(nodeCount); node++ {
+		if err := visit(node); err != nil {
+			return err
+		}
+		if s.level[node] > maxLevel {
+			maxLevel = s.level[node]
+		}
+		for _, wire := range s.inputs(node) {
+			fanoutCount[wire+1]++
+		}
+	}
+	// 配線ごとの入力先のノードを連続した配列に格納する
+	s.fanoutStart = make([]int32, wireCount+1)
+	for i := int32(1); i <= wireCount; i++ {
+		s.fanoutStart[i] = s.fanoutStart[i-1] + fanoutCount[i]
+	}
+	s.fanout = make([]int32, s.fanoutStart[wireCount])
+	next := append([]int32{}, s.fanoutStart[:wireCount]...)
+	for node := int32(0); node < int32(nodeCount); node++ {
+		for _, wire := range s.inputs(node) {
+			s.fanout[next[wire]] = node
+			next[wire]++
+		}
+	}
+	s.queue = make([][]int32, maxLevel+1)
+	s.scheduled = make([]bool, nodeCount)
+	return nil
+}
+
+func (s *Simulator) schedule(node int32) {
+	if s.scheduled[node] {
+		return
+	}
+	s.scheduled[node] = true
+	level := s.level[node]
+	s.queue[level] = append(s.queue[level], node)
+}
+
+func (s *Simulator) setWire(wire int32, value bool) {
+	if s.values[wire] == value {
+		return
+	}
+	s.values[wire] = value
+	for _, node := range s.fanout[s.fanoutStart[wire]:s.fanoutStart[wire+1]] {
+		s.schedule(node)
+	}
+}
+
+func (s *Simulator) read(wires []int32) uint16 {
+	v := uint16(0)
+	for i, wire := range wires {
+		if s.values[wire] {
+			v |= 1 << i
+		}
+	}
+	return v
+}
+
+func (s *Simulator) write(wires []int32, v uint16) {
+	for i, wire := range wires {
+		s.setWire(wire, v&(1<<i) != 0)
+	}
+}
+
+func (s *Simulator) readInputs(d *device) {
+	for i, wires := range d.in {
+		d.inVal[i] = s.read(wires)
+	}
+}
+
+// Eval propagates changes of inputs and states to all pins
+func (s *Simulator) Eval() {
+	for level := range s.queue {
+		// 同じレベルのノードは互いに影響しないので、評価中に追加されるノードはより高いレベルのみ
+		for i := 0; i < len(s.queue[level]); i++ {
+			node := s.queue[level][i]
+			s.scheduled[node] = false
+			if int(node) < len(s.nands) {
+				n := s.nands[node]
+				s.setWire(n.out, !(s.values[n.a] && s.values[n.b]))
+				continue
+			}
+			d := s.devices[int(node)-len(s.nands)]
+			s.readInputs(d)
+			d.device.Eval(d.inVal, d.outVal)
+			for j, wires := range d.out {
+				s.write(wires, d.outVal[j])
+			}
+		}
+		s.queue[level] = s.queue[level][:0]
+	}
+}
+
+// Tick is rising edge of clock. clocked chips read their inputs.
+func (s *Simulator) Tick() {
+	s.Eval()
+	for i := range s.dffs {
+		s.dffs[i].next = s.values[s.dffs[i].in]
+	}
+	for _, d := range s.devices {
+		if clocked, ok := d.device.(ClockedDevice); ok {
+			s.readInputs(d)
+			clocked.Tick(d.inVal)
+		}
+	}
+}
+
+// Tock is falling edge of clock. clocked chips change their outputs.
+func (s *Simulator) Tock() {
+	for _, d := range s.dffs {
+		s.setWire(d.out, d.next)
+	}
+	for i, d := range s.devices {
+		if clocked, ok := d.device.(ClockedDevice); ok {
+			clocked.Tock()
+			s.schedule(int32(len(s.nands) + i))
+		}
+	}
+	s.Eval()
+}
+
+// Pins returns names of pins and internal pins of chip in sorted order
+func (s *Simulator) Pins() []string {
+	names := []string{}
+	for name := range s.pins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Width returns width of pin
+func (s *Simulator) Width(pin string) (int, bool) {
+	wires, ok := s.pins[pin]
+	return len(wires), ok
+}
+
+// Get returns value of pin or internal pin. bit 0 of pin is LSB.
+func (s *Simulator) Get(pin string) (uint16, error) {
+	wires, ok := s.pins[pin]
+	if !ok {
+		return 0, fmt.Errorf("%s has no pin %s", s.Chip.Name, pin)
+	}
+	return s.read(wires), nil
+}
+
+// Set sets value of input pin. changes are propagated by Eval, Tick or Tock.
+func (s *Simulator) Set(pin string, v uint16) error {
+	if _, isInput, ok := s.Chip.Pin(pin); !ok || !isInput {
+		return fmt.Errorf("%s has no input pin %s", s.Chip.Name, pin)
+	}
+	s.write(s.pins[pin], v)
+	return nil
+}
+
+// Memory returns the first builtin part which has state. e.g. "RAM16K", "ARegister", "ROM32K"
+func (s *Simulator) Memory(name string) (Memory, bool) {
+	for i, d := range s.devices {
+		if m, ok := d.device.(Memory); ok && d.name == name {
+			s.schedule(int32(len(s.nands) + i)) // 状態が書き換えられても次の評価で出力に反映する
+			return m, true
+		}
+	}
+	return nil, false
+}
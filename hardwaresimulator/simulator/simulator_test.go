@@ -0,0 +1,200 @@
+package simulator
+
+import (
+	"hardwaresimulator/hdl"
+	"io/ioutil"
+	"math/rand"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+var hardwareDirs = []string{
+	"../../hardware/bool_gate",
+	"../../hardware/alu",
+}
+
+// newPair returns simulator of chip built from parts in dirs and simulator of builtin chip
+func newPair(t *testing.T, name string, dirs ...string) (*Simulator, *Simulator) {
+	fromParts, err := New(NewLoader(dirs...), name)
+	if err != nil {
+		t.Fatalf("%s: %s", name, err)
+	}
+	if fromParts.Chip.Builtin != "" {
+		t.Fatalf("%s should be built from parts", name)
+	}
+	builtin, err := New(NewLoader(), name)
+	if err != nil {
+		t.Fatalf("%s: %s", name, err)
+	}
+	return fromParts, builtin
+}
+
+func compareOutputs(t *testing.T, fromParts *Simulator, builtin *Simulator, step string) {
+	for _, pin := range builtin.Chip.Out {
+		expected, _ := builtin.Get(pin.Name)
+		actual, err := fromParts.Get(pin.Name)
+		if err != nil {
+			t.Fatalf("%s: %s", fromParts.Chip.Name, err)
+		}
+		if actual != expected {
+			t.Fatalf("%s: %s: %s should be %d. got %d", fromParts.Chip.Name, step, pin.Name, expected, actual)
+		}
+	}
+}
+
+func setRandomInputs(fromParts *Simulator, builtin *Simulator, r *rand.Rand) string {
+	inputs := []string{}
+	for _, pin := range builtin.Chip.In {
+		v := uint16(r.Intn(1 << pin.Width))
+		fromParts.Set(pin.Name, v)
+		builtin.Set(pin.Name, v)
+		inputs = append(inputs, pin.Name+"="+strconv.Itoa(int(v)))
+	}
+	return strings.Join(inputs, ",")
+}
+
+func TestCombinationalChips(t *testing.T) {
+	// DMux4Way, DMux8Way, Mux8Way16 は PARTS が未実装
+	chips := []string{
+		"Not", "And", "Or", "Xor", "Mux", "DMux", "Not16", "And16", "Or16", "Mux16", "Or8Way",
+		"Mux4Way16", "HalfAdder", "FullAdder", "Add16", "Inc16", "ALU",
+	}
+	r := rand.New(rand.NewSource(1))
+	for _, name := range chips {
+		fromParts, builtin := newPair(t, name, hardwareDirs...)
+		for i := 0; i < 200; i++ {
+			inputs := setRandomInputs(fromParts, builtin, r)
+			fromParts.Eval()
+			builtin.Eval()
+			compareOutputs(t, fromParts, builtin, inputs)
+		}
+	}
+}
+
+func TestSequentialChips(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, name := range []string{"Bit", "Register", "PC", "RAM8", "RAM64"} {
+		// 他のディレクトリのチップは組み込みのチップを使う
+		fromParts, builtin := newPair(t, name, "../../hardware/sequential_circuit")
+		for i := 0; i < 300; i++ {
+			inputs := setRandomInputs(fromParts, builtin, r)
+			fromParts.Tick()
+			builtin.Tick()
+			compareOutputs(t, fromParts, builtin, "tick "+inputs)
+			fromParts.Tock()
+			builtin.Tock()
+			compareOutputs(t, fromParts, builtin, "tock "+inputs)
+		}
+	}
+}
+
+func TestComputer(t *testing.T) {
+	// CPUはNandから組み立て、RAM16KとROM32Kは組み込みのチップを使う
+	s, err := New(NewLoader("../../hardware/computer", "../../hardware/alu", "../../hardware/bool_gate"), "Computer")
+	if err != nil {
+		t.Fatalf("New() returned error: %s", err)
+	}
+	hack, err := ioutil.ReadFile("../../hardware/computer/Max.hack")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rom, ok := s.Memory("ROM32K")
+	if !ok {
+		t.Fatalf("Computer should have ROM32K")
+	}
+	for i, line := range strings.Fields(string(hack)) {
+		instruction, _ := strconv.ParseUint(line, 2, 16)
+		rom.Set(i, uint16(instruction))
+	}
+	ram, ok := s.Memory("RAM16K")
+	if !ok {
+		t.Fatalf("Computer should have RAM16K")
+	}
+	ram.Set(0, 3)
+	ram.Set(1, 5)
+	s.Eval()
+	for i := 0; i < 14; i++ {
+		s.Tick()
+		s.Tock()
+	}
+	if ram.Get(2) != 5 {
+		t.Errorf("RAM16K[2] should be 5. got %d", ram.Get(2))
+	}
+	pc, _ := s.Memory("PC")
+	if pc.Get(0) != 14 {
+		t.Errorf("PC should be 14. got %d", pc.Get(0))
+	}
+}
+
+func TestLoad(t *testing.T) {
+	s, err := Load("../../hardware/sequential_circuit/Bit.hdl")
+	if err != nil {
+		t.Fatalf("Load() returned error: %s", err)
+	}
+	if len(s.devices) != 1 || len(s.dffs) != 1 {
+		t.Fatalf("Bit should have Mux and DFF. got %d devices and %d DFFs", len(s.devices), len(s.dffs))
+	}
+	s.Set("in", 1)
+	s.Set("load", 1)
+	s.Tick()
+	if out, _ := s.Get("out"); out != 0 {
+		t.Errorf("out should be 0 before tock. got %d", out)
+	}
+	s.Tock()
+	if out, _ := s.Get("out"); out != 1 {
+		t.Errorf("out should be 1 after tock. got %d", out)
+	}
+	if fb, _ := s.Get("fb"); fb != 1 {
+		t.Errorf("internal pin fb should be 1. got %d", fb)
+	}
+}
+
+func TestBuildErrors(t *testing.T) {
+	testCases := []struct {
+		hdl           string
+		expectedError string
+	}{
+		{"CHIP X { IN a; OUT out; PARTS: Foo(in=a, out=out); }", "X line 1: chip Foo is not found"},
+		{"CHIP X { IN a; OUT out; PARTS: Not(x=a, out=out); }", "X line 1: Not has no pin x"},
+		{"CHIP X { IN a[2]; OUT out; PARTS: Not(in=a, out=out); }", "X line 1: width of a is 2. but 1 is expected"},
+		{"CHIP X { IN a; OUT out; PARTS: Not16(in[16]=a, out[0]=out); }", "X line 1: in[16] is out of range of in[16]"},
+		{"CHIP X { IN a; OUT out; PARTS: Not(in=a, out=a); }", "X line 1: input pin a can not be connected to output of part"},
+		{"CHIP X { IN a; OUT out; PARTS: Not(in=a, out=out); Not(in=out, out=b); }", "X line 1: output pin out can not be connected to input of part"},
+		{"CHIP X { IN a; OUT out; PARTS: Not(in=a, out=out); Not(in=a, out=out); }", "X line 1: out is driven by multiple parts"},
+		{"CHIP X { IN a; OUT out; PARTS: Not(in=a, out=true); }", "X line 1: output can not be connected to true"},
+		{"CHIP X { IN a; OUT out; PARTS: Not(in=a, out=b); Not(in=b[0], out=out); }", "X line 1: internal pin b[0] can not have sub bus"},
+		{"CHIP X { IN a; OUT out; PARTS: Not(in=a, in=a, out=out); }", "X line 1: in of Not is connected twice"},
+		{"CHIP X { IN a; OUT out; PARTS: Or(a=a, b=c, out=c); Not(in=c, out=out); }", "X has combinational loop"},
+		{"CHIP X { IN a; OUT out; PARTS: X(a=a, out=out); }", "chip X uses itself"},
+	}
+	for _, tt := range testCases {
+		chip, err := parseTestChip(tt.hdl)
+		if err != nil {
+			t.Fatalf("failed to parse %q: %s", tt.hdl, err)
+		}
+		loader := NewLoader()
+		loader.chips["X"] = chip
+		_, err = New(loader, "X")
+		if err == nil || err.Error() != tt.expectedError {
+			t.Errorf("New(%q) should return error %q. got %v", tt.hdl, tt.expectedError, err)
+		}
+	}
+}
+
+func parseTestChip(input string) (*hdl.Chip, error) {
+	return hdl.Parse("X.hdl", input)
+}
+
+func TestLoadAllChips(t *testing.T) {
+	hdlFilenames, _ := filepath.Glob("../../hardware/*/*.hdl")
+	if len(hdlFilenames) == 0 {
+		t.Fatalf("no .hdl file is found")
+	}
+	for _, hdlFilename := range hdlFilenames {
+		if _, err := Load(hdlFilename); err != nil {
+			t.Errorf("Load(%s) returned error: %s", hdlFilename, err)
+		}
+	}
+}
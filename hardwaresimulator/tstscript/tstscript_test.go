@@ -0,0 +1,81 @@
+package tstscript
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// unimplementedChips are exercise chips whose PARTS are still empty in hardware/.
+// their scripts always fail until they are implemented, so they are skipped.
+var unimplementedChips = map[string]bool{"DMux4Way.tst": true, "DMux8Way.tst": true, "Mux8Way16.tst": true}
+
+func TestRunHardwareScripts(t *testing.T) {
+	for _, dir := range []string{"bool_gate", "alu", "sequential_circuit", "computer"} {
+		tstFilenames, _ := filepath.Glob(filepath.Join("../../hardware", dir, "*.tst"))
+		if len(tstFilenames) == 0 {
+			t.Fatalf("no .tst file in %s", dir)
+		}
+		for _, tstFilename := range tstFilenames {
+			if unimplementedChips[filepath.Base(tstFilename)] {
+				continue
+			}
+			// Memory.tst はキーボードのキーを押すように echo で指示する
+			if _, err := RunFile(tstFilename, false, PressRequestedKey); err != nil {
+				t.Errorf("%s: %s", tstFilename, err)
+			}
+		}
+	}
+}
+
+func TestRunOutput(t *testing.T) {
+	script := `
+load Xor.hdl,
+output-list a%B1.1.1 b%X2.1.2 out%D1.3.1 notb%S1.2.1;
+set a %B1, set b 0, eval, output;
+/* 内部ピンも出力できる */
+set b 1, eval, output;
+`
+	expected := "| a |  b  | out |notb|\r\n| 1 |  0  |   1 | 1  |\r\n| 1 |  1  |   0 | 0  |\r\n"
+	r := NewRunner("../../hardware/bool_gate")
+	if err := r.Run(script); err != nil {
+		t.Fatalf("Run() returned error: %s", err)
+	}
+	if r.Output() != expected {
+		t.Fatalf("Output() should be %q. got %q", expected, r.Output())
+	}
+}
+
+func TestComparisonError(t *testing.T) {
+	// And.cmp と異なる出力列で比較する
+	script := "load And.hdl, compare-to And.cmp, output-list a%B3.1.3 b%B3.1.3 out%B3.1.3;" +
+		"set a 1, set b 1, eval, output;"
+	err := NewRunner("../../hardware/bool_gate").Run(script)
+	cmpErr, ok := err.(*ComparisonError)
+	if !ok {
+		t.Fatalf("Run() should return ComparisonError. got %v", err)
+	}
+	if cmpErr.Line != 2 || cmpErr.Actual != "|   1   |   1   |   1   |" {
+		t.Errorf("unexpected ComparisonError: %s", cmpErr)
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	testCases := []struct {
+		script        string
+		expectedError string
+	}{
+		{"set a 1;", `line 1: set is executed before chip is loaded`},
+		{"load And.hdl,\nset x 1;", `line 2: And has no pin x`},
+		{"load And.hdl, set a 2;", `line 1: 2 is out of range of a[1]`},
+		{"load And.hdl, set RAM16K[0] 1;", `line 1: And has no builtin part RAM16K`},
+		{"load Foo.hdl;", `line 1: chip Foo is not found`},
+		{"load And.hdl, ROM32K load Max.hack;", `line 1: And has no ROM32K`},
+		{"load And.hdl, vmstep;", `line 1: unknown command "vmstep"`},
+	}
+	for _, tt := range testCases {
+		err := NewRunner("../../hardware/bool_gate").Run(tt.script)
+		if err == nil || err.Error() != tt.expectedError {
+			t.Errorf("Run(%q) should return error %q. got %v", tt.script, tt.expectedError, err)
+		}
+	}
+}
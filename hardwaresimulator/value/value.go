@@ -0,0 +1,5 @@
+package value
+
+var LF = "\n"
+var CR = "\r"
+var NEW_LINE = CR + LF
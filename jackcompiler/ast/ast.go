@@ -67,6 +67,7 @@ type ClassStatement struct {
 	Name              token.Token
 	ClassVarDecList   []ClassVarDecStatement
 	SubroutineDecList []SubroutineDecStatement
+	DocComment        string // "/** ... */" placed before "class"
 }
 
 func (cs *ClassStatement) statementNode() {}
@@ -111,6 +112,7 @@ type SubroutineDecStatement struct {
 	Name           token.Token // IDENTIFIER
 	ParameterList  *ParameterListStatement
 	SubroutineBody *SubroutineBodyStatement
+	DocComment     string // "/** ... */" placed before subroutine declaration
 }
 
 func (sds *SubroutineDecStatement) statementNode() {}
@@ -583,9 +585,11 @@ func (pe *SingleExpression) Xml() string {
 	return out.String()
 }
 
+// InfixExpression is Ast of "term (op term)*".
+// "a + b + c" is nested to left as ((a + b) + c), because Jack evaluates operators from left to right.
 type InfixExpression struct {
 	Token    token.Token // 式の最初のトークン
-	Left     Term
+	Left     Expression  // SingleExpression or InfixExpression
 	Operator token.Token
 	Right    Term
 }
@@ -601,7 +605,20 @@ func (ie *InfixExpression) String() string {
 func (ie *InfixExpression) Xml() string {
 	var out bytes.Buffer
 	out.WriteString("<expression>")
-	out.WriteString(ie.Left.Xml())
+	out.WriteString(ie.termsXml())
+	out.WriteString("</expression>")
+	return out.String()
+}
+
+// termsXml returns "term op term op term ..." flatly. it is not wrapped by <expression>.
+func (ie *InfixExpression) termsXml() string {
+	var out bytes.Buffer
+	switch left := ie.Left.(type) {
+	case *InfixExpression:
+		out.WriteString(left.termsXml())
+	case *SingleExpression:
+		out.WriteString(left.Value.Xml())
+	}
 	switch token.Symbol(ie.Operator.String()) {
 	case token.LT:
 		out.WriteString(symbolXml("&lt;"))
@@ -613,7 +630,6 @@ func (ie *InfixExpression) Xml() string {
 		out.WriteString(symbolXml(ie.Operator.Literal))
 	}
 	out.WriteString(ie.Right.Xml())
-	out.WriteString("</expression>")
 	return out.String()
 }
 
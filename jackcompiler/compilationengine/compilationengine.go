@@ -7,22 +7,56 @@ import (
 	"jackcompiler/symboltable"
 	"jackcompiler/token"
 	"jackcompiler/vmwriter"
+	"sort"
+	"strings"
 )
 
 // CompilationEngine is struct
 type CompilationEngine struct {
 	*vmwriter.VMWriter
 	*symboltable.SymbolTable
-	ClassName string
-	labelFlag int
+	ClassName     string
+	labelFlag     int
+	index         *symboltable.ProgramIndex // nil if classes in program are unknown
+	externalCalls map[string]bool
 }
 
 // New is initializer of compilation engine
 func New(className string, vm *vmwriter.VMWriter, st *symboltable.SymbolTable) *CompilationEngine {
-	ce := &CompilationEngine{VMWriter: vm, SymbolTable: st, ClassName: className, labelFlag: 0}
+	ce := &CompilationEngine{VMWriter: vm, SymbolTable: st, ClassName: className, labelFlag: 0, externalCalls: map[string]bool{}}
 	return ce
 }
 
+// NewWithIndex is initializer of compilation engine which refers index of all classes in program.
+// kind of called subroutine and size of object are resolved with index.
+func NewWithIndex(className string, vm *vmwriter.VMWriter, st *symboltable.SymbolTable, index *symboltable.ProgramIndex) *CompilationEngine {
+	ce := New(className, vm, st)
+	ce.index = index
+	return ce
+}
+
+// ExternalCalls returns subroutines which are called but not defined in program (e.g. OS) in alphabetical order.
+// It is available only when compilation engine is initialized with index.
+func (ce *CompilationEngine) ExternalCalls() []string {
+	externalCalls := []string{}
+	for name := range ce.externalCalls {
+		externalCalls = append(externalCalls, name)
+	}
+	sort.Strings(externalCalls)
+	return externalCalls
+}
+
+// WriteCall writes call command and records it if callee is defined outside of program.
+func (ce *CompilationEngine) WriteCall(name string, nArgs int) {
+	if ce.index != nil {
+		className := strings.SplitN(name, ".", 2)[0]
+		if classSignature, ok := ce.index.Class(className); !ok || classSignature.External {
+			ce.externalCalls[name] = true
+		}
+	}
+	ce.VMWriter.WriteCall(name, nArgs)
+}
+
 func (ce *CompilationEngine) CompileProgram(programAst *ast.Program) {
 	for _, stmtAst := range programAst.Statements {
 		ce.CompileStatement(stmtAst)
@@ -105,6 +139,11 @@ func (ce *CompilationEngine) CompileSubroutineDecConstructorStatement(subroutine
 
 	// フィールド変数分だけ、メモリを確保し → thisポインタにオブジェクトの先頭アドレスを格納する。
 	fieldVarCount := ce.VarCount(symboltable.FIELD)
+	if ce.index != nil {
+		if classSignature, ok := ce.index.Class(ce.ClassName); ok {
+			fieldVarCount = classSignature.FieldCount()
+		}
+	}
 	ce.WritePush(vmwriter.CONST, fieldVarCount)
 	ce.WriteCall("Memory.alloc", 1)
 	ce.WritePop(vmwriter.LOCAL, 0)
@@ -258,7 +297,8 @@ func (ce *CompilationEngine) CompileSingleExpression(singleExpressionAst *ast.Si
 }
 
 func (ce *CompilationEngine) CompileInfixExpression(infixExpressionAst *ast.InfixExpression) error {
-	ce.CompileTerm(infixExpressionAst.Left)
+	// 左の式を先に評価する。(a+b)+c のように左側にネストしている。
+	ce.CompileExpression(infixExpressionAst.Left)
 	ce.CompileTerm(infixExpressionAst.Right)
 	switch token.Symbol(infixExpressionAst.Operator.Literal) {
 	case token.PLUS:
@@ -340,6 +380,12 @@ func (ce *CompilationEngine) CompileIntergerConstTerm(intergerConstTerm *ast.Int
 func (ce *CompilationEngine) CompileSubroutineCallTerm(subroutineCallTerm *ast.SubroutineCallTerm) error {
 
 	if subroutineCallTerm.ClassName.Literal == "" {
+		// 同じクラスのfunction,constructorの呼び出しであれば、thisをpushしない
+		if subroutine, ok := ce.lookupSubroutine(ce.ClassName, subroutineCallTerm.SubroutineName.Literal); ok && subroutine.Kind != symboltable.METHOD {
+			ce.CompileExpressionListStatement(subroutineCallTerm.ExpressionListStmt)
+			ce.WriteCall(fmt.Sprintf("%s.%s", ce.ClassName, subroutineCallTerm.SubroutineName.String()), len(subroutineCallTerm.ExpressionListStmt.ExpressionList))
+			return nil
+		}
 		thisVarKind := ce.KindOf("this")
 		thisIndexOf := ce.IndexOf(string(token.THIS))
 		switch thisVarKind {
@@ -348,7 +394,8 @@ func (ce *CompilationEngine) CompileSubroutineCallTerm(subroutineCallTerm *ast.S
 		case symboltable.VAR:
 			ce.WritePush(vmwriter.LOCAL, thisIndexOf)
 		default:
-			return nil // TODO:Error,fmt.Errorf("Identifier ...")
+			// functionからはthisがないのでmethodを呼び出せない。callを書かないのでエラーを返す
+			return fmt.Errorf("method %s.%s cannot be called without object", ce.ClassName, subroutineCallTerm.SubroutineName.Literal)
 		}
 		ce.CompileExpressionListStatement(subroutineCallTerm.ExpressionListStmt)
 		ce.WriteCall(fmt.Sprintf("%s.%s", ce.ClassName, subroutineCallTerm.SubroutineName.String()), len(subroutineCallTerm.ExpressionListStmt.ExpressionList)+1)
@@ -367,6 +414,14 @@ func (ce *CompilationEngine) CompileSubroutineCallTerm(subroutineCallTerm *ast.S
 	return nil
 }
 
+// lookupSubroutine returns signature of subroutine in index. ok is false if index is not given or subroutine is not found.
+func (ce *CompilationEngine) lookupSubroutine(className string, subroutineName string) (symboltable.SubroutineSignature, bool) {
+	if ce.index == nil {
+		return symboltable.SubroutineSignature{}, false
+	}
+	return ce.index.Subroutine(className, subroutineName)
+}
+
 func (ce *CompilationEngine) CompileBracketTerm(bracketTerm *ast.BracketTerm) error {
 	return ce.CompileExpression(bracketTerm.Value)
 }
@@ -487,34 +542,16 @@ func (ce *CompilationEngine) CompileKeywordConstTerm(keywordConstTerm *ast.Keywo
 }
 
 func (ce *CompilationEngine) CompileDoStatement(doStatement *ast.DoStatement) error {
-	if doStatement.ClassName.Literal == "" {
-		thisVarKind := ce.KindOf("this")
-		thisIndexOf := ce.IndexOf(string(token.THIS))
-		switch thisVarKind {
-		case symboltable.ARGUMENT:
-			ce.WritePush(vmwriter.ARG, thisIndexOf)
-		case symboltable.VAR:
-			ce.WritePush(vmwriter.LOCAL, thisIndexOf)
-		default:
-			return nil // TODO:Error,fmt.Errorf("Identifier ...")
-		}
-		ce.CompileExpressionListStatement(doStatement.ExpressionListStmt)
-		ce.WriteCall(fmt.Sprintf("%s.%s", ce.ClassName, doStatement.SubroutineName.String()), len(doStatement.ExpressionListStmt.ExpressionList)+1)
-		ce.WritePop(vmwriter.TEMP, 0)
-		return nil
-	}
-	if ce.IndexOf(doStatement.ClassName.Literal) != -1 {
-		typeOf := ce.TypeOf(doStatement.ClassName.Literal)
-		ce.CompileIdentifierTerm(&ast.IdentifierTerm{Token: doStatement.ClassName, Value: doStatement.ClassName.Literal}) // インスタンスのアドレスをpushする。
-		ce.CompileExpressionListStatement(doStatement.ExpressionListStmt)
-		ce.WriteCall(fmt.Sprintf("%s.%s", typeOf, doStatement.SubroutineName.Literal), len(doStatement.ExpressionListStmt.ExpressionList)+1)
-		ce.WritePop(vmwriter.TEMP, 0)
-		return nil
-	}
-
-	ce.CompileExpressionListStatement(doStatement.ExpressionListStmt)
-	ce.WriteCall(fmt.Sprintf("%s.%s", doStatement.ClassName.Literal, doStatement.SubroutineName.String()), len(doStatement.ExpressionListStmt.ExpressionList))
-	ce.WritePop(vmwriter.TEMP, 0)
+	err := ce.CompileSubroutineCallTerm(&ast.SubroutineCallTerm{
+		Token:              doStatement.SubroutineName,
+		ClassName:          doStatement.ClassName,
+		SubroutineName:     doStatement.SubroutineName,
+		ExpressionListStmt: doStatement.ExpressionListStmt,
+	})
+	if err != nil {
+		return err
+	}
+	ce.WritePop(vmwriter.TEMP, 0) // 戻り値は使わないので捨てる
 	return nil
 }
 
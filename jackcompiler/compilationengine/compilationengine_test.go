@@ -7,6 +7,7 @@ import (
 	"jackcompiler/tokenizer"
 	"jackcompiler/value"
 	"jackcompiler/vmwriter"
+	"reflect"
 	"testing"
 )
 
@@ -37,6 +38,8 @@ func TestExpression(t *testing.T) {
 		{"4 < 2", "push constant 4" + value.NEW_LINE + "push constant 2" + value.NEW_LINE + "lt" + value.NEW_LINE},
 		{"4 / 2", "push constant 4" + value.NEW_LINE + "push constant 2" + value.NEW_LINE + "call Math.divide 2" + value.NEW_LINE},
 		{"4 * 3", "push constant 4" + value.NEW_LINE + "push constant 3" + value.NEW_LINE + "call Math.multiply 2" + value.NEW_LINE},
+		{"1 + 2 + 3", "push constant 1" + value.NEW_LINE + "push constant 2" + value.NEW_LINE + "add" + value.NEW_LINE + "push constant 3" + value.NEW_LINE + "add" + value.NEW_LINE},
+		{"8 - 2 * 3", "push constant 8" + value.NEW_LINE + "push constant 2" + value.NEW_LINE + "sub" + value.NEW_LINE + "push constant 3" + value.NEW_LINE + "call Math.multiply 2" + value.NEW_LINE},
 		{"(2+3)*(5+4)", "push constant 2" + value.NEW_LINE + "push constant 3" + value.NEW_LINE + "add" + value.NEW_LINE + "push constant 5" + value.NEW_LINE + "push constant 4" + value.NEW_LINE + "add" + value.NEW_LINE + "call Math.multiply 2" + value.NEW_LINE},
 	}
 	for _, tt := range testCases {
@@ -219,6 +222,18 @@ func TestDoStatement(t *testing.T) {
 	}
 }
 
+func TestDoStatementWithoutObject(t *testing.T) {
+	// thisのないfunctionからのmethod呼び出しはcallを書けないので、pop temp 0も書かない
+	p := newParser("do run();")
+	ce := newCompilationEngine("Main")
+	if err := ce.CompileDoStatement(p.ParseDoStatement()); err == nil {
+		t.Fatalf("CompileDoStatement() should return error for method call without object")
+	}
+	if len(ce.VMCode) != 0 {
+		t.Fatalf("no VMCode should be written. got %s", ce.VMCode)
+	}
+}
+
 func TestLetStatement(t *testing.T) {
 	testCases := []struct {
 		input   string
@@ -459,3 +474,35 @@ func TestClassStatement(t *testing.T) {
 		}
 	}
 }
+
+func TestCompileWithProgramIndex(t *testing.T) {
+	input := `class Main {
+    field int x, y;
+    method void draw() { return; }
+    constructor Main new() { do draw(); do reset(); do Output.println(); return this; }
+    function void reset() { return; }
+}`
+	p := newParser(input)
+	program, _ := p.ParseProgram()
+	index := symboltable.NewProgramIndex()
+	index.AddProgram(program)
+	index.AddExternalClass(&symboltable.ClassSignature{Name: "Output", Subroutines: map[string]symboltable.SubroutineSignature{}})
+	ce := NewWithIndex("Main", vmwriter.New("test.vm", 0644), symboltable.New(), index)
+	ce.CompileProgram(program)
+	vmCode := "function Main.draw 0" + value.NEW_LINE + "push constant 0" + value.NEW_LINE + "return" + value.NEW_LINE +
+		// フィールド変数の数は索引から求められる
+		"function Main.new 1" + value.NEW_LINE + "push constant 2" + value.NEW_LINE + "call Memory.alloc 1" + value.NEW_LINE + "pop local 0" + value.NEW_LINE +
+		// methodの呼び出しではthisをpushし、functionの呼び出しではpushしない
+		"push local 0" + value.NEW_LINE + "call Main.draw 1" + value.NEW_LINE + "pop temp 0" + value.NEW_LINE +
+		"call Main.reset 0" + value.NEW_LINE + "pop temp 0" + value.NEW_LINE +
+		"call Output.println 0" + value.NEW_LINE + "pop temp 0" + value.NEW_LINE +
+		"push local 0" + value.NEW_LINE + "return" + value.NEW_LINE +
+		"function Main.reset 0" + value.NEW_LINE + "push constant 0" + value.NEW_LINE + "return" + value.NEW_LINE
+	if !bytes.Equal([]byte(vmCode), ce.VMCode) {
+		t.Fatalf("VMCode should be %s, got %s", vmCode, ce.VMCode)
+	}
+	expectedExternalCalls := []string{"Memory.alloc", "Output.println"}
+	if !reflect.DeepEqual(ce.ExternalCalls(), expectedExternalCalls) {
+		t.Errorf("ce.ExternalCalls() should be %v. got %v", expectedExternalCalls, ce.ExternalCalls())
+	}
+}
@@ -9,11 +9,23 @@ import (
 	"jackcompiler/parser"
 	"jackcompiler/symboltable"
 	"jackcompiler/tokenizer"
+	"jackcompiler/typechecker"
 	"jackcompiler/vmwriter"
+	"jackcompiler/xmlwriter"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 )
 
+// jackSource is jack file which is parsed successfully
+type jackSource struct {
+	filename   string
+	jackCode   string
+	programAst *ast.Program
+	classStmt  *ast.ClassStatement
+}
+
 func getJackFileListInDir(dirPath string) ([]string, error) {
 	vmPathPattern := filepath.Join(dirPath, "*.jack")
 	vmFileListInDir, err := filepath.Glob(vmPathPattern)
@@ -23,41 +35,233 @@ func getJackFileListInDir(dirPath string) ([]string, error) {
 	return vmFileListInDir, nil
 }
 
-func main() {
-	flag.Parse()
-	pathToJack := flag.Arg(0)
+// getJackFileList returns jack file passed by argument or jack files in the dir passed by argument
+func getJackFileList(pathToJack string) ([]string, error) {
+	fileInfo, err := os.Stat(pathToJack)
+	if err != nil {
+		return []string{}, err
+	}
+	if !fileInfo.IsDir() {
+		return []string{pathToJack}, nil
+	}
+	jackFileList, err := getJackFileListInDir(pathToJack)
+	if err != nil {
+		return []string{}, err
+	}
+	if len(jackFileList) == 0 {
+		return []string{}, fmt.Errorf("no .jack file in %s", pathToJack)
+	}
+	return jackFileList, nil
+}
 
-	jackFileList := []string{}
+// parseJackFile parses jack file. errors are printed to stderr and ok is false if the file has any error.
+func parseJackFile(jackFilename string) (*jackSource, bool) {
+	jackCode, err := ioutil.ReadFile(jackFilename)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return nil, false
+	}
+	jt := tokenizer.NewWithFilename(jackFilename, string(jackCode))
+	p := parser.New(jt)
+	programAst, parseErrors := p.ParseProgram()
+	if len(parseErrors) > 0 {
+		for _, parseError := range parseErrors {
+			fmt.Fprintln(os.Stderr, parseError.Error())
+		}
+		return nil, false
+	}
+	if len(programAst.Statements) == 0 {
+		fmt.Fprintf(os.Stderr, "no class is declared in %s\n", jackFilename)
+		return nil, false
+	}
+	classStmt, ok := programAst.Statements[0].(*ast.ClassStatement)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Statement[0] should be ClassStatement, but got %T in %s\n", programAst.Statements[0], jackFilename)
+		return nil, false
+	}
+	// Jack OSなどは<クラス名>.vmを参照するので、ファイル名とクラス名は一致させるべき
+	if baseName := strings.TrimSuffix(filepath.Base(jackFilename), filepath.Ext(jackFilename)); baseName != classStmt.Name.Literal {
+		fmt.Fprintf(os.Stderr, "warning: class %s is declared in %s. file name should be %s.jack\n", classStmt.Name.Literal, jackFilename, classStmt.Name.Literal)
+	}
+	return &jackSource{filename: jackFilename, jackCode: string(jackCode), programAst: programAst, classStmt: classStmt}, true
+}
 
-	fileInfo, _ := os.Stat(pathToJack)
-	if fileInfo.IsDir() {
-		jackFileListInDir, err := getJackFileListInDir(pathToJack)
+// addSiblingClasses adds classes in the same dir as jackFilename to index.
+// they are referred but not compiled when single jack file is compiled. files which have syntax error are ignored.
+func addSiblingClasses(index *symboltable.ProgramIndex, jackFilename string) {
+	siblingFileList, err := getJackFileListInDir(filepath.Dir(jackFilename))
+	if err != nil {
+		return
+	}
+	for _, siblingFilename := range siblingFileList {
+		jackCode, err := ioutil.ReadFile(siblingFilename)
 		if err != nil {
-			panic(err)
+			continue
+		}
+		p := parser.New(tokenizer.NewWithFilename(siblingFilename, string(jackCode)))
+		if programAst, parseErrors := p.ParseProgram(); len(parseErrors) == 0 {
+			index.AddProgram(programAst)
 		}
-		jackFileList = jackFileListInDir
-	} else {
-		jackFileList = []string{pathToJack}
 	}
+}
 
-	for _, jackFilename := range jackFileList {
-		jackCode, err := ioutil.ReadFile(jackFilename)
+// outputFilename returns path of <Class><suffix> (e.g. Main.vm, MainT.xml). it is placed next to jack file if outputDir is empty.
+func outputFilename(source *jackSource, outputDir string, suffix string) string {
+	if outputDir == "" {
+		outputDir = filepath.Dir(source.filename)
+	}
+	return filepath.Join(outputDir, source.classStmt.Name.Literal+suffix)
+}
+
+// writeXml writes <Class>T.xml(tokens) and <Class>.xml(parse tree) of chapter 10.
+// if compareDir is not empty, they are compared with the files which have same name in compareDir.
+func writeXml(source *jackSource, outputDir string, toStdout bool, compareDir string) bool {
+	ok := true
+	outputs := []struct {
+		suffix string
+		xml    string
+	}{
+		{"T.xml", xmlwriter.TokensXml(tokenizer.NewWithFilename(source.filename, source.jackCode))},
+		{".xml", xmlwriter.Format(source.programAst.Xml())},
+	}
+	for _, output := range outputs {
+		filename := outputFilename(source, outputDir, output.suffix)
+		if toStdout {
+			fmt.Print(output.xml)
+		} else if err := ioutil.WriteFile(filename, []byte(output.xml), 0644); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			ok = false
+			continue
+		}
+		if compareDir == "" {
+			continue
+		}
+		expectedFilename := filepath.Join(compareDir, filepath.Base(filename))
+		expected, err := ioutil.ReadFile(expectedFilename)
 		if err != nil {
-			panic(err)
+			fmt.Fprintln(os.Stderr, err)
+			ok = false
+			continue
 		}
+		if err := xmlwriter.Compare(output.xml, string(expected)); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: comparison failure: %s\n", expectedFilename, err)
+			ok = false
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "%s: comparison ended successfully\n", expectedFilename)
+	}
+	return ok
+}
+
+func main() {
+	outputDir := flag.String("o", "", "output dir of .vm files. .vm files are placed next to .jack files by default")
+	toStdout := flag.Bool("stdout", false, "write vm code of all classes to standard output instead of files")
+	xmlMode := flag.Bool("xml", false, "write <Class>T.xml(tokens) and <Class>.xml(parse tree) instead of .vm")
+	compareDir := flag.String("compare", "", "with -xml, compare xml with the reference files in the dir ignoring whitespace")
+	showExternals := flag.Bool("externals", false, "list subroutines which are called but not defined in program (e.g. OS)")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [options] {path to Jack File or Dir}\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	jackFileList, err := getJackFileList(flag.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
 
-		jt := tokenizer.New(string(jackCode))
-		parser := parser.New(jt)
-		programAst := parser.ParseProgram()
-		classStmt, ok := programAst.Statements[0].(*ast.ClassStatement)
+	hasError := false
+	sources := []*jackSource{}
+	for _, jackFilename := range jackFileList {
+		source, ok := parseJackFile(jackFilename)
 		if !ok {
-			panic(fmt.Sprintf("Statement[0] should be ClassStatement, but got %T", classStmt))
+			hasError = true
+			continue
+		}
+		sources = append(sources, source)
+	}
+	if hasError {
+		os.Exit(1)
+	}
+
+	if *outputDir != "" && !*toStdout {
+		if err := os.MkdirAll(*outputDir, 0755); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+
+	// 構文解析の結果のみを出力する(10章)
+	if *xmlMode {
+		for _, source := range sources {
+			if !writeXml(source, *outputDir, *toStdout, *compareDir) {
+				hasError = true
+			}
 		}
-		className := classStmt.Name.Literal
-		vm := vmwriter.New(fmt.Sprintf("vm/program/%s.vm", className), 0644)
+		if hasError {
+			os.Exit(1)
+		}
+		return
+	}
+
+	// 全クラスを解析してから、ディレクトリ内の全クラスとOSのクラスの索引を作る
+	index := symboltable.NewProgramIndex()
+	if len(jackFileList) == 1 {
+		addSiblingClasses(index, jackFileList[0])
+	}
+	for _, source := range sources {
+		index.AddProgram(source.programAst)
+	}
+	typechecker.AddOSClasses(index)
+	for _, source := range sources {
+		tc := typechecker.New(index)
+		for _, typeError := range tc.CheckProgram(source.programAst) {
+			fmt.Fprintln(os.Stderr, typeError.Error())
+			hasError = true
+		}
+	}
+	if hasError {
+		os.Exit(1)
+	}
+
+	externalCalls := map[string]bool{}
+	for _, source := range sources {
+		className := source.classStmt.Name.Literal
+		vm := vmwriter.New(outputFilename(source, *outputDir, ".vm"), 0644)
 		st := symboltable.New()
-		ce := compilationengine.New(className, vm, st)
-		ce.CompileProgram(programAst)
-		ce.Close()
+		ce := compilationengine.NewWithIndex(className, vm, st, index)
+		ce.CompileProgram(source.programAst)
+		if *toStdout {
+			os.Stdout.Write(ce.VMCode)
+		} else if err := ce.Close(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			hasError = true
+		}
+		for _, externalCall := range ce.ExternalCalls() {
+			externalCalls[externalCall] = true
+		}
+	}
+	if *showExternals {
+		names := []string{}
+		for name := range externalCalls {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		// vmコードを標準出力に書き出している場合は、混ざらないように標準エラーに出力する
+		out := os.Stdout
+		if *toStdout {
+			out = os.Stderr
+		}
+		for _, name := range names {
+			fmt.Fprintln(out, name)
+		}
+	}
+	if hasError {
+		os.Exit(1)
 	}
 }
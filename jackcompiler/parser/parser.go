@@ -8,183 +8,251 @@ import (
 	"strconv"
 )
 
+// ErrorType is type of ParseError
+type ErrorType string
+
+const (
+	ILLEGAL_TOKEN    ErrorType = "ILLEGAL_TOKEN"    // tokenizer could not read token
+	UNEXPECTED_TOKEN ErrorType = "UNEXPECTED_TOKEN" // token is not allowed by Jack grammar
+	INVALID_CONSTANT ErrorType = "INVALID_CONSTANT" // integer constant is out of range
+)
+
+// ParseError is diagnostic reported by Parser
+type ParseError struct {
+	Type    ErrorType
+	Token   token.Token // token where error is detected
+	Message string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%s at %s", e.Message, e.Token.Position())
+}
+
 // Parser is struct
 type Parser struct {
 	jt        *tokenizer.JackTokenizer
 	curToken  token.Token
 	nextToken token.Token
+	errors    []*ParseError
 }
 
 // New is initializer of compilation engine
 func New(jt *tokenizer.JackTokenizer) *Parser {
-	p := &Parser{jt: jt}
+	p := &Parser{jt: jt, errors: []*ParseError{}}
 	p.advanceToken()
 	p.advanceToken()
 	return p
 }
 
-// ParseProgram is Parser for all program
-func (p *Parser) ParseProgram() *ast.Program {
+// ParseProgram is Parser for all program. errors found while parsing are returned with ast.
+func (p *Parser) ParseProgram() (*ast.Program, []*ParseError) {
 	program := &ast.Program{}
 	program.Statements = []ast.Statement{}
 	for p.curToken.Type != token.EOF {
 		stmt := p.ParseStatement()
 		if stmt != nil {
 			program.Statements = append(program.Statements, stmt)
+		} else {
+			p.synchronize(token.CLASS)
 		}
 		p.advanceToken()
 	}
-	return program
+	return program, p.errors
+}
+
+// Errors returns errors found while parsing
+func (p *Parser) Errors() []*ParseError {
+	return p.errors
 }
 
 func (p *Parser) advanceToken() {
 	p.curToken = p.nextToken
-	p.nextToken, _ = p.jt.Advance()
+	for {
+		nextToken, err := p.jt.Advance()
+		if err == nil {
+			p.nextToken = nextToken
+			return
+		}
+		// tokenizer skips invalid characters. so, read next token again.
+		p.addError(ILLEGAL_TOKEN, nextToken, "%s", err.Error())
+	}
 }
 
 func (p *Parser) ParseStatement() ast.Statement {
 	if p.curToken.Type != token.KEYWORD {
-		panic(fmt.Sprintf("Initial Token Type should be KEYWORD. got %s(%s) ", p.curToken.Type, p.curToken.String()))
+		p.addError(UNEXPECTED_TOKEN, p.curToken, "expected statement but got '%s'", p.curToken.Literal)
+		return nil
 	}
 	return p.ParseKeyWord()
 }
 
+// ParseKeyWord parses statement which starts with keyword.
+// NOTE: nil pointer of each statement is not returned as it is, because it is not nil as ast.Statement.
 func (p *Parser) ParseKeyWord() ast.Statement {
 	keyWord, _ := tokenizer.KeyWord(p.curToken)
 	switch keyWord {
 	case token.LET:
-		return p.ParseLetStatement()
+		if stmt := p.ParseLetStatement(); stmt != nil {
+			return stmt
+		}
 	case token.RETURN:
-		return p.ParseReturnStatement()
+		if stmt := p.ParseReturnStatement(); stmt != nil {
+			return stmt
+		}
 	case token.DO:
-		return p.ParseDoStatement()
+		if stmt := p.ParseDoStatement(); stmt != nil {
+			return stmt
+		}
 	case token.VAR:
-		return p.ParseVarDecStatement()
-	case token.STATIC:
-		return p.ParseClassVarDecStatement()
-	case token.FIELD:
-		return p.ParseClassVarDecStatement()
+		if stmt := p.ParseVarDecStatement(); stmt != nil {
+			return stmt
+		}
+	case token.STATIC, token.FIELD:
+		if stmt := p.ParseClassVarDecStatement(); stmt != nil {
+			return stmt
+		}
 	case token.IF:
-		return p.ParseIfStatement()
+		if stmt := p.ParseIfStatement(); stmt != nil {
+			return stmt
+		}
 	case token.WHILE:
-		return p.ParseWhileStatement()
+		if stmt := p.ParseWhileStatement(); stmt != nil {
+			return stmt
+		}
 	case token.CLASS:
-		return p.ParseClassStatement()
-	case token.METHOD:
-		return p.ParseSubroutineDecStatement()
-	case token.CONSTRUCTOR:
-		return p.ParseSubroutineDecStatement()
-	case token.FUNCTION:
-		return p.ParseSubroutineDecStatement()
+		if stmt := p.ParseClassStatement(); stmt != nil {
+			return stmt
+		}
+	case token.METHOD, token.CONSTRUCTOR, token.FUNCTION:
+		if stmt := p.ParseSubroutineDecStatement(); stmt != nil {
+			return stmt
+		}
 	default:
-		return nil
+		p.addError(UNEXPECTED_TOKEN, p.curToken, "expected statement but got keyword '%s'", p.curToken.Literal)
 	}
+	return nil
 }
 
 func (p *Parser) ParseClassStatement() *ast.ClassStatement {
-	stmt := &ast.ClassStatement{Token: p.curToken}
-	if !p.expectNext(token.IDENTIFIER) {
+	stmt := &ast.ClassStatement{Token: p.curToken, DocComment: p.curToken.DocComment}
+	if !p.expectNext(token.IDENTIFIER, "class name after 'class'") {
 		return nil
 	}
 	stmt.Name = p.curToken
-	p.advanceToken()
-	if token.Symbol(p.curToken.Literal) != token.LBRACE {
+	if !p.expectNextSymbol(token.LBRACE, "after class name") {
 		return nil
 	}
 	p.advanceToken()
 	stmt.ClassVarDecList = []ast.ClassVarDecStatement{}
-	for token.KeyWord(p.curToken.Literal) == token.STATIC || token.KeyWord(p.curToken.Literal) == token.FIELD {
-
+	for p.curKeyWordIs(token.STATIC) || p.curKeyWordIs(token.FIELD) {
 		classVarDec := p.ParseClassVarDecStatement()
-		stmt.ClassVarDecList = append(stmt.ClassVarDecList, *classVarDec)
+		if classVarDec != nil {
+			stmt.ClassVarDecList = append(stmt.ClassVarDecList, *classVarDec)
+		} else {
+			p.synchronize(classMemberKeyWords...)
+		}
 		p.advanceToken()
 	}
 	stmt.SubroutineDecList = []ast.SubroutineDecStatement{}
-	for token.KeyWord(p.curToken.Literal) == token.CONSTRUCTOR || token.KeyWord(p.curToken.Literal) == token.FUNCTION || token.KeyWord(p.curToken.Literal) == token.METHOD {
+	for p.curKeyWordIs(token.CONSTRUCTOR) || p.curKeyWordIs(token.FUNCTION) || p.curKeyWordIs(token.METHOD) {
 		subroutineDec := p.ParseSubroutineDecStatement()
-		stmt.SubroutineDecList = append(stmt.SubroutineDecList, *subroutineDec)
+		if subroutineDec != nil {
+			stmt.SubroutineDecList = append(stmt.SubroutineDecList, *subroutineDec)
+		} else {
+			p.synchronize(classMemberKeyWords...)
+		}
 		p.advanceToken()
 	}
-	if token.Symbol(p.curToken.Literal) != token.RBRACE {
+	if !p.curSymbolIs(token.RBRACE) {
+		p.addError(UNEXPECTED_TOKEN, p.curToken, "expected '}' at end of class %s", stmt.Name.Literal)
 		return nil
 	}
 	return stmt
 }
 
 func (p *Parser) ParseSubroutineDecStatement() *ast.SubroutineDecStatement {
-	stmt := &ast.SubroutineDecStatement{Token: p.curToken}
+	stmt := &ast.SubroutineDecStatement{Token: p.curToken, DocComment: p.curToken.DocComment}
 	if !p.nextTokenIs(token.IDENTIFIER) && !p.nextTokenIs(token.KEYWORD) {
+		p.addError(UNEXPECTED_TOKEN, p.nextToken, "expected return type after '%s'", p.curToken.Literal)
 		return nil
 	}
 	p.advanceToken()
 	stmt.ReturnType = p.curToken
-	if !p.expectNext(token.IDENTIFIER) {
+	if !p.expectNext(token.IDENTIFIER, "subroutine name after return type") {
 		return nil
 	}
 	stmt.Name = p.curToken
-	p.advanceToken()
-	if token.Symbol(p.curToken.Literal) != token.LPAREN {
+	if !p.expectNextSymbol(token.LPAREN, "after subroutine name") {
 		return nil
 	}
 	stmt.ParameterList = p.ParseParameterListStatement()
-	if token.Symbol(p.curToken.Literal) != token.RPAREN {
+	if stmt.ParameterList == nil {
+		return nil
+	}
+	if !p.expectNextSymbol(token.LBRACE, "before subroutine body") {
 		return nil
 	}
-	p.advanceToken()
 	stmt.SubroutineBody = p.ParseSubroutineBodyStatement()
+	if stmt.SubroutineBody == nil {
+		return nil
+	}
 	return stmt
 }
 
 func (p *Parser) ParseSubroutineBodyStatement() *ast.SubroutineBodyStatement {
 	stmt := &ast.SubroutineBodyStatement{Token: p.curToken}
-	if token.Symbol(p.curToken.Literal) != token.LBRACE {
+	if !p.curSymbolIs(token.LBRACE) {
+		p.addError(UNEXPECTED_TOKEN, p.curToken, "expected '{' before subroutine body")
 		return nil
 	}
 	p.advanceToken()
 	stmt.VarDecList = []ast.VarDecStatement{}
-	for token.KeyWord(p.curToken.Literal) == token.VAR {
+	for p.curKeyWordIs(token.VAR) {
 		varDec := p.ParseVarDecStatement()
-		stmt.VarDecList = append(stmt.VarDecList, *varDec)
-		p.advanceToken()
-	}
-	stmt.Statements = []ast.Statement{}
-	for token.Symbol(p.curToken.Literal) != token.RBRACE && !p.curTokenIs(token.EOF) {
-		statement := p.ParseStatement()
-		if statement != nil {
-			stmt.Statements = append(stmt.Statements, statement)
+		if varDec != nil {
+			stmt.VarDecList = append(stmt.VarDecList, *varDec)
+		} else {
+			p.synchronize(statementKeyWords...)
 		}
 		p.advanceToken()
 	}
+	stmt.Statements = p.parseStatements()
+	if !p.curSymbolIs(token.RBRACE) {
+		p.addError(UNEXPECTED_TOKEN, p.curToken, "expected '}' at end of subroutine body")
+		return nil
+	}
 	return stmt
 }
 
 func (p *Parser) ParseLetStatement() *ast.LetStatement {
 	stmt := &ast.LetStatement{Token: p.curToken}
-	if !p.expectNext(token.IDENTIFIER) {
+	if !p.expectNext(token.IDENTIFIER, "variable name after 'let'") {
 		return nil
 	}
 	stmt.Name = p.curToken
-	p.advanceToken()
 
-	if token.Symbol(p.curToken.Literal) == token.LBRACKET {
+	if p.nextSymbolIs(token.LBRACKET) {
 		p.advanceToken()
-		stmt.Idx = p.ParseExpression()
 		p.advanceToken()
-		if token.Symbol(p.curToken.Literal) != token.RBRACKET {
+		stmt.Idx = p.ParseExpression()
+		if stmt.Idx == nil {
+			return nil
+		}
+		if !p.expectNextSymbol(token.RBRACKET, "after array index") {
 			return nil
 		}
-		p.advanceToken()
 	}
 
-	if token.Symbol(p.curToken.Literal) != token.EQ {
+	if !p.expectNextSymbol(token.EQ, "in let statement") {
 		return nil
 	}
-
 	stmt.Symbol = p.curToken
 	p.advanceToken()
 	stmt.Value = p.ParseExpression()
-	p.advanceToken()
-	if token.Symbol(p.curToken.Literal) != token.SEMICOLON {
+	if stmt.Value == nil {
+		return nil
+	}
+	if !p.expectNextSymbol(token.SEMICOLON, "after let statement") {
 		return nil
 	}
 	return stmt
@@ -192,13 +260,16 @@ func (p *Parser) ParseLetStatement() *ast.LetStatement {
 
 func (p *Parser) ParseReturnStatement() *ast.ReturnStatement {
 	stmt := &ast.ReturnStatement{Token: p.curToken}
-	p.advanceToken()
-	if token.Symbol(p.curToken.Literal) == token.SEMICOLON {
+	if p.nextSymbolIs(token.SEMICOLON) {
+		p.advanceToken()
 		return stmt
 	}
-	stmt.Value = p.ParseExpression()
 	p.advanceToken()
-	if token.Symbol(p.curToken.Literal) != token.SEMICOLON {
+	stmt.Value = p.ParseExpression()
+	if stmt.Value == nil {
+		return nil
+	}
+	if !p.expectNextSymbol(token.SEMICOLON, "after return statement") {
 		return nil
 	}
 	return stmt
@@ -206,187 +277,245 @@ func (p *Parser) ParseReturnStatement() *ast.ReturnStatement {
 
 func (p *Parser) ParseDoStatement() *ast.DoStatement {
 	stmt := &ast.DoStatement{Token: p.curToken}
-	p.advanceToken()
-
-	if token.Symbol(p.nextToken.Literal) == token.DOT {
+	if !p.expectNext(token.IDENTIFIER, "subroutine name after 'do'") {
+		return nil
+	}
+	if p.nextSymbolIs(token.DOT) {
 		stmt.ClassName = p.curToken
 		p.advanceToken() // className
-		p.advanceToken() // token.DOT
+		if !p.expectNext(token.IDENTIFIER, "subroutine name after '.'") {
+			return nil
+		}
 	}
 	stmt.SubroutineName = p.curToken
-	p.advanceToken()
-	if token.Symbol(p.curToken.Literal) != token.LPAREN {
+	if !p.expectNextSymbol(token.LPAREN, "after subroutine name") {
 		return nil
 	}
 	stmt.ExpressionListStmt = p.ParseExpressionListStatement()
-	p.advanceToken()
-	if token.Symbol(p.curToken.Literal) != token.SEMICOLON {
+	if stmt.ExpressionListStmt == nil {
+		return nil
+	}
+	if !p.expectNextSymbol(token.SEMICOLON, "after do statement") {
 		return nil
 	}
-
 	return stmt
 }
 
 func (p *Parser) ParseVarDecStatement() *ast.VarDecStatement {
 	stmt := &ast.VarDecStatement{Token: p.curToken, Identifiers: []token.Token{}}
-	if token.KeyWord(p.nextToken.Literal) != token.INT && token.KeyWord(p.nextToken.Literal) != token.BOOLEAN && token.KeyWord(p.nextToken.Literal) != token.CHAR && !p.nextTokenIs(token.IDENTIFIER) {
+	if !p.nextTokenIsType() {
+		p.addError(UNEXPECTED_TOKEN, p.nextToken, "expected type after 'var'")
 		return nil
 	}
 	p.advanceToken()
 	stmt.ValueType = p.curToken
-	for token.Symbol(p.curToken.Literal) != token.SEMICOLON {
-		p.advanceToken()
-		identifier := p.curToken
-		stmt.Identifiers = append(stmt.Identifiers, identifier)
-		p.advanceToken() //
+	identifiers := p.parseIdentifierList("variable declaration")
+	if identifiers == nil {
+		return nil
 	}
+	stmt.Identifiers = identifiers
 	return stmt
 }
 
 func (p *Parser) ParseClassVarDecStatement() *ast.ClassVarDecStatement {
 	stmt := &ast.ClassVarDecStatement{Token: p.curToken, Identifiers: []token.Token{}}
-	p.advanceToken()
-	if token.KeyWord(p.curToken.Literal) != token.INT && token.KeyWord(p.curToken.Literal) != token.BOOLEAN && token.KeyWord(p.curToken.Literal) != token.CHAR && !p.curTokenIs(token.IDENTIFIER) {
+	if !p.nextTokenIsType() {
+		p.addError(UNEXPECTED_TOKEN, p.nextToken, "expected type after '%s'", p.curToken.Literal)
 		return nil
 	}
-	stmt.ValueType = p.curToken
 	p.advanceToken()
+	stmt.ValueType = p.curToken
+	identifiers := p.parseIdentifierList(stmt.Token.Literal + " declaration")
+	if identifiers == nil {
+		return nil
+	}
+	stmt.Identifiers = identifiers
+	return stmt
+}
 
+// parseIdentifierList parses "varName (',' varName)* ';'" of variable declaration.
+func (p *Parser) parseIdentifierList(declaration string) []token.Token {
+	identifiers := []token.Token{}
 	for {
-		identifier := p.curToken
-		stmt.Identifiers = append(stmt.Identifiers, identifier)
-		p.advanceToken()
-		// TODO:refactoring
-		if token.Symbol(p.curToken.Literal) == token.COMMA {
+		if !p.expectNext(token.IDENTIFIER, "variable name in "+declaration) {
+			return nil
+		}
+		identifiers = append(identifiers, p.curToken)
+		if p.nextSymbolIs(token.COMMA) {
 			p.advanceToken()
 			continue
-		} else if token.Symbol(p.curToken.Literal) == token.SEMICOLON {
-			break
-		} else {
+		}
+		if !p.expectNextSymbol(token.SEMICOLON, "after "+declaration) {
 			return nil
 		}
+		return identifiers
 	}
-	if token.Symbol(p.curToken.Literal) != token.SEMICOLON {
-		return nil
-	}
-	return stmt
 }
 
 func (p *Parser) ParseIfStatement() *ast.IfStatement {
 	stmt := &ast.IfStatement{Token: p.curToken}
-	p.advanceToken()
-	if token.Symbol(p.curToken.Literal) != token.LPAREN {
+	if !p.expectNextSymbol(token.LPAREN, "after 'if'") {
 		return nil
 	}
 	p.advanceToken()
 	stmt.Condition = p.ParseExpression()
-	p.advanceToken()
-	if token.Symbol(p.curToken.Literal) != token.RPAREN {
+	if stmt.Condition == nil {
+		return nil
+	}
+	if !p.expectNextSymbol(token.RPAREN, "after if condition") {
+		return nil
+	}
+	if !p.expectNextSymbol(token.LBRACE, "before if block") {
 		return nil
 	}
-	p.advanceToken()
-
 	stmt.Consequence = p.ParseBlockStatement()
-	if token.KeyWord(p.nextToken.Literal) == token.ELSE {
+	if stmt.Consequence == nil {
+		return nil
+	}
+	if p.nextKeyWordIs(token.ELSE) {
 		p.advanceToken() // advance "}" of "if(x){}"
-		p.advanceToken() // advance "else"
-		if token.Symbol(p.curToken.Literal) != token.LBRACE {
+		if !p.expectNextSymbol(token.LBRACE, "after 'else'") {
 			return nil
 		}
 		stmt.Alternative = p.ParseBlockStatement()
+		if stmt.Alternative == nil {
+			return nil
+		}
 	}
 	return stmt
 }
 
 func (p *Parser) ParseWhileStatement() *ast.WhileStatement {
 	stmt := &ast.WhileStatement{Token: p.curToken}
-	if p.expectNext(token.SYMBOL) {
-		if token.Symbol(p.curToken.Literal) != token.LPAREN {
-			return nil
-		}
+	if !p.expectNextSymbol(token.LPAREN, "after 'while'") {
+		return nil
 	}
 	p.advanceToken()
 	stmt.Condition = p.ParseExpression()
-	p.advanceToken()
-	if token.Symbol(p.curToken.Literal) != token.RPAREN {
+	if stmt.Condition == nil {
+		return nil
+	}
+	if !p.expectNextSymbol(token.RPAREN, "after while condition") {
+		return nil
+	}
+	if !p.expectNextSymbol(token.LBRACE, "before while block") {
 		return nil
 	}
-	p.advanceToken()
 	stmt.Statements = p.ParseBlockStatement()
+	if stmt.Statements == nil {
+		return nil
+	}
 	return stmt
 }
 
 func (p *Parser) ParseBlockStatement() *ast.BlockStatement {
 	block := &ast.BlockStatement{Token: p.curToken}
+	if !p.curSymbolIs(token.LBRACE) {
+		p.addError(UNEXPECTED_TOKEN, p.curToken, "expected '{' at start of block")
+		return nil
+	}
 	p.advanceToken()
-	block.Statements = []ast.Statement{}
-	for token.Symbol(p.curToken.Literal) != token.RBRACE && !p.curTokenIs(token.EOF) {
-		stmt := p.ParseStatement()
-		if stmt != nil {
-			block.Statements = append(block.Statements, stmt)
+	block.Statements = p.parseStatements()
+	if !p.curSymbolIs(token.RBRACE) {
+		p.addError(UNEXPECTED_TOKEN, p.curToken, "expected '}' at end of block")
+		return nil
+	}
+	return block
+}
+
+// parseStatements parses statements until "}". statements which have errors are skipped.
+func (p *Parser) parseStatements() []ast.Statement {
+	statements := []ast.Statement{}
+	for !p.curSymbolIs(token.RBRACE) && !p.curTokenIs(token.EOF) {
+		statement := p.ParseStatement()
+		if statement != nil {
+			statements = append(statements, statement)
+		} else {
+			p.synchronize(statementKeyWords...)
 		}
 		p.advanceToken()
 	}
-	return block
+	return statements
 }
 
 func (p *Parser) ParseExpressionListStatement() *ast.ExpressionListStatement {
 	expressionListStmt := &ast.ExpressionListStatement{Token: p.curToken}
-	p.advanceToken()
 	expressionListStmt.ExpressionList = []ast.Expression{}
-	for token.Symbol(p.curToken.Literal) != token.RPAREN {
+	if p.nextSymbolIs(token.RPAREN) {
+		p.advanceToken()
+		return expressionListStmt
+	}
+	p.advanceToken()
+	for {
 		expression := p.ParseExpression()
-		if expression != nil {
-			expressionListStmt.ExpressionList = append(expressionListStmt.ExpressionList, expression)
+		if expression == nil {
+			return nil
 		}
-		p.advanceToken()
-		if token.Symbol(p.curToken.Literal) == token.RPAREN { // ")"の場合はParseを終了
-			break
-		} else if token.Symbol(p.curToken.Literal) == token.COMMA { // ","の場合はまだ式が存在する
+		expressionListStmt.ExpressionList = append(expressionListStmt.ExpressionList, expression)
+		if p.nextSymbolIs(token.COMMA) { // ","の場合はまだ式が存在する
 			p.advanceToken()
-		} else { // TODO: 例外
-			return nil
+			p.advanceToken()
+			continue
+		}
+		if p.nextSymbolIs(token.RPAREN) { // ")"の場合はParseを終了
+			p.advanceToken()
+			return expressionListStmt
 		}
+		p.addError(UNEXPECTED_TOKEN, p.nextToken, "expected ',' or ')' in expression list")
+		return nil
 	}
-	return expressionListStmt
 }
 
 func (p *Parser) ParseParameterListStatement() *ast.ParameterListStatement {
 	parameterListStmt := &ast.ParameterListStatement{Token: p.curToken}
-	p.advanceToken()
 	parameterListStmt.ParameterList = []ast.ParameterStatement{}
-	for token.Symbol(p.curToken.Literal) != token.RPAREN {
+	if p.nextSymbolIs(token.RPAREN) {
+		p.advanceToken()
+		return parameterListStmt
+	}
+	p.advanceToken()
+	for {
 		parameterStmt := p.ParseParameterStatement()
 		if parameterStmt == nil {
 			return nil
 		}
 		parameterListStmt.ParameterList = append(parameterListStmt.ParameterList, *parameterStmt)
-		p.advanceToken()
-		if token.Symbol(p.curToken.Literal) == token.RPAREN {
-			break
+		if p.nextSymbolIs(token.COMMA) {
+			p.advanceToken()
+			p.advanceToken()
+			continue
 		}
-		p.advanceToken()
+		if p.nextSymbolIs(token.RPAREN) {
+			p.advanceToken()
+			return parameterListStmt
+		}
+		p.addError(UNEXPECTED_TOKEN, p.nextToken, "expected ',' or ')' in parameter list")
+		return nil
 	}
-	return parameterListStmt
 }
 
 func (p *Parser) ParseParameterStatement() *ast.ParameterStatement {
 	parameterStmt := &ast.ParameterStatement{Token: p.curToken}
 	if p.curToken.Type != token.KEYWORD && p.curToken.Type != token.IDENTIFIER {
+		p.addError(UNEXPECTED_TOKEN, p.curToken, "expected parameter type")
 		return nil
 	}
-	parameterStmt.ValueType = token.Token{Type: p.curToken.Type, Literal: p.curToken.Literal}
-	p.advanceToken()
-	if p.curToken.Type != token.IDENTIFIER {
+	parameterStmt.ValueType = p.curToken
+	if !p.expectNext(token.IDENTIFIER, "parameter name") {
 		return nil
 	}
 	parameterStmt.Name = p.curToken.Literal
 	return parameterStmt
 }
 
+// ParseExpression parses "term (op term)*". nil is returned if expression has errors.
 func (p *Parser) ParseExpression() ast.Expression {
 	expressionToken := p.curToken
 	prefixTerm := p.ParseTerm()
+	if prefixTerm == nil {
+		return nil
+	}
+	var expression ast.Expression = &ast.SingleExpression{Token: expressionToken, Value: prefixTerm}
 	InfixSymbol := map[token.Symbol]token.Symbol{ // 中置演算子となりうるSymbol
 		token.EQ:       token.EQ,
 		token.PLUS:     token.PLUS,
@@ -398,52 +527,76 @@ func (p *Parser) ParseExpression() ast.Expression {
 		token.OR:       token.OR,
 		token.AMP:      token.AMP,
 	}
-	if _, ok := InfixSymbol[token.Symbol(p.nextToken.Literal)]; ok {
+	// term (op term)* を左結合で読む。Jackには演算子の優先順位がなく、左から順に評価される。
+	for p.nextTokenIs(token.SYMBOL) {
+		if _, ok := InfixSymbol[token.Symbol(p.nextToken.Literal)]; !ok {
+			break
+		}
 		p.advanceToken()
 		operator := p.curToken
 		p.advanceToken()
 		suffixTerm := p.ParseTerm()
-		return &ast.InfixExpression{Left: prefixTerm, Operator: operator, Right: suffixTerm}
-	} else {
-		return &ast.SingleExpression{Token: expressionToken, Value: prefixTerm}
+		if suffixTerm == nil {
+			return nil
+		}
+		expression = &ast.InfixExpression{Token: expressionToken, Left: expression, Operator: operator, Right: suffixTerm}
 	}
+	return expression
 }
 
+// ParseTerm parses term. nil is returned if term has errors.
+// NOTE: nil pointer of each term is not returned as it is, because it is not nil as ast.Term.
 func (p *Parser) ParseTerm() ast.Term {
 	switch p.curToken.Type {
 	case token.INTCONST:
-		return p.ParseIntegerConstTerm()
-	case token.IDENTIFIER:
-		if token.Symbol(p.nextToken.Literal) == token.LPAREN {
-			return p.ParseSubroutineCallTerm()
+		if term := p.ParseIntegerConstTerm(); term != nil {
+			return term
 		}
-		if token.Symbol(p.nextToken.Literal) == token.DOT {
-			return p.ParseSubroutineCallTerm()
+		return nil
+	case token.IDENTIFIER:
+		if p.nextSymbolIs(token.LPAREN) || p.nextSymbolIs(token.DOT) {
+			if term := p.ParseSubroutineCallTerm(); term != nil {
+				return term
+			}
+			return nil
 		}
-		if token.Symbol(p.nextToken.Literal) == token.LBRACKET {
-			return p.ParseArrayElementTerm()
+		if p.nextSymbolIs(token.LBRACKET) {
+			if term := p.ParseArrayElementTerm(); term != nil {
+				return term
+			}
+			return nil
 		}
 		return p.ParseIdentifierTerm()
 	case token.STARTINGCONST:
 		return p.ParseStringConstTerm()
 	case token.SYMBOL:
-		if token.Symbol(p.curToken.Literal) == token.LPAREN {
-			return p.ParseBracketTerm()
+		if p.curSymbolIs(token.LPAREN) {
+			if term := p.ParseBracketTerm(); term != nil {
+				return term
+			}
+			return nil
 		}
-		if token.Symbol(p.curToken.Literal) == token.MINUS || token.Symbol(p.curToken.Literal) == token.BANG {
-			return p.ParsePrefixTerm()
+		if p.curSymbolIs(token.MINUS) || p.curSymbolIs(token.BANG) {
+			if term := p.ParsePrefixTerm(); term != nil {
+				return term
+			}
+			return nil
 		}
 	case token.KEYWORD:
-		return p.ParseKeyWordConstTerm()
+		if term := p.ParseKeyWordConstTerm(); term != nil {
+			return term
+		}
+		return nil
 	}
-
+	p.addError(UNEXPECTED_TOKEN, p.curToken, "expected expression but got '%s'", p.curToken.Literal)
 	return nil
 }
 
 func (p *Parser) ParseIntegerConstTerm() *ast.IntergerConstTerm {
-	value, err := strconv.ParseInt(p.curToken.Literal, 0, 64)
-	if err != nil {
-		panic(fmt.Sprintf("could not Parse %q as integer", p.curToken.Literal))
+	value, err := strconv.ParseInt(p.curToken.Literal, 10, 64)
+	if err != nil || value < 0 || value > 32767 {
+		p.addError(INVALID_CONSTANT, p.curToken, "integer constant %s is out of range 0..32767", p.curToken.Literal)
+		return nil
 	}
 	return &ast.IntergerConstTerm{Token: p.curToken, Value: value}
 }
@@ -457,41 +610,44 @@ func (p *Parser) ParseStringConstTerm() *ast.StringConstTerm {
 }
 
 func (p *Parser) ParseKeyWordConstTerm() *ast.KeywordConstTerm {
-	if token.KeyWord(p.curToken.Literal) != token.NULL && token.KeyWord(p.curToken.Literal) != (token.TRUE) && token.KeyWord(p.curToken.Literal) != token.FALSE && token.KeyWord(p.curToken.Literal) != token.THIS {
-		panic(fmt.Sprintf("could not Parse %s as keywordConst", p.curToken.Literal))
+	if !p.curKeyWordIs(token.NULL) && !p.curKeyWordIs(token.TRUE) && !p.curKeyWordIs(token.FALSE) && !p.curKeyWordIs(token.THIS) {
+		p.addError(UNEXPECTED_TOKEN, p.curToken, "expected expression but got keyword '%s'", p.curToken.Literal)
+		return nil
 	}
 	return &ast.KeywordConstTerm{Token: p.curToken, KeyWord: token.KeyWord(p.curToken.Literal)}
 }
 
 func (p *Parser) ParseSubroutineCallTerm() *ast.SubroutineCallTerm {
 	subroutineCallTerm := &ast.SubroutineCallTerm{Token: p.curToken}
-	if token.Symbol(p.nextToken.Literal) == token.DOT {
+	if p.nextSymbolIs(token.DOT) {
 		subroutineCallTerm.ClassName = p.curToken
 		p.advanceToken() // className
-		p.advanceToken() // token.DOT
+		if !p.expectNext(token.IDENTIFIER, "subroutine name after '.'") {
+			return nil
+		}
 	}
 	subroutineCallTerm.SubroutineName = p.curToken
-	p.advanceToken()
-
-	if token.Symbol(p.curToken.Literal) != token.LPAREN {
+	if !p.expectNextSymbol(token.LPAREN, "after subroutine name") {
 		return nil
 	}
-
 	subroutineCallTerm.ExpressionListStmt = p.ParseExpressionListStatement()
+	if subroutineCallTerm.ExpressionListStmt == nil {
+		return nil
+	}
 	return subroutineCallTerm
 }
 
 func (p *Parser) ParseArrayElementTerm() *ast.ArrayElementTerm {
 	arrayElementTerm := &ast.ArrayElementTerm{Token: p.curToken, ArrayName: p.curToken}
-	p.advanceToken()
-	if token.Symbol(p.curToken.Literal) != token.LBRACKET {
+	if !p.expectNextSymbol(token.LBRACKET, "after array name") {
 		return nil
 	}
 	p.advanceToken()
-	idx := p.ParseExpression()
-	arrayElementTerm.Idx = idx
-	p.advanceToken()
-	if token.Symbol(p.curToken.Literal) != token.RBRACKET {
+	arrayElementTerm.Idx = p.ParseExpression()
+	if arrayElementTerm.Idx == nil {
+		return nil
+	}
+	if !p.expectNextSymbol(token.RBRACKET, "after array index") {
 		return nil
 	}
 	return arrayElementTerm
@@ -501,21 +657,60 @@ func (p *Parser) ParsePrefixTerm() *ast.PrefixTerm {
 	prefixTerm := &ast.PrefixTerm{Token: p.curToken, Prefix: token.Symbol(p.curToken.Literal)}
 	p.advanceToken()
 	prefixTerm.Value = p.ParseTerm()
+	if prefixTerm.Value == nil {
+		return nil
+	}
 	return prefixTerm
 }
 
 func (p *Parser) ParseBracketTerm() *ast.BracketTerm {
 	bracketTerm := &ast.BracketTerm{Token: p.curToken}
 	p.advanceToken()
-	expression := p.ParseExpression()
-	bracketTerm.Value = expression
-	p.advanceToken()
-	if token.Symbol(p.curToken.Literal) != token.RPAREN {
+	bracketTerm.Value = p.ParseExpression()
+	if bracketTerm.Value == nil {
+		return nil
+	}
+	if !p.expectNextSymbol(token.RPAREN, "after expression") {
 		return nil
 	}
 	return bracketTerm
 }
 
+// statementKeyWords are keywords where parsing of statements can restart after error
+var statementKeyWords = []token.KeyWord{token.LET, token.DO, token.IF, token.WHILE, token.RETURN, token.VAR}
+
+// classMemberKeyWords are keywords where parsing of class members can restart after error
+var classMemberKeyWords = []token.KeyWord{token.STATIC, token.FIELD, token.CONSTRUCTOR, token.FUNCTION, token.METHOD}
+
+// synchronize skips tokens after error so that parsing can restart.
+// It stops at ";" or before "}", EOF and given keywords, which are not enclosed by braces skipped here.
+// After synchronize, caller advances token as if construct was parsed successfully.
+func (p *Parser) synchronize(keyWords ...token.KeyWord) {
+	depth := 0
+	for !p.curTokenIs(token.EOF) {
+		if p.curSymbolIs(token.LBRACE) {
+			depth++
+		} else if p.curSymbolIs(token.RBRACE) {
+			depth--
+		}
+		if depth <= 0 {
+			if p.curSymbolIs(token.SEMICOLON) || p.nextSymbolIs(token.RBRACE) || p.nextTokenIs(token.EOF) {
+				return
+			}
+			for _, keyWord := range keyWords {
+				if p.nextKeyWordIs(keyWord) {
+					return
+				}
+			}
+		}
+		p.advanceToken()
+	}
+}
+
+func (p *Parser) addError(errorType ErrorType, tok token.Token, format string, a ...interface{}) {
+	p.errors = append(p.errors, &ParseError{Type: errorType, Token: tok, Message: fmt.Sprintf(format, a...)})
+}
+
 func (p *Parser) curTokenIs(t token.TokenType) bool {
 	return p.curToken.Type == t
 }
@@ -524,10 +719,43 @@ func (p *Parser) nextTokenIs(t token.TokenType) bool {
 	return p.nextToken.Type == t
 }
 
-func (p *Parser) expectNext(t token.TokenType) bool {
+func (p *Parser) curSymbolIs(symbol token.Symbol) bool {
+	return p.curTokenIs(token.SYMBOL) && token.Symbol(p.curToken.Literal) == symbol
+}
+
+func (p *Parser) nextSymbolIs(symbol token.Symbol) bool {
+	return p.nextTokenIs(token.SYMBOL) && token.Symbol(p.nextToken.Literal) == symbol
+}
+
+func (p *Parser) curKeyWordIs(keyWord token.KeyWord) bool {
+	return p.curTokenIs(token.KEYWORD) && token.KeyWord(p.curToken.Literal) == keyWord
+}
+
+func (p *Parser) nextKeyWordIs(keyWord token.KeyWord) bool {
+	return p.nextTokenIs(token.KEYWORD) && token.KeyWord(p.nextToken.Literal) == keyWord
+}
+
+// nextTokenIsType returns whether next token is "int","char","boolean" or class name
+func (p *Parser) nextTokenIsType() bool {
+	return p.nextKeyWordIs(token.INT) || p.nextKeyWordIs(token.CHAR) || p.nextKeyWordIs(token.BOOLEAN) || p.nextTokenIs(token.IDENTIFIER)
+}
+
+// expectNext advances token if next token is type t. Otherwise, error "expected {what}" is recorded.
+func (p *Parser) expectNext(t token.TokenType, what string) bool {
 	if p.nextTokenIs(t) {
 		p.advanceToken()
 		return true
 	}
+	p.addError(UNEXPECTED_TOKEN, p.nextToken, "expected %s", what)
+	return false
+}
+
+// expectNextSymbol advances token if next token is symbol. Otherwise, error "expected '{symbol}' {context}" is recorded.
+func (p *Parser) expectNextSymbol(symbol token.Symbol, context string) bool {
+	if p.nextSymbolIs(symbol) {
+		p.advanceToken()
+		return true
+	}
+	p.addError(UNEXPECTED_TOKEN, p.nextToken, "expected '%s' %s", symbol, context)
 	return false
 }
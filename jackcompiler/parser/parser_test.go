@@ -635,6 +635,9 @@ func TestParseInfixExpression(t *testing.T) {
 		{"1<1", "1<1"},
 		{"1>1", "1>1"},
 		{"1=1", "1=1"},
+		{"1+2+3", "1+2+3"},
+		{"x*2-1", "x*2-1"},
+		{"a+(b*c)-d/e", "a+(b*c)-d/e"},
 	}
 	for _, tt := range testCases {
 		jt := tokenizer.New(tt.input)
@@ -649,3 +652,163 @@ func TestParseInfixExpression(t *testing.T) {
 		}
 	}
 }
+
+func TestParseInfixExpressionIsLeftAssociative(t *testing.T) {
+	jt := tokenizer.New("1-2-3;")
+	p := New(jt)
+	expression := p.ParseExpression()
+	outer, ok := expression.(*ast.InfixExpression)
+	if !ok {
+		t.Fatalf("expression should be *ast.InfixExpression. got %T", expression)
+	}
+	if outer.Right.String() != "3" {
+		t.Fatalf("outer.Right.String() should be 3. got %s", outer.Right.String())
+	}
+	inner, ok := outer.Left.(*ast.InfixExpression)
+	if !ok {
+		t.Fatalf("outer.Left should be *ast.InfixExpression. got %T", outer.Left)
+	}
+	if inner.String() != "1-2" {
+		t.Fatalf("inner.String() should be 1-2. got %s", inner.String())
+	}
+	if token.Symbol(p.nextToken.Literal) != token.SEMICOLON {
+		t.Fatalf("next token should be ;. got %s", p.nextToken.Literal)
+	}
+	expectedXml := "<expression><term><integerConstant> 1 </integerConstant></term><symbol> - </symbol><term><integerConstant> 2 </integerConstant></term><symbol> - </symbol><term><integerConstant> 3 </integerConstant></term></expression>"
+	if outer.Xml() != expectedXml {
+		t.Fatalf("outer.Xml() should be %s. got %s", expectedXml, outer.Xml())
+	}
+}
+
+func TestParseDocComment(t *testing.T) {
+	input := `/** A class. */
+class Hoge {
+    field int x; // x coordinate
+
+    /**
+     * Constructs a new Hoge.
+     */
+    constructor Hoge new() { return this; }
+
+    // not a doc comment
+    method void dispose() { return; }
+}`
+	jt := tokenizer.New(input)
+	p := New(jt)
+	stmt := p.ParseClassStatement()
+	if stmt == nil {
+		t.Fatalf("ParseClassStatement() returned nil")
+	}
+	if stmt.DocComment != "A class." {
+		t.Fatalf("stmt.DocComment should be %q. got %q", "A class.", stmt.DocComment)
+	}
+	expectedDocComments := []string{"Constructs a new Hoge.", ""}
+	if len(stmt.SubroutineDecList) != len(expectedDocComments) {
+		t.Fatalf("len(stmt.SubroutineDecList) should be %d. got %d", len(expectedDocComments), len(stmt.SubroutineDecList))
+	}
+	for i, subroutineDec := range stmt.SubroutineDecList {
+		if subroutineDec.DocComment != expectedDocComments[i] {
+			t.Fatalf("SubroutineDecList[%d].DocComment should be %q. got %q", i, expectedDocComments[i], subroutineDec.DocComment)
+		}
+	}
+}
+
+func TestParseProgramErrors(t *testing.T) {
+	input := `class Main {
+    field int x
+    function void main() {
+        var int a;
+        let a = 1
+        let a = a + ;
+        do Output.printInt(a $ 2);
+        return;
+    }
+    method int get() { return x; }
+}`
+	expectedErrors := []struct {
+		errorType ErrorType
+		message   string
+	}{
+		{UNEXPECTED_TOKEN, "expected ';' after field declaration at Main.jack:3:5"},
+		{UNEXPECTED_TOKEN, "expected ';' after let statement at Main.jack:6:9"},
+		{UNEXPECTED_TOKEN, "expected expression but got ';' at Main.jack:6:21"},
+		{ILLEGAL_TOKEN, "invalid character '$' at Main.jack:7:30"},
+		{UNEXPECTED_TOKEN, "expected ',' or ')' in expression list at Main.jack:7:32"},
+	}
+	jt := tokenizer.NewWithFilename("Main.jack", input)
+	p := New(jt)
+	program, errors := p.ParseProgram()
+	if len(errors) != len(expectedErrors) {
+		for _, err := range errors {
+			t.Log(err.Error())
+		}
+		t.Fatalf("len(errors) should be %d. got %d", len(expectedErrors), len(errors))
+	}
+	for i, expected := range expectedErrors {
+		if errors[i].Type != expected.errorType {
+			t.Errorf("errors[%d].Type should be %s. got %s", i, expected.errorType, errors[i].Type)
+		}
+		if errors[i].Error() != expected.message {
+			t.Errorf("errors[%d].Error() should be %q. got %q", i, expected.message, errors[i].Error())
+		}
+	}
+	// parsing restarts after errors. so, following subroutine is parsed.
+	if len(program.Statements) != 1 {
+		t.Fatalf("len(program.Statements) should be 1. got %d", len(program.Statements))
+	}
+	classStmt, ok := program.Statements[0].(*ast.ClassStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] should be *ast.ClassStatement. got %T", program.Statements[0])
+	}
+	if len(classStmt.SubroutineDecList) != 2 {
+		t.Fatalf("len(classStmt.SubroutineDecList) should be 2. got %d", len(classStmt.SubroutineDecList))
+	}
+	if len(classStmt.SubroutineDecList[0].SubroutineBody.Statements) != 1 {
+		t.Fatalf("only return statement should be parsed in main. got %s", classStmt.SubroutineDecList[0].SubroutineBody.String())
+	}
+}
+
+func TestParseIntegerConstTermDecimal(t *testing.T) {
+	// Jackの整数定数は10進数なので、先頭の0は8進数の接頭辞ではない
+	testCases := map[string]int64{"0": 0, "09": 9, "010": 10, "32767": 32767}
+	for input, expected := range testCases {
+		p := New(tokenizer.New(input))
+		term := p.ParseIntegerConstTerm()
+		if term == nil || len(p.Errors()) > 0 {
+			t.Fatalf("ParseIntegerConstTerm(%q) returned errors: %v", input, p.Errors())
+		}
+		if term.Value != expected {
+			t.Errorf("ParseIntegerConstTerm(%q) should be %d. got %d", input, expected, term.Value)
+		}
+	}
+}
+
+func TestParseStatementErrors(t *testing.T) {
+	testCases := []struct {
+		input           string
+		expectedMessage string
+	}{
+		{"let = 1;", "expected variable name after 'let' at 1:5"},
+		{"let x 1;", "expected '=' in let statement at 1:7"},
+		{"let x = 40000;", "integer constant 40000 is out of range 0..32767 at 1:9"},
+		{"do Output.();", "expected subroutine name after '.' at 1:11"},
+		{"if (x) let x = 1;", "expected '{' before if block at 1:8"},
+		{"while x {}", "expected '(' after 'while' at 1:7"},
+		{"return 1 2;", "expected ';' after return statement at 1:10"},
+		{"x = 1;", "expected statement but got 'x' at 1:1"},
+	}
+	for _, tt := range testCases {
+		jt := tokenizer.New(tt.input)
+		p := New(jt)
+		stmt := p.ParseStatement()
+		if stmt != nil {
+			t.Fatalf("ParseStatement(%q) should return nil. got %T", tt.input, stmt)
+		}
+		if len(p.Errors()) == 0 {
+			t.Fatalf("ParseStatement(%q) should record error", tt.input)
+		}
+		if p.Errors()[0].Error() != tt.expectedMessage {
+			t.Fatalf("error should be %q. got %q", tt.expectedMessage, p.Errors()[0].Error())
+		}
+	}
+}
@@ -2,6 +2,7 @@ package symboltable
 
 import (
 	"errors"
+	"jackcompiler/ast"
 )
 
 type SymbolTable struct {
@@ -137,3 +138,118 @@ func (st *SymbolTable) IndexOf(name string) int {
 	}
 	return -1
 }
+
+// SubroutineKind is kind of subroutine
+type SubroutineKind string
+
+const (
+	CONSTRUCTOR SubroutineKind = "constructor"
+	FUNCTION    SubroutineKind = "function"
+	METHOD      SubroutineKind = "method"
+)
+
+// SubroutineSignature is signature of subroutine which is referred when subroutine is called.
+type SubroutineSignature struct {
+	Name           string
+	Kind           SubroutineKind
+	ReturnType     string   // "void","int","char","boolean" or class name
+	ParameterTypes []string // type of each parameter. "this" of method is not included
+}
+
+// ClassSignature is signatures of subroutines and variables declared in class.
+type ClassSignature struct {
+	Name        string
+	Fields      map[string]Symbol // static and field variables
+	Subroutines map[string]SubroutineSignature
+	External    bool // declared outside of program (e.g. OS). its implementation is not compiled with program.
+}
+
+// NewClassSignature returns signature of class declared in classStmt
+func NewClassSignature(classStmt *ast.ClassStatement) *ClassSignature {
+	classSignature := &ClassSignature{Name: classStmt.Name.Literal, Fields: map[string]Symbol{}, Subroutines: map[string]SubroutineSignature{}}
+	st := New()
+	for _, classVarDec := range classStmt.ClassVarDecList {
+		for _, identifier := range classVarDec.Identifiers {
+			st.Define(identifier.Literal, classVarDec.ValueType.Literal, VarKind(classVarDec.Token.Literal))
+		}
+	}
+	for name, symbol := range st.ClassScopeSymbolTable {
+		classSignature.Fields[name] = symbol
+	}
+	for i := range classStmt.SubroutineDecList {
+		subroutineDec := &classStmt.SubroutineDecList[i]
+		classSignature.Subroutines[subroutineDec.Name.Literal] = NewSubroutineSignature(subroutineDec)
+	}
+	return classSignature
+}
+
+// NewSubroutineSignature returns signature of subroutine declared in subroutineDec
+func NewSubroutineSignature(subroutineDec *ast.SubroutineDecStatement) SubroutineSignature {
+	parameterTypes := []string{}
+	for _, parameter := range subroutineDec.ParameterList.ParameterList {
+		parameterTypes = append(parameterTypes, parameter.ValueType.Literal)
+	}
+	return SubroutineSignature{
+		Name:           subroutineDec.Name.Literal,
+		Kind:           SubroutineKind(subroutineDec.Token.Literal),
+		ReturnType:     subroutineDec.ReturnType.Literal,
+		ParameterTypes: parameterTypes,
+	}
+}
+
+// FieldCount returns number of field variables. it is size of object allocated by constructor.
+func (cs *ClassSignature) FieldCount() int {
+	fieldCount := 0
+	for _, symbol := range cs.Fields {
+		if symbol.VarKind == FIELD {
+			fieldCount++
+		}
+	}
+	return fieldCount
+}
+
+// ProgramIndex is index of all classes which can be referred from program.
+// It is built from all classes in directory before they are checked and compiled.
+type ProgramIndex struct {
+	Classes map[string]*ClassSignature
+}
+
+func NewProgramIndex() *ProgramIndex {
+	return &ProgramIndex{Classes: map[string]*ClassSignature{}}
+}
+
+// AddProgram adds all classes declared in programAst
+func (pi *ProgramIndex) AddProgram(programAst *ast.Program) {
+	for _, stmt := range programAst.Statements {
+		if classStmt, ok := stmt.(*ast.ClassStatement); ok {
+			pi.AddClass(NewClassSignature(classStmt))
+		}
+	}
+}
+
+func (pi *ProgramIndex) AddClass(classSignature *ClassSignature) {
+	pi.Classes[classSignature.Name] = classSignature
+}
+
+// AddExternalClass adds class declared outside of program. class in program has priority over it.(e.g. OS class implemented by user)
+func (pi *ProgramIndex) AddExternalClass(classSignature *ClassSignature) {
+	if _, ok := pi.Classes[classSignature.Name]; ok {
+		return
+	}
+	classSignature.External = true
+	pi.Classes[classSignature.Name] = classSignature
+}
+
+func (pi *ProgramIndex) Class(className string) (*ClassSignature, bool) {
+	classSignature, ok := pi.Classes[className]
+	return classSignature, ok
+}
+
+func (pi *ProgramIndex) Subroutine(className string, subroutineName string) (SubroutineSignature, bool) {
+	classSignature, ok := pi.Classes[className]
+	if !ok {
+		return SubroutineSignature{}, false
+	}
+	subroutine, ok := classSignature.Subroutines[subroutineName]
+	return subroutine, ok
+}
@@ -1,6 +1,8 @@
 package symboltable
 
 import (
+	"jackcompiler/parser"
+	"jackcompiler/tokenizer"
 	"reflect"
 	"testing"
 )
@@ -181,3 +183,57 @@ func TestIndexOf(t *testing.T) {
 		}
 	}
 }
+
+func TestProgramIndex(t *testing.T) {
+	input := `class Point {
+    static int count;
+    field int x, y;
+    constructor Point new(int ax, int ay) { return this; }
+    method int getX() { return x; }
+    function void reset() { return; }
+}`
+	p := parser.New(tokenizer.New(input))
+	program, _ := p.ParseProgram()
+	index := NewProgramIndex()
+	index.AddProgram(program)
+	index.AddExternalClass(&ClassSignature{Name: "Point", Subroutines: map[string]SubroutineSignature{}})
+	index.AddExternalClass(&ClassSignature{Name: "Math", Subroutines: map[string]SubroutineSignature{}})
+
+	point, ok := index.Class("Point")
+	if !ok {
+		t.Fatalf("index.Class(\"Point\") should be found")
+	}
+	if point.External {
+		t.Errorf("class in program should not be overwritten by external class")
+	}
+	if point.FieldCount() != 2 {
+		t.Errorf("point.FieldCount() should be 2. got %d", point.FieldCount())
+	}
+	if point.Fields["y"].Idx != 1 || point.Fields["count"].VarKind != STATIC {
+		t.Errorf("point.Fields is not valid. got %v", point.Fields)
+	}
+	math, ok := index.Class("Math")
+	if !ok || !math.External {
+		t.Errorf("Math should be external class")
+	}
+	testCases := []struct {
+		subroutineName string
+		expected       SubroutineSignature
+	}{
+		{"new", SubroutineSignature{"new", CONSTRUCTOR, "Point", []string{"int", "int"}}},
+		{"getX", SubroutineSignature{"getX", METHOD, "int", []string{}}},
+		{"reset", SubroutineSignature{"reset", FUNCTION, "void", []string{}}},
+	}
+	for _, tt := range testCases {
+		subroutine, ok := index.Subroutine("Point", tt.subroutineName)
+		if !ok {
+			t.Fatalf("index.Subroutine(\"Point\", %q) should be found", tt.subroutineName)
+		}
+		if !reflect.DeepEqual(subroutine, tt.expected) {
+			t.Errorf("index.Subroutine(\"Point\", %q) should be %v. got %v", tt.subroutineName, tt.expected, subroutine)
+		}
+	}
+	if _, ok := index.Subroutine("Point", "missing"); ok {
+		t.Errorf("index.Subroutine(\"Point\", \"missing\") should not be found")
+	}
+}
@@ -1,35 +1,57 @@
 package token
 
+import (
+	"fmt"
+	"strings"
+)
+
 // TokenType is type of token
 type TokenType string
 
 // Token has memeber tokentype,tokenliteral
 type Token struct {
-	Type    TokenType
-	Literal string
+	Type       TokenType
+	Literal    string
+	DocComment string // text of "/** ... */" placed just before this token
+	File       string // name of source file. empty if token is not read from file
+	Line       int    // 1-origin
+	Column     int    // 1-origin
 }
 
 func (token *Token) String() string {
 	return token.Literal
 }
 
+// Position returns "file:line:column" of token. "file:" is omitted if file is empty.
+func (token *Token) Position() string {
+	if token.File == "" {
+		return fmt.Sprintf("%d:%d", token.Line, token.Column)
+	}
+	return fmt.Sprintf("%s:%d:%d", token.File, token.Line, token.Column)
+}
+
 func (token *Token) Xml() string {
 	switch token.Type {
 	case KEYWORD:
 		return "<keyword> " + token.Literal + " </keyword>"
 	case SYMBOL:
-		return "<symbol> " + token.Literal + " </symbol>"
+		return "<symbol> " + escapeXml(token.Literal) + " </symbol>"
 	case IDENTIFIER:
 		return "<identifier> " + token.Literal + " </identifier>"
 	case INTCONST:
 		return "<integerConstant> " + token.Literal + " </integerConstant>"
 	case STARTINGCONST:
-		return "<stringConstant> " + token.Literal + " </stringConstant>"
+		return "<stringConstant> " + escapeXml(token.Literal) + " </stringConstant>"
 	default:
 		return ""
 	}
 }
 
+// escapeXml escapes "<",">","&" and '"' which can not be written in xml as they are.
+func escapeXml(literal string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "\"", "&quot;").Replace(literal)
+}
+
 const (
 	SYMBOL        TokenType = "SYMBOL"
 	KEYWORD       TokenType = "KEYWORD"
@@ -37,6 +59,7 @@ const (
 	INTCONST      TokenType = "INT_CONST"
 	STARTINGCONST TokenType = "STARTING_CONST"
 	EOF           TokenType = "EOF"
+	ILLEGAL       TokenType = "ILLEGAL"
 )
 
 // KeyWord is keyword type
@@ -4,56 +4,89 @@ import (
 	"errors"
 	"fmt"
 	"jackcompiler/token"
+	"strings"
 )
 
 // JackTokenizer has member necessary for parsing
 type JackTokenizer struct {
 	input        string // code input
+	filename     string
 	position     int
 	readPosition int
 	ch           byte
+	line         int    // line of ch
+	column       int    // column of ch
+	docComment   string // last "/** */" comment which is not attached to token yet
 }
 
 // New is initializer of jack tokenizer
 func New(input string) *JackTokenizer {
-	jt := &JackTokenizer{input: input}
+	jt := &JackTokenizer{input: input, line: 1}
 	jt.readChar()
 	return jt
 }
 
+// NewWithFilename is initializer of jack tokenizer. filename is set to position of each token.
+func NewWithFilename(filename string, input string) *JackTokenizer {
+	jt := New(input)
+	jt.filename = filename
+	return jt
+}
+
 // HasMoreTokens returns whether hasMoreToken
 func (jackTokenizer *JackTokenizer) HasMoreTokens() bool {
 	return len(jackTokenizer.input) > jackTokenizer.position
 }
 
-// Advance returns next token
+// Advance returns next token.
+// If error is returned, invalid characters are skipped so that Advance can be called again.
 func (jackTokenizer *JackTokenizer) Advance() (advanceToken token.Token, err error) {
+	tok, err := jackTokenizer.advance()
+	if err != nil {
+		return tok, err
+	}
+	if tok.Type != token.EOF {
+		tok.DocComment = jackTokenizer.docComment
+		jackTokenizer.docComment = ""
+	}
+	return tok, nil
+}
+
+func (jackTokenizer *JackTokenizer) advance() (advanceToken token.Token, err error) {
 	var tok token.Token
 	// TODO: refactoring.
-	jackTokenizer.skipWhitespace()
+	if tok, err := jackTokenizer.skipWhitespaceAndComment(); err != nil {
+		return tok, err
+	}
+	line, column := jackTokenizer.line, jackTokenizer.column
 	if !jackTokenizer.HasMoreTokens() {
-		return token.Token{Type: token.EOF}, nil
+		return jackTokenizer.newToken(token.EOF, "", line, column), nil
 	}
 	if _, ok := token.SymbolMap[jackTokenizer.ch]; ok {
-		tok = token.Token{Type: token.SYMBOL, Literal: string(jackTokenizer.ch)}
-	} else if isLetter(jackTokenizer.ch) { // KEYWORD or IDENTIFIER
+		tok = jackTokenizer.newToken(token.SYMBOL, string(jackTokenizer.ch), line, column)
+	} else if isLetter(jackTokenizer.ch) || isUnderline(jackTokenizer.ch) { // KEYWORD or IDENTIFIER
 		word := jackTokenizer.readWord()
 		if _, ok := token.KeyWordMap[word]; ok {
-			tok = token.Token{Type: token.KEYWORD, Literal: word}
+			tok = jackTokenizer.newToken(token.KEYWORD, word, line, column)
 		} else {
-			tok = token.Token{Type: token.IDENTIFIER, Literal: word}
+			tok = jackTokenizer.newToken(token.IDENTIFIER, word, line, column)
 		}
 		return tok, nil
 	} else if isNumber(jackTokenizer.ch) {
 		word := jackTokenizer.readNumber()
-		tok = token.Token{Type: token.INTCONST, Literal: word}
+		tok = jackTokenizer.newToken(token.INTCONST, word, line, column)
 		return tok, nil
 	} else if isDoubleQuote(jackTokenizer.ch) {
-		word := jackTokenizer.readString()
-		tok = token.Token{Type: token.STARTINGCONST, Literal: word[1:]}
+		word, ok := jackTokenizer.readString()
+		if !ok {
+			return jackTokenizer.newToken(token.ILLEGAL, word, line, column), errors.New("string constant is not terminated. expected \"")
+		}
+		tok = jackTokenizer.newToken(token.STARTINGCONST, word[1:], line, column)
 		return tok, nil
 	} else {
-		return tok, fmt.Errorf("invalid ch. got %s", string(jackTokenizer.ch))
+		tok = jackTokenizer.newToken(token.ILLEGAL, string(jackTokenizer.ch), line, column)
+		jackTokenizer.readChar() // skip invalid ch
+		return tok, fmt.Errorf("invalid character '%s'", tok.Literal)
 	}
 	jackTokenizer.readChar()
 	return tok, nil
@@ -67,7 +100,15 @@ func KeyWord(tok token.Token) (keyword token.KeyWord, err error) {
 	return token.KeyWordMap[tok.Literal], nil
 }
 
+func (jackTokenizer *JackTokenizer) newToken(tokenType token.TokenType, literal string, line int, column int) token.Token {
+	return token.Token{Type: tokenType, Literal: literal, File: jackTokenizer.filename, Line: line, Column: column}
+}
+
 func (jackTokenizer *JackTokenizer) readChar() {
+	if jackTokenizer.ch == '\n' {
+		jackTokenizer.line++
+		jackTokenizer.column = 0
+	}
 	if jackTokenizer.readPosition >= len(jackTokenizer.input) {
 		jackTokenizer.ch = 0
 	} else {
@@ -76,6 +117,14 @@ func (jackTokenizer *JackTokenizer) readChar() {
 	jackTokenizer.position = jackTokenizer.readPosition
 
 	jackTokenizer.readPosition++
+	jackTokenizer.column++
+}
+
+func (jackTokenizer *JackTokenizer) peekChar() byte {
+	if jackTokenizer.readPosition >= len(jackTokenizer.input) {
+		return 0
+	}
+	return jackTokenizer.input[jackTokenizer.readPosition]
 }
 
 func (jackTokenizer *JackTokenizer) readWord() string {
@@ -94,14 +143,18 @@ func (jackTokenizer *JackTokenizer) readNumber() string {
 	return jackTokenizer.input[position:jackTokenizer.position]
 }
 
-func (jackTokenizer *JackTokenizer) readString() string {
+// readString reads string constant. ok is false if closing double quote is not found before end of line.
+func (jackTokenizer *JackTokenizer) readString() (str string, ok bool) {
 	position := jackTokenizer.position
 	jackTokenizer.readChar() // read double quote
 	for !isDoubleQuote(jackTokenizer.ch) {
+		if jackTokenizer.ch == '\n' || !jackTokenizer.HasMoreTokens() {
+			return jackTokenizer.input[position:jackTokenizer.position], false
+		}
 		jackTokenizer.readChar()
 	}
 	jackTokenizer.readChar()
-	return jackTokenizer.input[position : jackTokenizer.position-1]
+	return jackTokenizer.input[position : jackTokenizer.position-1], true
 }
 
 func (jackTokenizer *JackTokenizer) skipWhitespace() {
@@ -110,6 +163,67 @@ func (jackTokenizer *JackTokenizer) skipWhitespace() {
 	}
 }
 
+// skipWhitespaceAndComment skips whitespaces and comments.
+// If comment is not terminated, ILLEGAL token at the beginning of comment is returned with error.
+func (jackTokenizer *JackTokenizer) skipWhitespaceAndComment() (token.Token, error) {
+	for {
+		jackTokenizer.skipWhitespace()
+		if jackTokenizer.ch != '/' {
+			return token.Token{}, nil
+		}
+		switch jackTokenizer.peekChar() {
+		case '/':
+			jackTokenizer.skipLineComment()
+		case '*':
+			line, column := jackTokenizer.line, jackTokenizer.column
+			if err := jackTokenizer.skipBlockComment(); err != nil {
+				return jackTokenizer.newToken(token.ILLEGAL, "/*", line, column), err
+			}
+		default: // "/" is SYMBOL(division)
+			return token.Token{}, nil
+		}
+	}
+}
+
+// skipLineComment skips "// ..." until end of line
+func (jackTokenizer *JackTokenizer) skipLineComment() {
+	for jackTokenizer.ch != '\n' && jackTokenizer.HasMoreTokens() {
+		jackTokenizer.readChar()
+	}
+}
+
+// skipBlockComment skips "/* ... */" and "/** ... */". text of "/** ... */" is kept as doc comment.
+func (jackTokenizer *JackTokenizer) skipBlockComment() error {
+	position := jackTokenizer.position
+	jackTokenizer.readChar() // read "/"
+	jackTokenizer.readChar() // read "*"
+	for !(jackTokenizer.ch == '*' && jackTokenizer.peekChar() == '/') {
+		if !jackTokenizer.HasMoreTokens() {
+			return errors.New("comment is not terminated. expected */")
+		}
+		jackTokenizer.readChar()
+	}
+	jackTokenizer.readChar() // read "*"
+	jackTokenizer.readChar() // read "/"
+	comment := jackTokenizer.input[position:jackTokenizer.position]
+	if strings.HasPrefix(comment, "/**") && comment != "/**/" {
+		jackTokenizer.docComment = docCommentText(comment)
+	}
+	return nil
+}
+
+// docCommentText removes "/**","*/" and leading "*" of each line from doc comment.
+func docCommentText(comment string) string {
+	comment = strings.TrimSuffix(strings.TrimPrefix(comment, "/**"), "*/")
+	lines := []string{}
+	for _, line := range strings.Split(comment, "\n") {
+		line = strings.TrimSpace(line)
+		line = strings.TrimSpace(strings.TrimPrefix(line, "*"))
+		lines = append(lines, line)
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
 func isLetter(ch byte) bool {
 	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z'
 }
@@ -74,3 +74,127 @@ func TestNextToken(t *testing.T) {
 		}
 	}
 }
+
+func TestSkipComment(t *testing.T) {
+	input := `// line comment
+/* block comment
+   over lines */
+class Main { // comment after code
+    /* inline */ field int x; /**/
+    /**
+     * Returns x / 2.
+     */
+    method int half() { return x / 2; }
+}
+// comment at EOF`
+	tests := []struct {
+		expectedType       token.TokenType
+		expectedLiteral    string
+		expectedDocComment string
+	}{
+		{token.KEYWORD, "class", ""},
+		{token.IDENTIFIER, "Main", ""},
+		{token.SYMBOL, "{", ""},
+		{token.KEYWORD, "field", ""},
+		{token.KEYWORD, "int", ""},
+		{token.IDENTIFIER, "x", ""},
+		{token.SYMBOL, ";", ""},
+		{token.KEYWORD, "method", "Returns x / 2."},
+		{token.KEYWORD, "int", ""},
+		{token.IDENTIFIER, "half", ""},
+		{token.SYMBOL, "(", ""},
+		{token.SYMBOL, ")", ""},
+		{token.SYMBOL, "{", ""},
+		{token.KEYWORD, "return", ""},
+		{token.IDENTIFIER, "x", ""},
+		{token.SYMBOL, "/", ""},
+		{token.INTCONST, "2", ""},
+		{token.SYMBOL, ";", ""},
+		{token.SYMBOL, "}", ""},
+		{token.SYMBOL, "}", ""},
+		{token.EOF, "", ""},
+	}
+	jt := New(input)
+	for i, tt := range tests {
+		tok, err := jt.Advance()
+		if err != nil {
+			t.Fatalf("test[%d] - unexpected error: %s", i, err)
+		}
+		if tok.Type != tt.expectedType {
+			t.Fatalf("test[%d] - tokentype wrong. expected=%q,got %q. \n - tokenliteral : expected=%q,got %q", i, tt.expectedType, tok.Type, tt.expectedLiteral, tok.Literal)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("test[%d] - tokenliteral wrong. expected=%q,got %q", i, tt.expectedLiteral, tok.Literal)
+		}
+		if tok.DocComment != tt.expectedDocComment {
+			t.Fatalf("test[%d] - doc comment wrong. expected=%q,got %q", i, tt.expectedDocComment, tok.DocComment)
+		}
+	}
+}
+
+func TestUnterminatedComment(t *testing.T) {
+	jt := New("class /* not closed")
+	if tok, err := jt.Advance(); err != nil || tok.Literal != "class" {
+		t.Fatalf("first token should be class. got %q (err=%v)", tok.Literal, err)
+	}
+	if _, err := jt.Advance(); err == nil {
+		t.Fatalf("unterminated comment should return error")
+	}
+}
+
+func TestTokenPosition(t *testing.T) {
+	input := "class Main {\n  // comment\n\tlet x = \"str\";\n}"
+	tests := []struct {
+		expectedLiteral string
+		expectedLine    int
+		expectedColumn  int
+	}{
+		{"class", 1, 1},
+		{"Main", 1, 7},
+		{"{", 1, 12},
+		{"let", 3, 2},
+		{"x", 3, 6},
+		{"=", 3, 8},
+		{"str", 3, 10},
+		{";", 3, 15},
+		{"}", 4, 1},
+	}
+	jt := NewWithFilename("Main.jack", input)
+	for i, tt := range tests {
+		tok, _ := jt.Advance()
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("test[%d] - tokenliteral wrong. expected=%q,got %q", i, tt.expectedLiteral, tok.Literal)
+		}
+		if tok.Line != tt.expectedLine || tok.Column != tt.expectedColumn {
+			t.Fatalf("test[%d] - position of %q wrong. expected=%d:%d,got %d:%d", i, tok.Literal, tt.expectedLine, tt.expectedColumn, tok.Line, tok.Column)
+		}
+		if tok.File != "Main.jack" {
+			t.Fatalf("test[%d] - file wrong. expected=%q,got %q", i, "Main.jack", tok.File)
+		}
+	}
+}
+
+func TestInvalidToken(t *testing.T) {
+	testCases := []struct {
+		input           string
+		expectedLiteral string
+	}{
+		{"$ x", "$"},
+		{"\"not closed\n x", "\"not closed"},
+	}
+	for _, tt := range testCases {
+		jt := New(tt.input)
+		tok, err := jt.Advance()
+		if err == nil {
+			t.Fatalf("%q should return error", tt.input)
+		}
+		if tok.Type != token.ILLEGAL || tok.Literal != tt.expectedLiteral {
+			t.Fatalf("token should be ILLEGAL(%q). got %s(%q)", tt.expectedLiteral, tok.Type, tok.Literal)
+		}
+		// invalid characters are skipped.
+		tok, err = jt.Advance()
+		if err != nil || tok.Literal != "x" {
+			t.Fatalf("next token should be x. got %q (err=%v)", tok.Literal, err)
+		}
+	}
+}
@@ -0,0 +1,370 @@
+package typechecker
+
+import (
+	"fmt"
+	"jackcompiler/ast"
+	"jackcompiler/symboltable"
+	"jackcompiler/token"
+)
+
+// ErrorType is type of TypeError
+type ErrorType string
+
+const (
+	UNDECLARED_IDENTIFIER ErrorType = "UNDECLARED_IDENTIFIER"
+	UNKNOWN_CLASS         ErrorType = "UNKNOWN_CLASS"
+	UNKNOWN_SUBROUTINE    ErrorType = "UNKNOWN_SUBROUTINE"
+	ARGUMENT_COUNT        ErrorType = "ARGUMENT_COUNT"  // number of arguments differs from parameters
+	INVALID_CALL          ErrorType = "INVALID_CALL"    // method is called without object, function is called on object etc
+	INVALID_THIS          ErrorType = "INVALID_THIS"    // this or field is used in function
+	RETURN_MISMATCH       ErrorType = "RETURN_MISMATCH" // return value does not match with return type
+	TYPE_MISMATCH         ErrorType = "TYPE_MISMATCH"   // value is assigned to variable of incompatible type
+)
+
+// TypeError is diagnostic reported by TypeChecker
+type TypeError struct {
+	Type    ErrorType
+	Token   token.Token // token where error is detected
+	Message string
+}
+
+func (e *TypeError) Error() string {
+	return fmt.Sprintf("%s at %s", e.Message, e.Token.Position())
+}
+
+// unknownType is type which can not be detected statically. e.g. element of Array
+const unknownType = ""
+
+// nullType is type of "null". it can be assigned to variable of any type.
+const nullType = "null"
+
+// TypeChecker is semantic analyzer which runs before compilationengine
+type TypeChecker struct {
+	*symboltable.SymbolTable
+	index      *symboltable.ProgramIndex
+	className  string
+	subroutine symboltable.SubroutineSignature // subroutine which is checked now
+	errors     []*TypeError
+}
+
+// New is initializer of type checker.
+// index should contain all classes which can be referred. (e.g. classes in same dir and OS classes)
+func New(index *symboltable.ProgramIndex) *TypeChecker {
+	return &TypeChecker{index: index, errors: []*TypeError{}}
+}
+
+// CheckProgram checks all classes in program and returns errors found
+func (tc *TypeChecker) CheckProgram(programAst *ast.Program) []*TypeError {
+	for _, stmt := range programAst.Statements {
+		if classStmt, ok := stmt.(*ast.ClassStatement); ok {
+			tc.CheckClassStatement(classStmt)
+		}
+	}
+	return tc.errors
+}
+
+// Errors returns errors found while checking
+func (tc *TypeChecker) Errors() []*TypeError {
+	return tc.errors
+}
+
+func (tc *TypeChecker) CheckClassStatement(classStmt *ast.ClassStatement) {
+	tc.SymbolTable = symboltable.New()
+	tc.className = classStmt.Name.Literal
+	for _, classVarDec := range classStmt.ClassVarDecList {
+		tc.checkType(classVarDec.ValueType)
+		for _, identifier := range classVarDec.Identifiers {
+			tc.Define(identifier.Literal, classVarDec.ValueType.Literal, symboltable.VarKind(classVarDec.Token.Literal))
+		}
+	}
+	for i := range classStmt.SubroutineDecList {
+		tc.CheckSubroutineDecStatement(&classStmt.SubroutineDecList[i])
+	}
+}
+
+func (tc *TypeChecker) CheckSubroutineDecStatement(subroutineDec *ast.SubroutineDecStatement) {
+	tc.subroutine = symboltable.NewSubroutineSignature(subroutineDec)
+	if tc.subroutine.ReturnType != string(token.VOID) {
+		tc.checkType(subroutineDec.ReturnType)
+	}
+	if tc.subroutine.Kind == symboltable.CONSTRUCTOR && tc.subroutine.ReturnType != tc.className {
+		tc.addError(RETURN_MISMATCH, subroutineDec.ReturnType, "constructor %s must return %s", tc.subroutine.Name, tc.className)
+	}
+	tc.StartSubroutine()
+	if tc.subroutine.Kind == symboltable.METHOD {
+		tc.Define(string(token.THIS), tc.className, symboltable.ARGUMENT)
+	}
+	for _, parameter := range subroutineDec.ParameterList.ParameterList {
+		tc.checkType(parameter.ValueType)
+		tc.Define(parameter.Name, parameter.ValueType.Literal, symboltable.ARGUMENT)
+	}
+	for _, varDec := range subroutineDec.SubroutineBody.VarDecList {
+		tc.checkType(varDec.ValueType)
+		for _, identifier := range varDec.Identifiers {
+			tc.Define(identifier.Literal, varDec.ValueType.Literal, symboltable.VAR)
+		}
+	}
+	tc.checkStatements(subroutineDec.SubroutineBody.Statements)
+}
+
+func (tc *TypeChecker) checkStatements(statements []ast.Statement) {
+	for _, stmt := range statements {
+		tc.CheckStatement(stmt)
+	}
+}
+
+func (tc *TypeChecker) CheckStatement(statementAst ast.Statement) {
+	switch stmt := statementAst.(type) {
+	case *ast.LetStatement:
+		tc.CheckLetStatement(stmt)
+	case *ast.DoStatement:
+		tc.checkSubroutineCall(stmt.ClassName, stmt.SubroutineName, stmt.ExpressionListStmt) // 戻り値は捨てられるのでvoidでも良い
+	case *ast.ReturnStatement:
+		tc.CheckReturnStatement(stmt)
+	case *ast.IfStatement:
+		tc.TypeOfExpression(stmt.Condition)
+		tc.checkStatements(stmt.Consequence.Statements)
+		if stmt.Alternative != nil {
+			tc.checkStatements(stmt.Alternative.Statements)
+		}
+	case *ast.WhileStatement:
+		tc.TypeOfExpression(stmt.Condition)
+		tc.checkStatements(stmt.Statements.Statements)
+	}
+}
+
+func (tc *TypeChecker) CheckLetStatement(letStmt *ast.LetStatement) {
+	varType, ok := tc.variableType(letStmt.Name)
+	if letStmt.Idx != nil {
+		tc.TypeOfExpression(letStmt.Idx)
+		tc.TypeOfExpression(letStmt.Value)
+		return // 配列の要素には型がない
+	}
+	valueType := tc.TypeOfExpression(letStmt.Value)
+	if ok && !assignable(varType, valueType) {
+		tc.addError(TYPE_MISMATCH, expressionToken(letStmt.Value), "cannot assign %s to %s %s", valueType, varType, letStmt.Name.Literal)
+	}
+}
+
+func (tc *TypeChecker) CheckReturnStatement(returnStmt *ast.ReturnStatement) {
+	returnType := tc.subroutine.ReturnType
+	if returnStmt.Value == nil {
+		if returnType != string(token.VOID) {
+			tc.addError(RETURN_MISMATCH, returnStmt.Token, "%s must return %s value", tc.subroutine.Name, returnType)
+		}
+		return
+	}
+	valueType := tc.TypeOfExpression(returnStmt.Value)
+	if returnType == string(token.VOID) {
+		tc.addError(RETURN_MISMATCH, expressionToken(returnStmt.Value), "void %s must not return value", tc.subroutine.Name)
+		return
+	}
+	if !assignable(returnType, valueType) {
+		tc.addError(RETURN_MISMATCH, expressionToken(returnStmt.Value), "cannot return %s from %s which returns %s", valueType, tc.subroutine.Name, returnType)
+	}
+}
+
+// TypeOfExpression returns type of expression. unknownType is returned if type can not be detected.
+func (tc *TypeChecker) TypeOfExpression(expressionAst ast.Expression) string {
+	switch expression := expressionAst.(type) {
+	case *ast.SingleExpression:
+		return tc.TypeOfTerm(expression.Value)
+	case *ast.InfixExpression:
+		leftType := tc.TypeOfExpression(expression.Left)
+		rightType := tc.TypeOfTerm(expression.Right)
+		switch token.Symbol(expression.Operator.Literal) {
+		case token.PLUS, token.MINUS, token.ASTERISK, token.SLASH:
+			return string(token.INT)
+		case token.EQ, token.LT, token.GT:
+			return string(token.BOOLEAN)
+		case token.AMP, token.OR: // booleanに対しては論理演算、intに対してはビット演算
+			if leftType == rightType {
+				return leftType
+			}
+		}
+	}
+	return unknownType
+}
+
+// TypeOfTerm returns type of term. unknownType is returned if type can not be detected.
+func (tc *TypeChecker) TypeOfTerm(termAst ast.Term) string {
+	switch term := termAst.(type) {
+	case *ast.IntergerConstTerm:
+		return string(token.INT)
+	case *ast.StringConstTerm:
+		return "String"
+	case *ast.KeywordConstTerm:
+		switch term.KeyWord {
+		case token.TRUE, token.FALSE:
+			return string(token.BOOLEAN)
+		case token.NULL:
+			return nullType
+		case token.THIS:
+			if tc.subroutine.Kind == symboltable.FUNCTION {
+				tc.addError(INVALID_THIS, term.Token, "'this' cannot be used in function %s", tc.subroutine.Name)
+				return unknownType
+			}
+			return tc.className
+		}
+	case *ast.IdentifierTerm:
+		varType, _ := tc.variableType(term.Token)
+		return varType
+	case *ast.ArrayElementTerm:
+		tc.variableType(term.ArrayName)
+		tc.TypeOfExpression(term.Idx)
+		return unknownType // 配列の要素には型がない
+	case *ast.SubroutineCallTerm:
+		returnType := tc.checkSubroutineCall(term.ClassName, term.SubroutineName, term.ExpressionListStmt)
+		if returnType == string(token.VOID) {
+			tc.addError(TYPE_MISMATCH, term.SubroutineName, "void %s has no value", term.SubroutineName.Literal)
+			return unknownType
+		}
+		return returnType
+	case *ast.BracketTerm:
+		return tc.TypeOfExpression(term.Value)
+	case *ast.PrefixTerm:
+		valueType := tc.TypeOfTerm(term.Value)
+		if term.Prefix == token.MINUS {
+			return string(token.INT)
+		}
+		return valueType
+	}
+	return unknownType
+}
+
+// checkSubroutineCall checks "className.subroutineName(expressionList)" or "subroutineName(expressionList)" and returns return type.
+func (tc *TypeChecker) checkSubroutineCall(className token.Token, subroutineName token.Token, expressionListStmt *ast.ExpressionListStatement) string {
+	for _, expression := range expressionListStmt.ExpressionList {
+		tc.TypeOfExpression(expression)
+	}
+	var signature symboltable.SubroutineSignature
+	var ok bool
+	if className.Literal == "" { // 同じクラスのサブルーチンの呼び出し
+		signature, ok = tc.lookupSubroutine(tc.className, subroutineName)
+		if !ok {
+			return unknownType
+		}
+		if signature.Kind == symboltable.METHOD && tc.subroutine.Kind == symboltable.FUNCTION {
+			tc.addError(INVALID_CALL, subroutineName, "method %s cannot be called from function %s", signature.Name, tc.subroutine.Name)
+		}
+		// methodの中の"f()"はthisに対するメソッド呼び出しなので、function,constructorは"Class.f()"で呼び出す
+		if signature.Kind != symboltable.METHOD && tc.subroutine.Kind == symboltable.METHOD {
+			tc.addError(INVALID_CALL, subroutineName, "%s %s cannot be called as method of this. call %s.%s", signature.Kind, signature.Name, tc.className, signature.Name)
+		}
+	} else if tc.IndexOf(className.Literal) != -1 { // 変数(オブジェクト)に対するメソッド呼び出し
+		varType, _ := tc.variableType(className)
+		if isPrimitiveType(varType) {
+			tc.addError(INVALID_CALL, className, "%s is %s, not object", className.Literal, varType)
+			return unknownType
+		}
+		if _, ok := tc.index.Class(varType); !ok {
+			return unknownType // 型が存在しないことは宣言時に報告済み
+		}
+		signature, ok = tc.lookupSubroutine(varType, subroutineName)
+		if !ok {
+			return unknownType
+		}
+		if signature.Kind != symboltable.METHOD {
+			tc.addError(INVALID_CALL, subroutineName, "%s %s.%s cannot be called on object %s", signature.Kind, varType, signature.Name, className.Literal)
+		}
+	} else { // function,constructorの呼び出し
+		if _, ok := tc.index.Class(className.Literal); !ok {
+			tc.addError(UNKNOWN_CLASS, className, "class %s is not defined", className.Literal)
+			return unknownType
+		}
+		signature, ok = tc.lookupSubroutine(className.Literal, subroutineName)
+		if !ok {
+			return unknownType
+		}
+		if signature.Kind == symboltable.METHOD {
+			tc.addError(INVALID_CALL, subroutineName, "method %s.%s cannot be called without object", className.Literal, signature.Name)
+		}
+	}
+	if len(expressionListStmt.ExpressionList) != len(signature.ParameterTypes) {
+		tc.addError(ARGUMENT_COUNT, subroutineName, "%s expects %d arguments but got %d", signature.Name, len(signature.ParameterTypes), len(expressionListStmt.ExpressionList))
+	}
+	return signature.ReturnType
+}
+
+func (tc *TypeChecker) lookupSubroutine(className string, subroutineName token.Token) (symboltable.SubroutineSignature, bool) {
+	classSignature, ok := tc.index.Class(className)
+	if !ok {
+		return symboltable.SubroutineSignature{}, false
+	}
+	signature, ok := classSignature.Subroutines[subroutineName.Literal]
+	if !ok {
+		tc.addError(UNKNOWN_SUBROUTINE, subroutineName, "subroutine %s.%s is not defined", className, subroutineName.Literal)
+	}
+	return signature, ok
+}
+
+// variableType returns declared type of variable. error is recorded if variable is not declared or field is used in function.
+func (tc *TypeChecker) variableType(name token.Token) (string, bool) {
+	if tc.IndexOf(name.Literal) == -1 {
+		tc.addError(UNDECLARED_IDENTIFIER, name, "%s is not declared", name.Literal)
+		return unknownType, false
+	}
+	if tc.KindOf(name.Literal) == symboltable.FIELD && tc.subroutine.Kind == symboltable.FUNCTION {
+		tc.addError(INVALID_THIS, name, "field %s cannot be used in function %s", name.Literal, tc.subroutine.Name)
+		return unknownType, false
+	}
+	return tc.TypeOf(name.Literal), true
+}
+
+// checkType checks that type of declaration is primitive type or declared class
+func (tc *TypeChecker) checkType(valueType token.Token) {
+	if isPrimitiveType(valueType.Literal) {
+		return
+	}
+	if _, ok := tc.index.Class(valueType.Literal); !ok {
+		tc.addError(UNKNOWN_CLASS, valueType, "class %s is not defined", valueType.Literal)
+	}
+}
+
+func (tc *TypeChecker) addError(errorType ErrorType, tok token.Token, format string, a ...interface{}) {
+	tc.errors = append(tc.errors, &TypeError{Type: errorType, Token: tok, Message: fmt.Sprintf(format, a...)})
+}
+
+func isPrimitiveType(valueType string) bool {
+	switch token.KeyWord(valueType) {
+	case token.INT, token.CHAR, token.BOOLEAN:
+		return true
+	}
+	return false
+}
+
+// assignable returns whether value of valueType can be assigned to variable of varType.
+// Jack is loosely typed. int and char are 16-bit values, and int is used as address of object. (e.g. "let memory = 0;" of Memory.jack)
+// so, only obviously wrong assignments like boolean to int are rejected.
+func assignable(varType string, valueType string) bool {
+	if varType == unknownType || valueType == unknownType || varType == valueType || valueType == nullType {
+		return true
+	}
+	isBoolean := func(t string) bool { return t == string(token.BOOLEAN) }
+	if isBoolean(varType) || isBoolean(valueType) {
+		return false
+	}
+	if isPrimitiveType(varType) && isPrimitiveType(valueType) { // charは文字コード(int)として扱われる
+		return true
+	}
+	// intとオブジェクト参照は相互に代入できる
+	if varType == string(token.INT) || valueType == string(token.INT) {
+		return true
+	}
+	if isPrimitiveType(varType) || isPrimitiveType(valueType) {
+		return false
+	}
+	// Arrayは任意のオブジェクトを指すポインタとして使われる
+	return varType == "Array" || valueType == "Array"
+}
+
+// expressionToken returns first token of expression
+func expressionToken(expressionAst ast.Expression) token.Token {
+	switch expression := expressionAst.(type) {
+	case *ast.SingleExpression:
+		return expression.Token
+	case *ast.InfixExpression:
+		return expression.Token
+	}
+	return token.Token{}
+}
@@ -0,0 +1,185 @@
+package typechecker
+
+import (
+	"jackcompiler/parser"
+	"jackcompiler/symboltable"
+	"jackcompiler/tokenizer"
+	"testing"
+)
+
+func checkClass(t *testing.T, input string) []*TypeError {
+	p := parser.New(tokenizer.NewWithFilename("Main.jack", input))
+	program, parseErrors := p.ParseProgram()
+	if len(parseErrors) > 0 {
+		t.Fatalf("ParseProgram() returned errors: %v", parseErrors)
+	}
+	index := symboltable.NewProgramIndex()
+	index.AddProgram(program)
+	AddOSClasses(index)
+	tc := New(index)
+	return tc.CheckProgram(program)
+}
+
+func TestCheckValidProgram(t *testing.T) {
+	input := `class Main {
+    field int count;
+    field Array items;
+    static Main instance;
+    constructor Main new(int n) {
+        let count = n;
+        let items = Array.new(n);
+        let instance = this;
+        return this;
+    }
+    method int get(int i) {
+        var char c;
+        let c = items[i];
+        let c = c + 1;
+        return count + c;
+    }
+    method void dispose() {
+        do items.dispose();
+        do Memory.deAlloc(this);
+        return;
+    }
+    function void main() {
+        var Main m;
+        var String s;
+        var boolean b;
+        var int address;
+        let m = Main.new(10);
+        let s = "hello";
+        let b = (m.get(1) < 3) & ~(s.length() = 0);
+        let m = null;
+        // Jackでは int と char, int と オブジェクト参照は相互に代入できる
+        let address = m.get(0);
+        let address = 65;
+        let s = 0;
+        let m = 2048;
+        let address = s;
+        if (b) { do Output.printString(s); } else { do Output.println(); }
+        while (~b) { let b = true; }
+        return;
+    }
+}`
+	errors := checkClass(t, input)
+	if len(errors) != 0 {
+		for _, err := range errors {
+			t.Log(err.Error())
+		}
+		t.Fatalf("len(errors) should be 0. got %d", len(errors))
+	}
+}
+
+func TestCheckErrors(t *testing.T) {
+	testCases := []struct {
+		body              string
+		expectedErrorType ErrorType
+		expectedMessage   string
+	}{
+		{`let y = 1; return;`, UNDECLARED_IDENTIFIER, "y is not declared at Main.jack:6:13"},
+		{`var Foo f; return;`, UNKNOWN_CLASS, "class Foo is not defined at Main.jack:6:13"},
+		{`do Foo.bar(); return;`, UNKNOWN_CLASS, "class Foo is not defined at Main.jack:6:12"},
+		{`do Main.missing(); return;`, UNKNOWN_SUBROUTINE, "subroutine Main.missing is not defined at Main.jack:6:17"},
+		{`do Output.printInt(); return;`, ARGUMENT_COUNT, "printInt expects 1 arguments but got 0 at Main.jack:6:19"},
+		{`do run(); return;`, INVALID_CALL, "method run cannot be called from function main at Main.jack:6:12"},
+		{`do Main.run(); return;`, INVALID_CALL, "method Main.run cannot be called without object at Main.jack:6:17"},
+		{`let count = 1; return;`, INVALID_THIS, "field count cannot be used in function main at Main.jack:6:13"},
+		{`do Output.printInt(this); return;`, INVALID_THIS, "'this' cannot be used in function main at Main.jack:6:28"},
+		{`return 1;`, RETURN_MISMATCH, "void main must not return value at Main.jack:6:16"},
+		{`var boolean b; let b = "a"; return;`, TYPE_MISMATCH, "cannot assign String to boolean b at Main.jack:6:32"},
+		{`var boolean b; let b = 1 + 2; return;`, TYPE_MISMATCH, "cannot assign int to boolean b at Main.jack:6:32"},
+		{`var int x; let x = true; return;`, TYPE_MISMATCH, "cannot assign boolean to int x at Main.jack:6:28"},
+		{`var char c; let c = 1 < 2; return;`, TYPE_MISMATCH, "cannot assign boolean to char c at Main.jack:6:29"},
+		{`var char c; let c = Main.new(); return;`, TYPE_MISMATCH, "cannot assign Main to char c at Main.jack:6:29"},
+		{`var String s; let s = Main.new(); return;`, TYPE_MISMATCH, "cannot assign Main to String s at Main.jack:6:31"},
+		{`var int x; let x = Output.println(); return;`, TYPE_MISMATCH, "void println has no value at Main.jack:6:35"},
+	}
+	for _, tt := range testCases {
+		input := `class Main {
+    field int count;
+    constructor Main new() { return this; }
+    method int run() { return count; }
+    function void main() {
+        ` + tt.body + `
+    }
+}`
+		errors := checkClass(t, input)
+		if len(errors) != 1 {
+			for _, err := range errors {
+				t.Log(err.Error())
+			}
+			t.Fatalf("%s: len(errors) should be 1. got %d", tt.body, len(errors))
+		}
+		if errors[0].Type != tt.expectedErrorType {
+			t.Errorf("%s: errors[0].Type should be %s. got %s", tt.body, tt.expectedErrorType, errors[0].Type)
+		}
+		if errors[0].Error() != tt.expectedMessage {
+			t.Errorf("%s: errors[0].Error() should be %q. got %q", tt.body, tt.expectedMessage, errors[0].Error())
+		}
+	}
+}
+
+func TestCheckReturnStatement(t *testing.T) {
+	input := `class Main {
+    constructor Main new() { return this; }
+    method int get() { return; }
+    method boolean isEmpty() { return 0; }
+    method String name() { return "main"; }
+    function Array create() { return null; }
+}`
+	expectedMessages := []string{
+		"get must return int value at Main.jack:3:24",
+		"cannot return int from isEmpty which returns boolean at Main.jack:4:39",
+	}
+	errors := checkClass(t, input)
+	if len(errors) != len(expectedMessages) {
+		for _, err := range errors {
+			t.Log(err.Error())
+		}
+		t.Fatalf("len(errors) should be %d. got %d", len(expectedMessages), len(errors))
+	}
+	for i, expected := range expectedMessages {
+		if errors[i].Type != RETURN_MISMATCH {
+			t.Errorf("errors[%d].Type should be %s. got %s", i, RETURN_MISMATCH, errors[i].Type)
+		}
+		if errors[i].Error() != expected {
+			t.Errorf("errors[%d].Error() should be %q. got %q", i, expected, errors[i].Error())
+		}
+	}
+}
+
+func TestCheckImplicitThisCall(t *testing.T) {
+	input := `class Main {
+    constructor Main new() { do init(); return this; }
+    method void run() {
+        var Main m;
+        do init();
+        let m = new();
+        do Main.init();
+        do stop();
+        return;
+    }
+    method void stop() { return; }
+    function void init() { do Main.init(); return; }
+}`
+	expectedMessages := []string{
+		"function init cannot be called as method of this. call Main.init at Main.jack:5:12",
+		"constructor new cannot be called as method of this. call Main.new at Main.jack:6:17",
+	}
+	errors := checkClass(t, input)
+	if len(errors) != len(expectedMessages) {
+		for _, err := range errors {
+			t.Log(err.Error())
+		}
+		t.Fatalf("len(errors) should be %d. got %d", len(expectedMessages), len(errors))
+	}
+	for i, expected := range expectedMessages {
+		if errors[i].Type != INVALID_CALL {
+			t.Errorf("errors[%d].Type should be %s. got %s", i, INVALID_CALL, errors[i].Type)
+		}
+		if errors[i].Error() != expected {
+			t.Errorf("errors[%d].Error() should be %q. got %q", i, expected, errors[i].Error())
+		}
+	}
+}
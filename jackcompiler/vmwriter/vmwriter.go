@@ -91,9 +91,7 @@ func (vm *VMWriter) writeData(vmCode string) {
 	vm.VMCode = append(vm.VMCode, []byte(vmCode)...)
 }
 
-func (vm *VMWriter) Close() {
-	err := ioutil.WriteFile(vm.Filename, vm.VMCode, vm.perm)
-	if err != nil {
-		panic(err)
-	}
+// Close writes vm code to file
+func (vm *VMWriter) Close() error {
+	return ioutil.WriteFile(vm.Filename, vm.VMCode, vm.perm)
 }
@@ -4,12 +4,12 @@ import (
 	"bytes"
 	"io/ioutil"
 	"jackcompiler/value"
+	"path/filepath"
 	"testing"
 )
 
-var filename = "test.vm"
-
 func TestClose(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "test.vm")
 	vmCode := []byte("Hello,World")
 	vmWriter := &VMWriter{
 		VMCode: vmCode, Filename: filename, perm: 0644,
@@ -23,7 +23,7 @@ func TestClose(t *testing.T) {
 func TestWriteData(t *testing.T) {
 	vmCode := "Hello,World."
 	vmWriter := &VMWriter{
-		VMCode: []byte(vmCode), Filename: filename, perm: 0644,
+		VMCode: []byte(vmCode), Filename: "test.vm", perm: 0644,
 	}
 	addVmCode := "Good bye, World"
 	vmWriter.writeData(addVmCode)
@@ -0,0 +1,106 @@
+package xmlwriter
+
+import (
+	"bytes"
+	"fmt"
+	"jackcompiler/token"
+	"jackcompiler/tokenizer"
+	"jackcompiler/value"
+	"strings"
+)
+
+const indent = "  "
+
+// TokensXml returns token stream of jack code(<Class>T.xml) in format of nand2tetris TextComparer
+func TokensXml(jt *tokenizer.JackTokenizer) string {
+	var out bytes.Buffer
+	out.WriteString("<tokens>" + value.NEW_LINE)
+	for {
+		tok, err := jt.Advance()
+		if tok.Type == token.EOF {
+			break
+		}
+		if err != nil {
+			continue // 字句エラーは構文解析時に報告される
+		}
+		out.WriteString(tok.Xml() + value.NEW_LINE)
+	}
+	out.WriteString("</tokens>" + value.NEW_LINE)
+	return out.String()
+}
+
+// Format writes each element of xml (e.g. ast.Program.Xml()) in its own line and indents it by depth.
+// element which has text like "<keyword> class </keyword>" is written in one line.
+func Format(xml string) string {
+	var out bytes.Buffer
+	depth := 0
+	for pos := 0; pos < len(xml); {
+		start := strings.Index(xml[pos:], "<")
+		if start == -1 {
+			break
+		}
+		start += pos
+		end := strings.Index(xml[start:], ">")
+		if end == -1 {
+			break
+		}
+		end += start + 1
+		tag := xml[start:end]
+		if strings.HasPrefix(tag, "</") {
+			depth--
+			out.WriteString(strings.Repeat(indent, depth) + tag + value.NEW_LINE)
+			pos = end
+			continue
+		}
+		if end < len(xml) && xml[end] != '<' { // 文字列を持つ要素は1行にまとめる
+			closeEnd := strings.Index(xml[end:], ">")
+			if closeEnd == -1 {
+				break
+			}
+			closeEnd += end + 1
+			out.WriteString(strings.Repeat(indent, depth) + xml[start:closeEnd] + value.NEW_LINE)
+			pos = closeEnd
+			continue
+		}
+		out.WriteString(strings.Repeat(indent, depth) + tag + value.NEW_LINE)
+		depth++
+		pos = end
+	}
+	return out.String()
+}
+
+// Compare compares xml with expected xml line by line like nand2tetris TextComparer.
+// whitespace and empty lines are ignored. error describes the first line which differs.
+func Compare(actual string, expected string) error {
+	actualLines, expectedLines := normalizedLines(actual), normalizedLines(expected)
+	for i := 0; i < len(actualLines) && i < len(expectedLines); i++ {
+		if actualLines[i].text != expectedLines[i].text {
+			return fmt.Errorf("line %d differs from expected line %d. expected %q, got %q", actualLines[i].number, expectedLines[i].number, expectedLines[i].text, actualLines[i].text)
+		}
+	}
+	if len(actualLines) < len(expectedLines) {
+		return fmt.Errorf("output ends before expected line %d %q", expectedLines[len(actualLines)].number, expectedLines[len(actualLines)].text)
+	}
+	if len(actualLines) > len(expectedLines) {
+		return fmt.Errorf("line %d %q is not expected", actualLines[len(expectedLines)].number, actualLines[len(expectedLines)].text)
+	}
+	return nil
+}
+
+type line struct {
+	number int // 1-indexed line number in original text
+	text   string
+}
+
+// normalizedLines returns non empty lines whose whitespace is removed
+func normalizedLines(text string) []line {
+	lines := []line{}
+	for i, l := range strings.Split(text, "\n") {
+		normalized := strings.Join(strings.Fields(l), "")
+		if normalized == "" {
+			continue
+		}
+		lines = append(lines, line{number: i + 1, text: normalized})
+	}
+	return lines
+}
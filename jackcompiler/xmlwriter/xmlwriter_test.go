@@ -0,0 +1,109 @@
+package xmlwriter
+
+import (
+	"jackcompiler/parser"
+	"jackcompiler/tokenizer"
+	"jackcompiler/value"
+	"strings"
+	"testing"
+)
+
+func TestTokensXml(t *testing.T) {
+	input := `if (x < 0) { let s = "a&b"; } // comment`
+	expected := strings.Join([]string{
+		"<tokens>",
+		"<keyword> if </keyword>",
+		"<symbol> ( </symbol>",
+		"<identifier> x </identifier>",
+		"<symbol> &lt; </symbol>",
+		"<integerConstant> 0 </integerConstant>",
+		"<symbol> ) </symbol>",
+		"<symbol> { </symbol>",
+		"<keyword> let </keyword>",
+		"<identifier> s </identifier>",
+		"<symbol> = </symbol>",
+		"<stringConstant> a&amp;b </stringConstant>",
+		"<symbol> ; </symbol>",
+		"<symbol> } </symbol>",
+		"</tokens>",
+	}, value.NEW_LINE) + value.NEW_LINE
+	actual := TokensXml(tokenizer.New(input))
+	if actual != expected {
+		t.Fatalf("TokensXml() should be %q. got %q", expected, actual)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	input := `class Main {
+    function void main() {
+        return 1 > 2;
+    }
+}`
+	expected := strings.Join([]string{
+		"<class>",
+		"  <keyword> class </keyword>",
+		"  <identifier> Main </identifier>",
+		"  <symbol> { </symbol>",
+		"  <subroutineDec>",
+		"    <keyword> function </keyword>",
+		"    <keyword> void </keyword>",
+		"    <identifier> main </identifier>",
+		"    <symbol> ( </symbol>",
+		"    <parameterList>",
+		"    </parameterList>",
+		"    <symbol> ) </symbol>",
+		"    <subroutineBody>",
+		"      <symbol> { </symbol>",
+		"      <statements>",
+		"        <returnStatement>",
+		"          <keyword> return </keyword>",
+		"          <expression>",
+		"            <term>",
+		"              <integerConstant> 1 </integerConstant>",
+		"            </term>",
+		"            <symbol> &gt; </symbol>",
+		"            <term>",
+		"              <integerConstant> 2 </integerConstant>",
+		"            </term>",
+		"          </expression>",
+		"          <symbol> ; </symbol>",
+		"        </returnStatement>",
+		"      </statements>",
+		"      <symbol> } </symbol>",
+		"    </subroutineBody>",
+		"  </subroutineDec>",
+		"  <symbol> } </symbol>",
+		"</class>",
+	}, value.NEW_LINE) + value.NEW_LINE
+	p := parser.New(tokenizer.New(input))
+	program, _ := p.ParseProgram()
+	actual := Format(program.Xml())
+	if actual != expected {
+		t.Fatalf("Format() should be %q. got %q", expected, actual)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	testCases := []struct {
+		actual        string
+		expected      string
+		expectedError string
+	}{
+		{"<tokens>\r\n  <keyword> class </keyword>\r\n</tokens>\r\n", "<tokens>\n<keyword>class</keyword>\n\n</tokens>", ""},
+		{"<tokens>\n<keyword> class </keyword>\n</tokens>", "<tokens>\n<keyword> var </keyword>\n</tokens>", `line 2 differs from expected line 2. expected "<keyword>var</keyword>", got "<keyword>class</keyword>"`},
+		{"<tokens>\n</tokens>", "<tokens>\n</tokens>\n<tokens>", `output ends before expected line 3 "<tokens>"`},
+		{"<tokens>\n\n</tokens>\n<tokens>", "<tokens>\n</tokens>", `line 4 "<tokens>" is not expected`},
+	}
+	for _, tt := range testCases {
+		err := Compare(tt.actual, tt.expected)
+		if tt.expectedError == "" {
+			if err != nil {
+				t.Errorf("Compare(%q, %q) should succeed. got %s", tt.actual, tt.expected, err)
+			}
+			continue
+		}
+		if err == nil || err.Error() != tt.expectedError {
+			t.Errorf("Compare(%q, %q) should return error %q. got %v", tt.actual, tt.expected, tt.expectedError, err)
+		}
+	}
+}
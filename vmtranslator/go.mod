@@ -1,3 +1,13 @@
 module vmtranslator
 
 go 1.16
+
+require (
+	assembler v0.0.0
+	cpuemulator v0.0.0
+)
+
+replace (
+	assembler => ../assembler
+	cpuemulator => ../cpuemulator
+)
@@ -1,9 +1,11 @@
 package main
 
 import (
+	"assembler/optimizer"
 	"flag"
 	"fmt"
 	"io/ioutil"
+	"os"
 	"path"
 	"path/filepath"
 	"strings"
@@ -25,12 +27,8 @@ func removeExt(filename string) string {
 	return strings.Trim(filename, filepath.Ext(filename))
 }
 
-func main() {
-
-	flag.Parse()
-	pathToVmDir := flag.Args()[0]
-	asmFilename := fmt.Sprintf("%s.asm", path.Base(pathToVmDir))
-
+// translateDir translates all vm files in dir to assembly of codeWriter which writes asmPath on Close
+func translateDir(pathToVmDir string, asmPath string) *codewriter.CodeWriter {
 	vmFileList, err := getVmFileListInDir(pathToVmDir)
 	if err != nil {
 		panic(err)
@@ -46,7 +44,7 @@ func main() {
 		vmClassNameList = append(vmClassNameList, removeExt(filename))
 		vmCodeList = append(vmCodeList, string(vmCode))
 	}
-	codeWriter := codewriter.New(path.Join(pathToVmDir, asmFilename))
+	codeWriter := codewriter.New(asmPath)
 	// writeInit
 	codeWriter.WriteInit()
 	for i := range vmCodeList {
@@ -85,5 +83,24 @@ func main() {
 			parser.Advance()
 		}
 	}
+	return codeWriter
+}
+
+func main() {
+	optimize := flag.Bool("O", false, "remove redundant instructions from assembly by peephole optimizer and print instructions saved")
+	flag.Parse()
+	pathToVmDir := flag.Args()[0]
+	asmFilename := fmt.Sprintf("%s.asm", path.Base(pathToVmDir))
+
+	codeWriter := translateDir(pathToVmDir, path.Join(pathToVmDir, asmFilename))
+	if *optimize {
+		optimized, report, err := optimizer.OptimizeAsm(string(codeWriter.Assembly), "")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", asmFilename, err)
+			os.Exit(1)
+		}
+		codeWriter.Assembly = []byte(optimized)
+		fmt.Fprintf(os.Stderr, "%s: %s\n", asmFilename, report)
+	}
 	codeWriter.Close()
 }
@@ -1,6 +1,11 @@
 package main
 
 import (
+	"assembler"
+	"assembler/optimizer"
+	"cpuemulator/tstscript"
+	"io/ioutil"
+	"path/filepath"
 	"reflect"
 	"testing"
 )
@@ -39,3 +44,48 @@ func TestRemoveExt(t *testing.T) {
 		}
 	}
 }
+
+func TestRunVmTestScripts(t *testing.T) {
+	// ブートストラップが Sys.init を呼ぶので、Sys.vm があるプログラムだけを実行する
+	names := []string{"FibonacciElement", "NestedCall", "StaticsTest"}
+	for _, name := range names {
+		dir := t.TempDir()
+		translateDir(filepath.Join("vm", name), filepath.Join(dir, name+".asm")).Close()
+		runTestScript(t, dir, name)
+	}
+}
+
+func TestRunOptimizedVmTestScripts(t *testing.T) {
+	// -O で最適化したアセンブリも同じ結果になる
+	names := []string{"FibonacciElement", "NestedCall", "StaticsTest"}
+	for _, name := range names {
+		dir := t.TempDir()
+		codeWriter := translateDir(filepath.Join("vm", name), filepath.Join(dir, name+".asm"))
+		optimized, report, err := optimizer.OptimizeAsm(string(codeWriter.Assembly), "")
+		if err != nil {
+			t.Fatalf("%s: OptimizeAsm() returned error: %s", name, err)
+		}
+		if report.Saved() <= 0 {
+			t.Errorf("%s should be optimized: %s", name, report)
+		}
+		codeWriter.Assembly = []byte(optimized)
+		codeWriter.Close()
+		runTestScript(t, dir, name)
+	}
+}
+
+// runTestScript copies .tst and .cmp of vm/name to dir and runs .tst with assembly in dir
+func runTestScript(t *testing.T, dir string, name string) {
+	for _, ext := range []string{".tst", ".cmp"} {
+		data, err := ioutil.ReadFile(filepath.Join("vm", name, name+ext))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, name+ext), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := tstscript.RunFile(filepath.Join(dir, name+".tst"), false, assembler.AssembleProgram); err != nil {
+		t.Errorf("%s: %s", name, err)
+	}
+}